@@ -7,15 +7,28 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"runtime"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/monoid-privacy/monoid/analytics/ingestor"
 	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/filestore/azurestore"
+	"github.com/monoid-privacy/monoid/filestore/encryptedstore"
 	"github.com/monoid-privacy/monoid/filestore/gcloudstore"
 	"github.com/monoid-privacy/monoid/filestore/localstore"
+	"github.com/monoid-privacy/monoid/filestore/s3store"
+	"github.com/monoid-privacy/monoid/kms"
+	"github.com/monoid-privacy/monoid/kms/awskms"
+	"github.com/monoid-privacy/monoid/kms/localkms"
 	"google.golang.org/api/option"
 
 	"github.com/monoid-privacy/monoid/model"
@@ -59,6 +72,13 @@ var Models = []interface{}{
 	model.OSSRegistration{},
 	model.QueryResult{},
 	model.DownloadableFile{},
+	model.RequestTombstone{},
+	model.ScannerRule{},
+	model.DiscoverySchedule{},
+	model.DiscoveryPolicy{},
+	model.DiscoveryDecision{},
+	model.DataMapSnapshot{},
+	model.NotificationChannel{},
 }
 
 func MigrateOSS(db *gorm.DB) {
@@ -101,6 +121,60 @@ func InitDb(dbInfo DBInfo) *gorm.DB {
 	return db
 }
 
+// getKeyProvider returns the key provider configured by KMS_TYPE. The
+// local provider uses the keys in KMS_KEY_FILE if it's set, and the
+// encryption key otherwise.
+func getKeyProvider(encryptionKey []byte) (kms.KeyProvider, error) {
+	var provider kms.KeyProvider
+	var err error
+
+	switch os.Getenv("KMS_TYPE") {
+	case "aws":
+		provider, err = awskms.NewAWSKeyProviderFromEnv(
+			context.Background(),
+			os.Getenv("AWS_KMS_KEY_ID"),
+			os.Getenv("AWS_KMS_ENDPOINT"),
+		)
+	default:
+		if keyFile := os.Getenv("KMS_KEY_FILE"); keyFile != "" {
+			provider, err = localkms.NewLocalKeyProviderFromFile(keyFile)
+		} else {
+			provider, err = localkms.NewLocalKeyProvider(encryptionKey)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return kms.NewCachingKeyProvider(provider, 1024)
+}
+
+// getAzureContainerClient returns a client for the configured blob
+// container. A connection string or a container URL with a SAS token
+// are used if they're set, otherwise the client authenticates with the
+// default Azure credential chain (e.g. a managed identity).
+func getAzureContainerClient() (*container.Client, error) {
+	if connStr := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); connStr != "" {
+		return container.NewClientFromConnectionString(
+			connStr,
+			os.Getenv("AZURE_STORAGE_CONTAINER"),
+			nil,
+		)
+	}
+
+	if sasURL := os.Getenv("AZURE_STORAGE_SAS_URL"); sasURL != "" {
+		return container.NewClientWithNoCredential(sasURL, nil)
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return container.NewClient(os.Getenv("AZURE_STORAGE_CONTAINER_URL"), cred, nil)
+}
+
 func GetBaseConfig(migrator func(db *gorm.DB)) config.BaseConfig {
 	err := godotenv.Load()
 	if err != nil {
@@ -130,6 +204,13 @@ func GetBaseConfig(migrator func(db *gorm.DB)) config.BaseConfig {
 
 	model.SetEncryptionKey(key)
 
+	keyProvider, err := getKeyProvider(key)
+	if err != nil {
+		panic(err)
+	}
+
+	model.SetKeyProvider(keyProvider)
+
 	reg := model.OSSRegistration{}
 	if err := db.First(&reg).Error; err != nil {
 		reg.ID = "temp_" + uuid.NewString()
@@ -140,11 +221,22 @@ func GetBaseConfig(migrator func(db *gorm.DB)) config.BaseConfig {
 		tempStore = os.TempDir()
 	}
 
+	scanWorkers := runtime.NumCPU()
+	if w := os.Getenv("SCAN_WORKERS"); w != "" {
+		scanWorkers, err = strconv.Atoi(w)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	conf := config.BaseConfig{
 		DB:              db,
 		WebURL:          os.Getenv("WEB_URL"),
 		TempStorePath:   tempStore,
 		ProtocolFactory: &docker.DockerProtocolFactory{},
+		EncryptionKey:   key,
+		KeyProvider:     keyProvider,
+		ScanWorkers:     scanWorkers,
 		AnalyticsIngestor: ingestor.NewSegmentIngestor(
 			os.Getenv("SEGMENT_KEY"),
 			&reg.ID,
@@ -165,9 +257,50 @@ func GetBaseConfig(migrator func(db *gorm.DB)) config.BaseConfig {
 			cli,
 			os.Getenv("GCS_BUCKET"),
 		)
+	case "azure":
+		cli, err := getAzureContainerClient()
+		if err != nil {
+			panic(err)
+		}
+
+		conf.FileStore = azurestore.NewAzureBlobStore(cli)
+	case "s3":
+		awsConf, err := awsconfig.LoadDefaultConfig(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		cli := s3.NewFromConfig(awsConf, func(o *s3.Options) {
+			if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+				o.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+			}
+
+			o.UsePathStyle = os.Getenv("S3_FORCE_PATH_STYLE") == "true"
+		})
+
+		partSize := int64(0)
+		if ps := os.Getenv("S3_PART_SIZE_MB"); ps != "" {
+			mb, err := strconv.Atoi(ps)
+			if err != nil {
+				panic(err)
+			}
+
+			partSize = int64(mb) * 1024 * 1024
+		}
+
+		conf.FileStore = s3store.NewS3Store(cli, os.Getenv("S3_BUCKET"), s3store.S3StoreOptions{
+			Prefix:               os.Getenv("S3_PREFIX"),
+			ServerSideEncryption: s3types.ServerSideEncryption(os.Getenv("S3_SSE")),
+			SSEKMSKeyID:          os.Getenv("S3_SSE_KMS_KEY_ID"),
+			PartSize:             partSize,
+		})
 	default:
 		conf.FileStore = localstore.NewLocalFileStore(os.Getenv("FILESTORE_PATH"))
 	}
 
+	if os.Getenv("FILESTORE_ENCRYPTION") == "true" {
+		conf.FileStore = encryptedstore.NewEncryptedFileStore(conf.FileStore, keyProvider)
+	}
+
 	return conf
 }
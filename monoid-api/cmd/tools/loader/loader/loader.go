@@ -2,9 +2,12 @@ package loader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/monoid-privacy/monoid/config"
 	"github.com/monoid-privacy/monoid/model"
@@ -100,3 +103,120 @@ func LoadCategories(conf *config.BaseConfig, configPath string) {
 		}
 	}
 }
+
+// scannerRuleEntry is a scanner rule in scanner-rules.yaml.
+type scannerRuleEntry struct {
+	model.ScannerRule `yaml:",inline"`
+	ColumnNames       []string `yaml:"columnNames"`
+}
+
+// validateScannerRule checks a rule from scanner-rules.yaml the same way
+// the createScannerRule mutation does, and fills in the default
+// confidence.
+func validateScannerRule(conf *config.BaseConfig, rule *model.ScannerRule, columnNames []string) error {
+	switch rule.Kind {
+	case model.ScannerRuleKindRegex:
+		if rule.Pattern == nil || *rule.Pattern == "" {
+			return fmt.Errorf("regex rules must have a pattern")
+		}
+
+		if len(columnNames) != 0 {
+			return fmt.Errorf("regex rules can't have column names")
+		}
+
+		if _, err := regexp.Compile(*rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case model.ScannerRuleKindName:
+		if len(columnNames) == 0 {
+			return fmt.Errorf("name rules must have at least one column name")
+		}
+
+		if rule.Pattern != nil {
+			return fmt.Errorf("name rules can't have a pattern")
+		}
+	default:
+		return fmt.Errorf("invalid kind %q", rule.Kind)
+	}
+
+	if rule.Confidence == "" {
+		rule.Confidence = "medium"
+	}
+
+	if rule.Confidence != "low" && rule.Confidence != "medium" && rule.Confidence != "high" {
+		return fmt.Errorf("invalid confidence %q", rule.Confidence)
+	}
+
+	categoryQuery := conf.DB.Where("id = ?", rule.CategoryID)
+	if rule.WorkspaceID == nil {
+		categoryQuery = categoryQuery.Where("workspace_id IS NULL")
+	} else {
+		categoryQuery = categoryQuery.Where(
+			conf.DB.Where("workspace_id = ?", *rule.WorkspaceID).Or("workspace_id IS NULL"),
+		)
+	}
+
+	if err := categoryQuery.First(&model.Category{}).Error; err != nil {
+		return fmt.Errorf("could not find category %q: %w", rule.CategoryID, err)
+	}
+
+	return nil
+}
+
+// LoadScannerRules creates or updates the custom scanner rules in
+// scanner-rules.yaml. The file is optional.
+func LoadScannerRules(conf *config.BaseConfig, configPath string) {
+	f, err := os.Open(filepath.Join(configPath, "scanner-rules.yaml"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+
+		panic(err)
+	}
+
+	defer f.Close()
+
+	entries := []scannerRuleEntry{}
+	if err := yaml.NewDecoder(f).Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		rule := entry.ScannerRule
+
+		if err := validateScannerRule(conf, &rule, entry.ColumnNames); err != nil {
+			log.Err(err).Msgf("Invalid scanner rule %s", rule.ID)
+			continue
+		}
+
+		columnNames, err := json.Marshal(entry.ColumnNames)
+		if err != nil {
+			log.Err(err).Msgf("Error encoding column names for scanner rule %s", rule.ID)
+			continue
+		}
+
+		rule.ColumnNames = columnNames
+
+		existing := model.ScannerRule{}
+		if err := conf.DB.Where("id = ?", rule.ID).First(&existing).Error; err != nil {
+			if err := conf.DB.Create(&rule).Error; err != nil {
+				log.Err(err).Msgf("Error creating scanner rule: %s", rule.Name)
+				continue
+			}
+
+			fmt.Printf("Successfully created scanner rule %s (%s)\n", rule.ID, rule.Name)
+
+			continue
+		}
+
+		// Select the columns so that removing a pattern or the column
+		// names from the file clears them.
+		if err := conf.DB.Model(&rule).Select(
+			"workspace_id", "name", "kind", "pattern", "column_names", "confidence", "category_id",
+		).Updates(&rule).Error; err != nil {
+			log.Err(err).Msgf("Error updating scanner rule: %s", rule.Name)
+			continue
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"gorm.io/gorm"
+)
+
+type loaderTestSuite struct {
+	suite.Suite
+
+	container testcontainers.Container
+	db        *gorm.DB
+	conf      *config.BaseConfig
+}
+
+func (s *loaderTestSuite) SetupSuite() {
+	container, db, err := testutil.SetupDB()
+	if err != nil {
+		s.T().Skipf("could not start postgres: %v", err)
+	}
+
+	s.container = container
+	s.db = db
+	s.conf = &config.BaseConfig{DB: db}
+
+	s.Require().NoError(db.Create(&model.Category{ID: "user_id", Name: "User ID"}).Error)
+}
+
+func (s *loaderTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+func (s *loaderTestSuite) writeRules(rules string) string {
+	dir := s.T().TempDir()
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "scanner-rules.yaml"), []byte(rules), 0600))
+
+	return dir
+}
+
+func (s *loaderTestSuite) rule(id string) (model.ScannerRule, error) {
+	rule := model.ScannerRule{}
+	err := s.db.Where("id = ?", id).First(&rule).Error
+
+	return rule, err
+}
+
+func (s *loaderTestSuite) TestLoadScannerRules() {
+	// The file is optional.
+	LoadScannerRules(s.conf, s.T().TempDir())
+
+	LoadScannerRules(s.conf, s.writeRules(`
+- id: customer_number
+  name: Customer Number
+  kind: REGEX
+  pattern: '\bCUST-\d{8}\b'
+  categoryId: user_id
+
+- id: employee_id
+  name: Employee ID
+  kind: NAME
+  columnNames: [employee_id, emp_no]
+  confidence: high
+  categoryId: user_id
+
+- id: invalid_pattern
+  name: Invalid
+  kind: REGEX
+  pattern: 'CUST-(\d+'
+  categoryId: user_id
+
+- id: missing_pattern
+  name: Missing
+  kind: REGEX
+  categoryId: user_id
+
+- id: name_with_pattern
+  name: Name with pattern
+  kind: NAME
+  pattern: '\bEMP-\d{6}\b'
+  columnNames: [badge]
+  categoryId: user_id
+
+- id: missing_columns
+  name: Missing columns
+  kind: NAME
+  categoryId: user_id
+
+- id: invalid_confidence
+  name: Invalid confidence
+  kind: NAME
+  columnNames: [badge]
+  confidence: certain
+  categoryId: user_id
+
+- id: missing_category
+  name: Missing category
+  kind: NAME
+  columnNames: [badge]
+  categoryId: not_a_category
+`))
+
+	customer, err := s.rule("customer_number")
+	s.Require().NoError(err)
+	s.Equal(`\bCUST-\d{8}\b`, *customer.Pattern)
+	s.Equal("medium", customer.Confidence)
+	s.Nil(customer.WorkspaceID)
+
+	employee, err := s.rule("employee_id")
+	s.Require().NoError(err)
+	s.Equal("high", employee.Confidence)
+
+	columnNames, err := employee.ColumnNameList()
+	s.Require().NoError(err)
+	s.Equal([]string{"employee_id", "emp_no"}, columnNames)
+
+	// Invalid rules are skipped.
+	for _, id := range []string{
+		"invalid_pattern",
+		"missing_pattern",
+		"name_with_pattern",
+		"missing_columns",
+		"invalid_confidence",
+		"missing_category",
+	} {
+		_, err = s.rule(id)
+		s.ErrorIs(err, gorm.ErrRecordNotFound, id)
+	}
+
+	// Loading again updates the existing rules.
+	LoadScannerRules(s.conf, s.writeRules(`
+- id: customer_number
+  name: Customer Number
+  kind: REGEX
+  pattern: '\bCUST-\d{10}\b'
+  confidence: high
+  categoryId: user_id
+`))
+
+	customer, err = s.rule("customer_number")
+	s.Require().NoError(err)
+	s.Equal(`\bCUST-\d{10}\b`, *customer.Pattern)
+	s.Equal("high", customer.Confidence)
+
+	// Changing the kind of a rule clears the fields it no longer uses.
+	LoadScannerRules(s.conf, s.writeRules(`
+- id: customer_number
+  name: Customer Number
+  kind: NAME
+  columnNames: [customer_number]
+  categoryId: user_id
+
+- id: employee_id
+  name: Employee ID
+  kind: REGEX
+  pattern: '\bEMP-\d{6}\b'
+  categoryId: user_id
+`))
+
+	customer, err = s.rule("customer_number")
+	s.Require().NoError(err)
+	s.Nil(customer.Pattern)
+	s.Equal("medium", customer.Confidence)
+
+	employee, err = s.rule("employee_id")
+	s.Require().NoError(err)
+
+	columnNames, err = employee.ColumnNameList()
+	s.Require().NoError(err)
+	s.Empty(columnNames)
+}
+
+func TestLoaderSuite(t *testing.T) {
+	suite.Run(t, new(loaderTestSuite))
+}
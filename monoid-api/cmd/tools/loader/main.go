@@ -44,4 +44,5 @@ func main() {
 
 	loader.LoadSpecs(&conf, os.Args[1])
 	loader.LoadCategories(&conf, os.Args[1])
+	loader.LoadScannerRules(&conf, os.Args[1])
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/monoid-privacy/monoid/cmd/tools/scan/scan"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/monoid-privacy/monoid/scanner/basicscanner"
+)
+
+// Exit codes, so the scan can be used as a CI check.
+const (
+	exitClean    = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+// defaultThreshold is the score below which findings are ignored, the same
+// as the server's default discovery threshold.
+const defaultThreshold = 0.2
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ./scan [flags] [file]")
+		fmt.Fprintln(flags.Output(), "\nScans a CSV, JSON Lines or Parquet file for PII. Exits with 1 if anything")
+		fmt.Fprintln(flags.Output(), "is found, and 2 if the file can't be scanned.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+
+	output := flags.String("format", string(scan.OutputTable), "output format: table, json or sarif")
+	inputFormat := flags.String("input-format", "", "input format: csv, jsonl or parquet (detected from the file extension by default)")
+	rulePacks := flags.String("rule-packs", "", "comma-separated rule packs to enable, e.g. eu,uk")
+	threshold := flags.Float64("threshold", defaultThreshold, "findings with a lower score (0-1) are ignored")
+	limit := flags.Int("limit", 0, "maximum number of rows to scan, 0 scans every row")
+	inferRows := flags.Int("infer-rows", 1000, "number of rows the schema is inferred from")
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	path := flags.Arg(0)
+
+	format := scan.Format(*inputFormat)
+	if format == "" {
+		var err error
+		if format, err = scan.DetectFormat(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+
+	ruleConfig := scanner.RuleConfig{}
+	for _, id := range strings.Split(*rulePacks, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+
+		if _, ok := basicscanner.LookupRulePack(id); !ok {
+			fmt.Fprintf(os.Stderr, "unknown rule pack %q\n", id)
+			return exitError
+		}
+
+		ruleConfig.RulePacks = append(ruleConfig.RulePacks, id)
+	}
+
+	r, err := scan.OpenFile(path, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening %s: %v\n", path, err)
+		return exitError
+	}
+
+	defer r.Close()
+
+	name := filepath.Base(path)
+	res, err := scan.Scan(r, scan.Options{
+		SchemaName: strings.TrimSuffix(name, filepath.Ext(name)),
+		InferRows:  *inferRows,
+		Limit:      *limit,
+		RuleConfig: ruleConfig,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error scanning %s: %v\n", path, err)
+		return exitError
+	}
+
+	findings := []scan.Finding{}
+	for _, f := range res.Findings {
+		if f.Score >= *threshold {
+			findings = append(findings, f)
+		}
+	}
+
+	res.Findings = findings
+
+	if err := scan.WriteReport(os.Stdout, scan.OutputFormat(*output), path, res); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if len(res.Findings) > 0 {
+		return exitFindings
+	}
+
+	return exitClean
+}
@@ -0,0 +1,125 @@
+package scan
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// schemaNode is the JSON schema of the values seen at a path.
+type schemaNode struct {
+	types      map[string]bool
+	properties map[string]*schemaNode
+	items      *schemaNode
+}
+
+func newSchemaNode() *schemaNode {
+	return &schemaNode{types: map[string]bool{}}
+}
+
+// InferSchema infers a JSON schema for records from the values in them.
+// A property that has values of more than one type (e.g. numbers and
+// strings) is typed as a string, since every value can be scanned as one.
+func InferSchema(records []map[string]interface{}) map[string]interface{} {
+	root := newSchemaNode()
+
+	for _, r := range records {
+		root.add(r)
+	}
+
+	// Every record is an object, even if none were read.
+	root.types = map[string]bool{"object": true}
+
+	return root.jsonSchema()
+}
+
+func (n *schemaNode) add(v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		n.types["null"] = true
+	case string:
+		n.types["string"] = true
+	case bool:
+		n.types["boolean"] = true
+	case float64, float32, json.Number:
+		n.types["number"] = true
+	case int, int32, int64:
+		n.types["integer"] = true
+	case map[string]interface{}:
+		n.types["object"] = true
+		if n.properties == nil {
+			n.properties = map[string]*schemaNode{}
+		}
+
+		for k, pv := range val {
+			p, ok := n.properties[k]
+			if !ok {
+				p = newSchemaNode()
+				n.properties[k] = p
+			}
+
+			p.add(pv)
+		}
+	case []interface{}:
+		n.types["array"] = true
+		if n.items == nil {
+			n.items = newSchemaNode()
+		}
+
+		for _, item := range val {
+			n.items.add(item)
+		}
+	default:
+		n.types["string"] = true
+	}
+}
+
+// typeName returns the type of the values, ignoring nulls.
+func (n *schemaNode) typeName() string {
+	types := []string{}
+	for t := range n.types {
+		if t != "null" {
+			types = append(types, t)
+		}
+	}
+
+	switch len(types) {
+	case 0:
+		return "null"
+	case 1:
+		return types[0]
+	}
+
+	sort.Strings(types)
+	if len(types) == 2 && types[0] == "integer" && types[1] == "number" {
+		return "number"
+	}
+
+	return "string"
+}
+
+func (n *schemaNode) jsonSchema() map[string]interface{} {
+	t := n.typeName()
+	res := map[string]interface{}{"type": t}
+
+	if n.types["null"] && t != "null" {
+		res["type"] = []interface{}{t, "null"}
+	}
+
+	switch t {
+	case "object":
+		props := map[string]interface{}{}
+		for k, p := range n.properties {
+			props[k] = p.jsonSchema()
+		}
+
+		res["properties"] = props
+	case "array":
+		if n.items != nil {
+			res["items"] = n.items.jsonSchema()
+		} else {
+			res["items"] = map[string]interface{}{}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,309 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// Format is the format of a file that can be scanned.
+type Format string
+
+const (
+	FormatCSV     = Format("csv")
+	FormatJSONL   = Format("jsonl")
+	FormatParquet = Format("parquet")
+)
+
+// parquetBatchSize is the number of rows read from a parquet file at once.
+const parquetBatchSize = 1000
+
+// RecordReader reads the records in a file one at a time.
+type RecordReader interface {
+	// Next returns the next record, or io.EOF once there are no more.
+	Next() (map[string]interface{}, error)
+	Close() error
+}
+
+// DetectFormat returns the format of a file from its extension.
+func DetectFormat(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".parquet":
+		return FormatParquet, nil
+	}
+
+	return "", fmt.Errorf("can't detect the format of %s, set it with -input-format", path)
+}
+
+// OpenFile opens a reader for the records in the file at path.
+func OpenFile(path string, format Format) (RecordReader, error) {
+	switch format {
+	case FormatCSV:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return newCSVReader(f)
+	case FormatJSONL:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return newJSONLReader(f), nil
+	case FormatParquet:
+		return newParquetReader(path)
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// csvReader reads the rows of a CSV file with a header row. Every value is
+// read as a string.
+type csvReader struct {
+	file   io.Closer
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(f io.ReadCloser) (RecordReader, error) {
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		f.Close()
+
+		if err == io.EOF {
+			return nil, fmt.Errorf("csv file doesn't have a header row")
+		}
+
+		return nil, err
+	}
+
+	return &csvReader{
+		file:   f,
+		reader: r,
+		header: append([]string{}, header...),
+	}, nil
+}
+
+func (r *csvReader) Next() (map[string]interface{}, error) {
+	row, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(r.header))
+	for i, name := range r.header {
+		if i < len(row) && row[i] != "" {
+			record[name] = row[i]
+		}
+	}
+
+	return record, nil
+}
+
+func (r *csvReader) Close() error {
+	return r.file.Close()
+}
+
+// jsonlReader reads a file with a JSON object on each line.
+type jsonlReader struct {
+	file    io.Closer
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(f io.ReadCloser) RecordReader {
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	return &jsonlReader{file: f, scanner: s}
+}
+
+func (r *jsonlReader) Next() (map[string]interface{}, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// Numbers are kept as json.Number, so long IDs and account numbers
+		// keep all their digits.
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+
+		record := map[string]interface{}{}
+		if err := dec.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		return record, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+func (r *jsonlReader) Close() error {
+	return r.file.Close()
+}
+
+// parquetReader reads the rows of a parquet file in batches. Rows are
+// converted to JSON objects, so nested columns become nested objects.
+type parquetReader struct {
+	file   source.ParquetFile
+	reader *reader.ParquetReader
+	rows   int64
+	read   int64
+	batch  []map[string]interface{}
+}
+
+func newParquetReader(path string) (RecordReader, error) {
+	f, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := reader.NewParquetReader(f, nil, 1)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &parquetReader{
+		file:   f,
+		reader: pr,
+		rows:   pr.GetNumRows(),
+	}, nil
+}
+
+func (r *parquetReader) Next() (map[string]interface{}, error) {
+	if len(r.batch) == 0 {
+		if err := r.readBatch(); err != nil {
+			return nil, err
+		}
+	}
+
+	record := r.batch[0]
+	r.batch = r.batch[1:]
+
+	return record, nil
+}
+
+func (r *parquetReader) readBatch() error {
+	if r.read >= r.rows {
+		return io.EOF
+	}
+
+	n := r.rows - r.read
+	if n > parquetBatchSize {
+		n = parquetBatchSize
+	}
+
+	rows, err := r.reader.ReadByNumber(int(n))
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return io.EOF
+	}
+
+	r.read += int64(len(rows))
+
+	root := r.reader.SchemaHandler.GetRootInName()
+	for _, row := range rows {
+		record, _ := r.convert(reflect.ValueOf(row), root).(map[string]interface{})
+		r.batch = append(r.batch, record)
+	}
+
+	return nil
+}
+
+// convert converts a value read from a parquet file to its JSON
+// representation. The rows are structs generated from the file's schema,
+// with Go field names, so the column names are looked up from inPath, the
+// path of the value in the generated structs.
+func (r *parquetReader) convert(v reflect.Value, inPath string) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return r.convert(v.Elem(), inPath)
+	case reflect.Struct:
+		res := make(map[string]interface{}, v.NumField())
+
+		for i := 0; i < v.NumField(); i++ {
+			fieldPath := inPath + common.PAR_GO_PATH_DELIMITER + v.Type().Field(i).Name
+			res[r.columnName(fieldPath)] = r.convert(v.Field(i), fieldPath)
+		}
+
+		return res
+	case reflect.Slice:
+		// Lists have List and Element groups between the field and its
+		// items, repeated fields don't.
+		elemPath := inPath + common.PAR_GO_PATH_DELIMITER + "List" + common.PAR_GO_PATH_DELIMITER + "Element"
+		if _, ok := r.reader.SchemaHandler.InPathToExPath[elemPath]; !ok {
+			elemPath = inPath
+		}
+
+		res := make([]interface{}, v.Len())
+		for i := range res {
+			res[i] = r.convert(v.Index(i), elemPath)
+		}
+
+		return res
+	case reflect.Map:
+		valuePath := inPath + common.PAR_GO_PATH_DELIMITER + "Key_value" + common.PAR_GO_PATH_DELIMITER + "Value"
+
+		res := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			res[fmt.Sprint(k.Interface())] = r.convert(v.MapIndex(k), valuePath)
+		}
+
+		return res
+	}
+
+	return v.Interface()
+}
+
+// columnName returns the name of the column at inPath in the file.
+func (r *parquetReader) columnName(inPath string) string {
+	exPath, ok := r.reader.SchemaHandler.InPathToExPath[inPath]
+	if !ok {
+		exPath = inPath
+	}
+
+	return exPath[strings.LastIndex(exPath, common.PAR_GO_PATH_DELIMITER)+1:]
+}
+
+func (r *parquetReader) Close() error {
+	r.reader.ReadStop()
+	return r.file.Close()
+}
@@ -0,0 +1,210 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// OutputFormat is the format a scan's results are written in.
+type OutputFormat string
+
+const (
+	OutputTable = OutputFormat("table")
+	OutputJSON  = OutputFormat("json")
+	OutputSARIF = OutputFormat("sarif")
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// WriteReport writes the findings of a scan of file to w.
+func WriteReport(w io.Writer, format OutputFormat, file string, res Result) error {
+	switch format {
+	case OutputTable:
+		return writeTable(w, res)
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(res)
+	case OutputSARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(sarifReport(file, res))
+	}
+
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+func writeTable(w io.Writer, res Result) error {
+	if len(res.Findings) == 0 {
+		_, err := fmt.Fprintf(w, "No PII found in %d rows.\n", res.RowsScanned)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROPERTY\tCATEGORY\tSCORE\tCONFIDENCE\tRULES\tMATCHES\tSAMPLES")
+
+	for _, f := range res.Findings {
+		matches := "-"
+		if f.ValueCount > 0 {
+			matches = fmt.Sprintf("%d/%d", f.MatchCount, f.ValueCount)
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%.2f\t%s\t%s\t%s\t%s\n",
+			f.Property,
+			f.Category,
+			f.Score,
+			f.Confidence,
+			strings.Join(f.Rules, ","),
+			matches,
+			strings.Join(f.Samples, ", "),
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d findings in %d rows.\n", len(res.Findings), res.RowsScanned)
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevels maps the confidence of a finding to a SARIF level.
+var sarifLevels = map[string]string{
+	"high":   "error",
+	"medium": "warning",
+	"low":    "note",
+}
+
+// sarifReport reports each finding as a result of a rule for its
+// category.
+func sarifReport(file string, res Result) sarifLog {
+	rules := []sarifRule{}
+	results := []sarifResult{}
+
+	for _, f := range res.Findings {
+		ruleID := "pii/" + f.Category
+
+		found := false
+		for _, r := range rules {
+			if r.ID == ruleID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			rules = append(rules, sarifRule{
+				ID:               ruleID,
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("Column contains %s data", f.Category)},
+			})
+		}
+
+		level, ok := sarifLevels[f.Confidence]
+		if !ok {
+			level = "note"
+		}
+
+		results = append(results, sarifResult{
+			RuleID: ruleID,
+			Level:  level,
+			Message: sarifMessage{Text: fmt.Sprintf(
+				"%s looks like %s data (score %.2f, rules %s)",
+				f.Property,
+				f.Category,
+				f.Score,
+				strings.Join(f.Rules, ", "),
+			)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: file},
+				},
+				LogicalLocations: []sarifLogicalLocation{{
+					FullyQualifiedName: f.Property,
+					Kind:               "member",
+				}},
+			}},
+			Properties: map[string]interface{}{
+				"score":      f.Score,
+				"matchCount": f.MatchCount,
+				"valueCount": f.ValueCount,
+				"samples":    f.Samples,
+			},
+		})
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "monoid-scan",
+				InformationURI: "https://github.com/monoid-privacy/monoid",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
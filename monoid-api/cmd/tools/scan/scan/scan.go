@@ -0,0 +1,200 @@
+package scan
+
+import (
+	"io"
+	"sort"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/monoid-privacy/monoid/scanner/basicscanner"
+)
+
+// Options configure a scan.
+type Options struct {
+	// SchemaName is the name records are scanned under, e.g. the file
+	// name.
+	SchemaName string
+
+	// InferRows is the number of records the schema is inferred from.
+	InferRows int
+
+	// Limit is the maximum number of records to scan, 0 scans them all.
+	Limit int
+
+	RuleConfig scanner.RuleConfig
+}
+
+// Finding is a category of data found in a property. The rules that
+// matched the property are combined into one score, the same way the
+// server scores category discoveries.
+type Finding struct {
+	Property   string   `json:"property"`
+	Category   string   `json:"category"`
+	Score      float64  `json:"score"`
+	Confidence string   `json:"confidence"`
+	Rules      []string `json:"rules"`
+	MatchTypes []string `json:"matchTypes"`
+	MatchCount int      `json:"matchCount"`
+	ValueCount int      `json:"valueCount"`
+	Samples    []string `json:"samples,omitempty"`
+}
+
+// Result is the result of scanning a file.
+type Result struct {
+	RowsScanned int       `json:"rowsScanned"`
+	Findings    []Finding `json:"findings"`
+}
+
+var confidenceRank = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// Scan infers a schema from the first records read from r, and scans
+// every record with a basic scanner.
+func Scan(r RecordReader, opts Options) (Result, error) {
+	sample := []map[string]interface{}{}
+
+	for opts.InferRows <= 0 || len(sample) < opts.InferRows {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return Result{}, err
+		}
+
+		sample = append(sample, record)
+	}
+
+	sc, err := basicscanner.NewBasicScanner(monoidprotocol.MonoidSchema{
+		Name:       opts.SchemaName,
+		JsonSchema: InferSchema(sample),
+	}, opts.RuleConfig)
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	rows := 0
+	scan := func(record map[string]interface{}) error {
+		rows++
+
+		return sc.Scan(&monoidprotocol.MonoidRecord{
+			SchemaName: opts.SchemaName,
+			Data:       record,
+		})
+	}
+
+	for _, record := range sample {
+		if opts.Limit > 0 && rows >= opts.Limit {
+			break
+		}
+
+		if err := scan(record); err != nil {
+			return Result{}, err
+		}
+	}
+
+	for opts.Limit <= 0 || rows < opts.Limit {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return Result{}, err
+		}
+
+		if err := scan(record); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return Result{
+		RowsScanned: rows,
+		Findings:    findings(sc.Summary()),
+	}, nil
+}
+
+// findings groups matches by property and category.
+func findings(matches []scanner.RuleMatch) []Finding {
+	type key struct {
+		property string
+		category string
+	}
+
+	grouped := map[key][]scanner.RuleMatch{}
+	for _, m := range matches {
+		k := key{property: m.Identifier, category: m.Category}
+		grouped[k] = append(grouped[k], m)
+	}
+
+	res := make([]Finding, 0, len(grouped))
+
+	for k, ms := range grouped {
+		f := Finding{
+			Property: k.property,
+			Category: k.category,
+			Rules:    []string{},
+		}
+
+		scores := make([]float64, 0, len(ms))
+
+		for _, m := range ms {
+			scores = append(scores, m.Score)
+
+			if !containsString(f.Rules, m.RuleName) {
+				f.Rules = append(f.Rules, m.RuleName)
+			}
+
+			if !containsString(f.MatchTypes, m.MatchType) {
+				f.MatchTypes = append(f.MatchTypes, m.MatchType)
+			}
+
+			if confidenceRank[m.Confidence] > confidenceRank[f.Confidence] {
+				f.Confidence = m.Confidence
+			}
+
+			f.MatchCount += m.ValidatedCount
+			if m.ValueCount > f.ValueCount {
+				f.ValueCount = m.ValueCount
+			}
+
+			for _, sample := range m.Samples {
+				if !containsString(f.Samples, sample) {
+					f.Samples = append(f.Samples, sample)
+				}
+			}
+		}
+
+		sort.Strings(f.Rules)
+		sort.Strings(f.MatchTypes)
+		sort.Strings(f.Samples)
+
+		f.Score = scanner.CombineScores(scores...)
+		res = append(res, f)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+
+		if res[i].Property != res[j].Property {
+			return res[i].Property < res[j].Property
+		}
+
+		return res[i].Category < res[j].Category
+	})
+
+	return res
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
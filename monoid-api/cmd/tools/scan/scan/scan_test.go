@@ -0,0 +1,103 @@
+package scan
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func TestInferSchema(t *testing.T) {
+	schema := InferSchema([]map[string]interface{}{
+		{"id": float64(1), "email": "jane@example.com", "user": map[string]interface{}{"ip": "10.0.0.1"}},
+		{"id": "2", "email": nil, "tags": []interface{}{"a"}},
+	})
+
+	props := schema["properties"].(map[string]interface{})
+	assert.Equal(t, "string", props["id"].(map[string]interface{})["type"])
+	assert.Equal(t, []interface{}{"string", "null"}, props["email"].(map[string]interface{})["type"])
+	assert.Equal(t, "object", props["user"].(map[string]interface{})["type"])
+	assert.Equal(t, "array", props["tags"].(map[string]interface{})["type"])
+}
+
+func TestScanCSV(t *testing.T) {
+	r, err := newCSVReader(io.NopCloser(strings.NewReader(
+		"id,email,notes\n" +
+			"1,jane@example.com,called\n" +
+			"2,joe@example.org,\n" +
+			"3,jim@example.net,left a message\n",
+	)))
+	assert.NoError(t, err)
+
+	res, err := Scan(r, Options{SchemaName: "users", InferRows: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.RowsScanned)
+
+	assert.Len(t, res.Findings, 1)
+	assert.Equal(t, "email", res.Findings[0].Property)
+	assert.Equal(t, "email", res.Findings[0].Category)
+	assert.Equal(t, 3, res.Findings[0].MatchCount)
+}
+
+func TestJSONLReader(t *testing.T) {
+	r := newJSONLReader(io.NopCloser(strings.NewReader(
+		`{"id": 4111111111111111, "email": "jane@example.com"}` + "\n\n" +
+			`{"id": 12345678901234567890}` + "\n",
+	)))
+
+	defer r.Close()
+
+	record, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("4111111111111111"), record["id"])
+	assert.Equal(t, "jane@example.com", record["email"])
+
+	record, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("12345678901234567890"), record["id"])
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+type parquetUser struct {
+	ID    int64  `parquet:"name=id, type=INT64"`
+	Email string `parquet:"name=email_address, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func TestParquetReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.parquet")
+
+	fw, err := local.NewLocalFileWriter(path)
+	assert.NoError(t, err)
+
+	pw, err := writer.NewParquetWriter(fw, new(parquetUser), 1)
+	assert.NoError(t, err)
+
+	for i, email := range []string{"jane@example.com", "joe@example.org"} {
+		assert.NoError(t, pw.Write(parquetUser{ID: int64(i), Email: email}))
+	}
+
+	assert.NoError(t, pw.WriteStop())
+	assert.NoError(t, fw.Close())
+
+	r, err := OpenFile(path, FormatParquet)
+	assert.NoError(t, err)
+
+	defer r.Close()
+
+	record, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": int64(0), "email_address": "jane@example.com"}, record)
+
+	_, err = r.Next()
+	assert.NoError(t, err)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
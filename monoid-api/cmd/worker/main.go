@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -37,6 +38,16 @@ func main() {
 		MaxConcurrentWorkflowTaskExecutionSize: 5,
 	})
 
+	purgeSchedule := os.Getenv("PURGE_SCHEDULE")
+	if purgeSchedule == "" {
+		purgeSchedule = "0 3 * * *"
+	}
+
+	// Create the purge schedule, or update it if PURGE_SCHEDULE changed.
+	if err := workflow.UpsertPurgeSchedule(context.Background(), c, purgeSchedule); err != nil {
+		log.Fatalln("unable to create purge schedule", err)
+	}
+
 	mworker.RegisterWorkerWorkflowActivities(
 		w,
 		mworker.DefaultActivites(&conf),
@@ -21,8 +21,14 @@ func DefaultActivites(conf *config.BaseConfig) []interface{} {
 	return []interface{}{
 		a.ValidateDataSiloDef,
 		a.DetectDataSources,
+		a.DetectDataSourcesWithEvent,
 		a.FindOrCreateJob,
 		a.UpdateJobStatus,
+		a.FindExpiredRequests,
+		a.PurgeRequestData,
+		a.PreviewDataSource,
+		a.FindNotificationChannels,
+		a.SendNotification,
 		ra.UpdateRequestStatusActivity,
 		ra.FindDBSilos,
 		ra.ProcessRequestResults,
@@ -44,6 +50,9 @@ func DefaultWorkflows(conf *config.BaseConfig) []interface{} {
 	return []interface{}{
 		mwf.ValidateDSWorkflow,
 		mwf.DetectDSWorkflow,
+		mwf.DetectAllDSWorkflow,
+		mwf.PurgeExpiredDataWorkflow,
+		mwf.PreviewDataSourceWorkflow,
 		rmwf.ExecuteRequestWorkflow,
 		rmwf.ExecuteSiloRequestWorkflow,
 	}
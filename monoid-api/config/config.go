@@ -5,6 +5,7 @@ import (
 
 	"github.com/monoid-privacy/monoid/analytics/ingestor"
 	"github.com/monoid-privacy/monoid/filestore"
+	"github.com/monoid-privacy/monoid/kms"
 	"github.com/monoid-privacy/monoid/monoidprotocol"
 	"go.temporal.io/sdk/client"
 	"gorm.io/gorm"
@@ -19,7 +20,11 @@ type BaseConfig struct {
 	TemporalClient    client.Client
 	AnalyticsIngestor ingestor.Ingestor
 	EncryptionKey     []byte
+	KeyProvider       kms.KeyProvider
 	ResourcePath      string
+
+	// ScanWorkers is the number of goroutines that scan records for PII.
+	ScanWorkers int
 }
 
 func (c BaseConfig) PreFlightHandler(next http.Handler) http.Handler {
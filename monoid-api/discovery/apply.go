@@ -0,0 +1,443 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrPropertyLinked is returned when accepting a primary key suggestion for
+// a property that is already linked to a user primary key.
+var ErrPropertyLinked = errors.New("property is already linked to a user primary key")
+
+func categoriesForDiscoveries(discoveries []model.NewCategoryDiscovery) []*model.Category {
+	categories := []*model.Category{}
+
+	for _, d := range discoveries {
+		categories = append(categories, &model.Category{
+			ID: d.CategoryID,
+		})
+	}
+
+	return categories
+}
+
+func propertiesForDiscoveries(discoveries []model.NewPropertyDiscovery) []*model.Property {
+	properties := []*model.Property{}
+
+	for _, d := range discoveries {
+		properties = append(properties, &model.Property{
+			Name:       d.Name,
+			ID:         uuid.NewString(),
+			Categories: categoriesForDiscoveries(d.Categories),
+			Type:       d.Type,
+			Format:     d.Format,
+
+			UserPrimaryKeyID: d.UserPrimaryKeyID,
+		})
+	}
+
+	return properties
+}
+
+// Apply accepts or rejects discoveries, and records the decision. Accepting
+// a discovery applies it to the data map, and a new version of the data map
+// of each silo that changed is recorded in the same transaction. Decisions
+// made by a policy are recorded with the policy, which is nil for decisions
+// made by hand. Returns the discoveries that were applied, and the errors
+// applying the others.
+func Apply(
+	conf *config.BaseConfig,
+	discoveries []*model.DataDiscovery,
+	action model.DiscoveryAction,
+	policy *model.DiscoveryPolicy,
+) ([]*model.DataDiscovery, []error) {
+	var res []*model.DataDiscovery
+	var errs []error
+
+	if err := conf.DB.Transaction(func(tx *gorm.DB) error {
+		res, errs = apply(conf, tx, discoveries, action, policy)
+
+		if action == model.DiscoveryActionAccept {
+			return snapshotDataMaps(tx, res)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, append(errs, err)
+	}
+
+	return res, errs
+}
+
+// snapshotDataMaps records a new version of the data map of each silo that
+// had discoveries accepted.
+func snapshotDataMaps(tx *gorm.DB, accepted []*model.DataDiscovery) error {
+	silos := map[string]bool{}
+
+	for _, d := range accepted {
+		if silos[d.SiloDefinitionID] {
+			continue
+		}
+
+		silos[d.SiloDefinitionID] = true
+
+		if _, err := model.CreateDataMapSnapshot(
+			d.SiloDefinitionID,
+			model.SnapshotReasonDiscoveries,
+			tx,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply accepts or rejects each discovery in its own nested transaction of
+// db, so that a discovery that fails doesn't undo the others.
+func apply(
+	conf *config.BaseConfig,
+	db *gorm.DB,
+	discoveries []*model.DataDiscovery,
+	action model.DiscoveryAction,
+	policy *model.DiscoveryPolicy,
+) ([]*model.DataDiscovery, []error) {
+	res := make([]*model.DataDiscovery, 0, len(discoveries))
+	errors := make([]error, 0, len(discoveries))
+
+	for _, discovery := range discoveries {
+		analyticsData := map[string]interface{}{
+			"action": action.String(),
+			"siloId": discovery.SiloDefinitionID,
+		}
+
+		if policy != nil {
+			analyticsData["policyId"] = policy.ID
+		}
+
+		conf.AnalyticsIngestor.Track("discoveryAction", nil, analyticsData)
+
+		if action == model.DiscoveryActionReject {
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				return setStatus(tx, discovery, model.DiscoveryStatusRejected, policy, nil)
+			}); err != nil {
+				errors = append(errors, err)
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				continue
+			}
+
+			res = append(res, discovery)
+			continue
+		}
+
+		switch discovery.Type {
+		case model.DiscoveryTypeCategoryFound:
+			data := model.NewCategoryDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil || data.PropertyID == nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				prop := &model.Property{ID: *data.PropertyID}
+
+				// The category may have been added by hand, in which case
+				// reverting the discovery has to keep it.
+				existing := tx.Model(prop).Where("categories.id = ?", data.CategoryID).Association("Categories")
+				count := existing.Count()
+				if existing.Error != nil {
+					return existing.Error
+				}
+
+				effect := &model.DiscoveryEffect{CategoryAdded: count == 0}
+
+				if err := tx.Model(prop).Association("Categories").Append(
+					&model.Category{
+						ID: data.CategoryID,
+					},
+				); err != nil {
+					return err
+				}
+
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, effect); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		case model.DiscoveryTypeDataSourceFound:
+			data := model.NewDataSourceDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				dataSource := model.DataSource{
+					ID:               uuid.NewString(),
+					Group:            data.Group,
+					Name:             data.Name,
+					SiloDefinitionID: discovery.SiloDefinitionID,
+					Properties:       propertiesForDiscoveries(data.Properties),
+				}
+
+				if err := tx.Create(&dataSource).Error; err != nil {
+					return err
+				}
+
+				effect := &model.DiscoveryEffect{DataSourceID: &dataSource.ID}
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, effect); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		case model.DiscoveryTypePropertyFound:
+			data := model.NewPropertyDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil || data.DataSourceId == nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				prop := propertiesForDiscoveries([]model.NewPropertyDiscovery{data})[0]
+				prop.DataSourceID = *data.DataSourceId
+
+				if err := tx.Create(&prop).Error; err != nil {
+					return err
+				}
+
+				effect := &model.DiscoveryEffect{PropertyIDs: []string{prop.ID}}
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, effect); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		case model.DiscoveryTypeDataSourceMissing:
+			data := model.DataSourceMissingDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				ds := model.DataSource{}
+				if err := tx.Model(&model.DataSource{}).Preload("Properties.Categories").Where(
+					"id = ?",
+					data.ID,
+				).First(&ds).Error; err != nil {
+					return err
+				}
+
+				effect := deletedPropertiesEffect(ds.Properties)
+				effect.DataSourceID = &ds.ID
+
+				if err := tx.Model(&ds.Properties).Association("Categories").Clear(); err != nil {
+					return err
+				}
+
+				if err := tx.Model(&model.Property{}).Where(
+					"data_source_id = ?", data.ID,
+				).Delete(nil).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Model(&model.DataSource{}).Where(
+					"id = ?",
+					data.ID,
+				).Delete(nil).Error; err != nil {
+					return err
+				}
+
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, effect); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		case model.DiscoveryTypePropertyChanged:
+			data := model.PropertyChangedDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&model.Property{ID: data.ID}).Updates(map[string]interface{}{
+					"type":   data.NewType,
+					"format": data.NewFormat,
+				}).Error; err != nil {
+					return err
+				}
+
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, nil); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		case model.DiscoveryTypePrimaryKeySuggested:
+			data := model.PrimaryKeySuggestedDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				prop := model.Property{}
+				if err := tx.Where("id = ?", data.PropertyID).First(&prop).Error; err != nil {
+					return err
+				}
+
+				// The property may have been linked by hand since the
+				// suggestion was made.
+				if prop.UserPrimaryKeyID != nil {
+					return ErrPropertyLinked
+				}
+
+				if err := tx.Model(&prop).Update(
+					"user_primary_key_id", data.UserPrimaryKeyID,
+				).Error; err != nil {
+					return err
+				}
+
+				// The property wasn't linked before, so reverting only has to
+				// unlink it and no effect is recorded.
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, nil); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		case model.DiscoveryTypePropertyMissing:
+			data := model.PropertyMissingDiscovery{}
+			if err := json.Unmarshal(discovery.Data, &data); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				prop := model.Property{}
+				if err := tx.Preload("Categories").Where("id = ?", data.ID).First(&prop).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Select("Categories").Delete(&model.Property{ID: data.ID}).Error; err != nil {
+					return err
+				}
+
+				effect := deletedPropertiesEffect([]*model.Property{&prop})
+				if err := setStatus(tx, discovery, model.DiscoveryStatusAccepted, policy, effect); err != nil {
+					return err
+				}
+
+				return nil
+			}); err != nil {
+				log.Err(err).Msgf("Error updating %s", discovery.ID)
+				errors = append(errors, err)
+				continue
+			}
+		}
+
+		res = append(res, discovery)
+	}
+
+	return res, errors
+}
+
+// deletedPropertiesEffect records the properties that are deleted by
+// accepting a discovery, with their categories.
+func deletedPropertiesEffect(properties []*model.Property) *model.DiscoveryEffect {
+	effect := &model.DiscoveryEffect{
+		PropertyIDs: make([]string, 0, len(properties)),
+		Categories:  map[string][]string{},
+	}
+
+	for _, p := range properties {
+		effect.PropertyIDs = append(effect.PropertyIDs, p.ID)
+
+		categoryIDs := make([]string, 0, len(p.Categories))
+		for _, c := range p.Categories {
+			categoryIDs = append(categoryIDs, c.ID)
+		}
+
+		effect.Categories[p.ID] = categoryIDs
+	}
+
+	return effect
+}
+
+// setStatus updates the status of a discovery, and records the decision
+// with its effect, which is nil if reverting the decision only needs the
+// discovery's data.
+func setStatus(
+	tx *gorm.DB,
+	discovery *model.DataDiscovery,
+	status model.DiscoveryStatus,
+	policy *model.DiscoveryPolicy,
+	effect *model.DiscoveryEffect,
+) error {
+	if err := tx.Model(discovery).Update("status", status).Error; err != nil {
+		return err
+	}
+
+	decision := model.DiscoveryDecision{
+		ID:              uuid.NewString(),
+		DataDiscoveryID: discovery.ID,
+		Status:          status,
+	}
+
+	if policy != nil {
+		decision.PolicyID = &policy.ID
+		decision.PolicyName = &policy.Name
+	}
+
+	if effect != nil {
+		data, err := json.Marshal(effect)
+		if err != nil {
+			return err
+		}
+
+		decision.Effect = data
+	}
+
+	return tx.Create(&decision).Error
+}
@@ -0,0 +1,311 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"gorm.io/gorm"
+)
+
+const testEncKey = "Tc7ILcxCi68Xk7646IrNBYmbMzbWNU+s94fnZMJ1zzk="
+
+// nopIngestor discards analytics events.
+type nopIngestor struct{}
+
+func (nopIngestor) Identify(*string, map[string]interface{}) error      { return nil }
+func (nopIngestor) Track(string, *string, map[string]interface{}) error { return nil }
+func (nopIngestor) Close() error                                        { return nil }
+
+type applyTestSuite struct {
+	suite.Suite
+
+	container testcontainers.Container
+	db        *gorm.DB
+	conf      *config.BaseConfig
+}
+
+func (s *applyTestSuite) SetupSuite() {
+	container, db, err := testutil.SetupDB()
+	if err != nil {
+		s.T().Skipf("could not start postgres: %v", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(testEncKey)
+	s.Require().NoError(err)
+	model.SetEncryptionKey(key)
+
+	s.container = container
+	s.db = db
+	s.conf = &config.BaseConfig{DB: db, AnalyticsIngestor: nopIngestor{}}
+}
+
+func (s *applyTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+// dataSource creates a data source in a new silo, and a user primary key
+// in the silo's workspace.
+func (s *applyTestSuite) dataSource() (model.DataSource, model.UserPrimaryKey) {
+	w := model.Workspace{ID: uuid.NewString()}
+	s.Require().NoError(s.db.Create(&w).Error)
+
+	spec := model.SiloSpecification{ID: uuid.NewString(), Name: "spec"}
+	s.Require().NoError(s.db.Create(&spec).Error)
+
+	silo := model.SiloDefinition{
+		ID:                  uuid.NewString(),
+		WorkspaceID:         w.ID,
+		SiloSpecificationID: spec.ID,
+	}
+	s.Require().NoError(s.db.Create(&silo).Error)
+
+	ds := model.DataSource{ID: uuid.NewString(), SiloDefinitionID: silo.ID, Name: "users"}
+	s.Require().NoError(s.db.Create(&ds).Error)
+
+	pk := model.UserPrimaryKey{ID: uuid.NewString(), WorkspaceID: w.ID, APIIdentifier: "email"}
+	s.Require().NoError(s.db.Create(&pk).Error)
+
+	return ds, pk
+}
+
+func (s *applyTestSuite) discovery(
+	siloID string,
+	typ model.DiscoveryType,
+	data interface{},
+) *model.DataDiscovery {
+	raw, err := json.Marshal(data)
+	s.Require().NoError(err)
+
+	d := &model.DataDiscovery{
+		ID:               uuid.NewString(),
+		SiloDefinitionID: siloID,
+		Type:             typ,
+		Status:           model.DiscoveryStatusOpen,
+		Data:             raw,
+	}
+	s.Require().NoError(s.db.Create(d).Error)
+
+	return d
+}
+
+func (s *applyTestSuite) property(id string) model.Property {
+	prop := model.Property{}
+	s.Require().NoError(s.db.Where("id = ?", id).First(&prop).Error)
+
+	return prop
+}
+
+func (s *applyTestSuite) propertyWithCategories(id string) model.Property {
+	prop := model.Property{}
+	s.Require().NoError(s.db.Preload("Categories").Where("id = ?", id).First(&prop).Error)
+
+	return prop
+}
+
+func (s *applyTestSuite) TestAcceptPrimaryKeyLinked() {
+	ds, pk := s.dataSource()
+
+	other := model.UserPrimaryKey{ID: uuid.NewString(), WorkspaceID: pk.WorkspaceID, APIIdentifier: "user_id"}
+	s.Require().NoError(s.db.Create(&other).Error)
+
+	// The property was linked by hand after the suggestion was made.
+	prop := model.Property{ID: uuid.NewString(), DataSourceID: ds.ID, Name: "email", UserPrimaryKeyID: &other.ID}
+	s.Require().NoError(s.db.Create(&prop).Error)
+
+	d := s.discovery(ds.SiloDefinitionID, model.DiscoveryTypePrimaryKeySuggested, model.PrimaryKeySuggestedDiscovery{
+		PropertyID:       prop.ID,
+		UserPrimaryKeyID: pk.ID,
+		CategoryID:       "email",
+	})
+
+	_, errs := Apply(s.conf, []*model.DataDiscovery{d}, model.DiscoveryActionAccept, nil)
+	s.Require().Len(errs, 1)
+	s.ErrorIs(errs[0], ErrPropertyLinked)
+
+	s.Equal(&other.ID, s.property(prop.ID).UserPrimaryKeyID)
+}
+
+func (s *applyTestSuite) TestAcceptAndRevertPrimaryKey() {
+	ds, pk := s.dataSource()
+
+	prop := model.Property{ID: uuid.NewString(), DataSourceID: ds.ID, Name: "email"}
+	s.Require().NoError(s.db.Create(&prop).Error)
+
+	d := s.discovery(ds.SiloDefinitionID, model.DiscoveryTypePrimaryKeySuggested, model.PrimaryKeySuggestedDiscovery{
+		PropertyID:       prop.ID,
+		UserPrimaryKeyID: pk.ID,
+		CategoryID:       "email",
+	})
+
+	_, errs := Apply(s.conf, []*model.DataDiscovery{d}, model.DiscoveryActionAccept, nil)
+	s.Require().Empty(errs)
+	s.Equal(&pk.ID, s.property(prop.ID).UserPrimaryKeyID)
+
+	_, err := Revert(s.conf, d)
+	s.Require().NoError(err)
+	s.Nil(s.property(prop.ID).UserPrimaryKeyID)
+
+	// Linking the key and unlinking it are both new versions.
+	versions := s.versions(ds.SiloDefinitionID)
+	s.Require().Len(versions, 2)
+
+	for i, want := range []*string{&pk.ID, nil} {
+		dataSources, err := versions[i].DataSources()
+		s.Require().NoError(err)
+		s.Require().Len(dataSources, 1)
+		s.Require().Len(dataSources[0].Properties, 1)
+		s.Equal(want, dataSources[0].Properties[0].UserPrimaryKeyID)
+	}
+}
+
+func (s *applyTestSuite) TestAcceptPropertyFoundWithPrimaryKey() {
+	ds, pk := s.dataSource()
+
+	d := s.discovery(ds.SiloDefinitionID, model.DiscoveryTypePropertyFound, model.NewPropertyDiscovery{
+		Name:             "email",
+		DataSourceId:     &ds.ID,
+		UserPrimaryKeyID: &pk.ID,
+	})
+
+	_, errs := Apply(s.conf, []*model.DataDiscovery{d}, model.DiscoveryActionAccept, nil)
+	s.Require().Empty(errs)
+
+	prop := model.Property{}
+	s.Require().NoError(s.db.Where("data_source_id = ? AND name = ?", ds.ID, "email").First(&prop).Error)
+	s.Equal(&pk.ID, prop.UserPrimaryKeyID)
+}
+
+// versions returns the data map versions of a silo, oldest first.
+func (s *applyTestSuite) versions(siloID string) []model.DataMapSnapshot {
+	res := []model.DataMapSnapshot{}
+	s.Require().NoError(s.db.Where("silo_definition_id = ?", siloID).Order("version").Find(&res).Error)
+
+	return res
+}
+
+func (s *applyTestSuite) TestAcceptAndRevertRecordVersions() {
+	ds, _ := s.dataSource()
+
+	d := s.discovery(ds.SiloDefinitionID, model.DiscoveryTypePropertyFound, model.NewPropertyDiscovery{
+		Name:         "email",
+		DataSourceId: &ds.ID,
+	})
+
+	_, errs := Apply(s.conf, []*model.DataDiscovery{d}, model.DiscoveryActionAccept, nil)
+	s.Require().Empty(errs)
+
+	versions := s.versions(ds.SiloDefinitionID)
+	s.Require().Len(versions, 1)
+	s.Equal(model.SnapshotReasonDiscoveries, versions[0].Reason)
+
+	dataSources, err := versions[0].DataSources()
+	s.Require().NoError(err)
+	s.Require().Len(dataSources, 1)
+	s.Require().Len(dataSources[0].Properties, 1)
+	s.Equal("email", dataSources[0].Properties[0].Name)
+
+	_, err = Revert(s.conf, d)
+	s.Require().NoError(err)
+
+	versions = s.versions(ds.SiloDefinitionID)
+	s.Require().Len(versions, 2)
+
+	dataSources, err = versions[1].DataSources()
+	s.Require().NoError(err)
+	s.Require().Len(dataSources, 1)
+	s.Empty(dataSources[0].Properties)
+}
+
+func (s *applyTestSuite) TestAcceptPropertyChangedRecordsVersion() {
+	ds, _ := s.dataSource()
+
+	oldType := "string"
+	prop := model.Property{ID: uuid.NewString(), DataSourceID: ds.ID, Name: "age", Type: &oldType}
+	s.Require().NoError(s.db.Create(&prop).Error)
+
+	_, err := model.CreateDataMapSnapshot(ds.SiloDefinitionID, model.SnapshotReasonManualEdit, s.db)
+	s.Require().NoError(err)
+
+	newType := "integer"
+	d := s.discovery(ds.SiloDefinitionID, model.DiscoveryTypePropertyChanged, model.PropertyChangedDiscovery{
+		ID:      prop.ID,
+		OldType: &oldType,
+		NewType: &newType,
+	})
+
+	_, errs := Apply(s.conf, []*model.DataDiscovery{d}, model.DiscoveryActionAccept, nil)
+	s.Require().Empty(errs)
+
+	versions := s.versions(ds.SiloDefinitionID)
+	s.Require().Len(versions, 2)
+
+	from, err := versions[0].DataSources()
+	s.Require().NoError(err)
+
+	to, err := versions[1].DataSources()
+	s.Require().NoError(err)
+
+	changes := model.DiffDataMaps(from, to)
+	s.Require().Len(changes, 1)
+	s.Equal(model.DataMapChangeTypeTypeChanged, changes[0].Type)
+	s.Equal(&newType, changes[0].NewType)
+}
+
+func (s *applyTestSuite) TestRevertCategoryFound() {
+	ds, _ := s.dataSource()
+
+	category := model.Category{ID: uuid.NewString(), Name: "Email"}
+	s.Require().NoError(s.db.Create(&category).Error)
+
+	added := model.Property{ID: uuid.NewString(), DataSourceID: ds.ID, Name: "email"}
+	s.Require().NoError(s.db.Create(&added).Error)
+
+	// The category was added by hand before the discovery was accepted.
+	existing := model.Property{
+		ID:           uuid.NewString(),
+		DataSourceID: ds.ID,
+		Name:         "contact",
+		Categories:   []*model.Category{{ID: category.ID}},
+	}
+	s.Require().NoError(s.db.Omit("Categories.*").Create(&existing).Error)
+
+	categories := func(propertyID string) []string {
+		ids := []string{}
+		for _, c := range s.propertyWithCategories(propertyID).Categories {
+			ids = append(ids, c.ID)
+		}
+
+		return ids
+	}
+
+	for _, prop := range []model.Property{added, existing} {
+		d := s.discovery(ds.SiloDefinitionID, model.DiscoveryTypeCategoryFound, model.NewCategoryDiscovery{
+			PropertyID: &prop.ID,
+			CategoryID: category.ID,
+		})
+
+		_, errs := Apply(s.conf, []*model.DataDiscovery{d}, model.DiscoveryActionAccept, nil)
+		s.Require().Empty(errs)
+		s.Equal([]string{category.ID}, categories(prop.ID))
+
+		_, err := Revert(s.conf, d)
+		s.Require().NoError(err)
+	}
+
+	s.Empty(categories(added.ID))
+	s.Equal([]string{category.ID}, categories(existing.ID))
+}
+
+func TestApplySuite(t *testing.T) {
+	suite.Run(t, new(applyTestSuite))
+}
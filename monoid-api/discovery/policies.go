@@ -0,0 +1,301 @@
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"gorm.io/gorm"
+)
+
+// actionPrecedence is used to pick the policy that handles a discovery
+// when several match it, the most conservative action wins.
+var actionPrecedence = map[model.DiscoveryPolicyAction]int{
+	model.DiscoveryPolicyActionAccept: 0,
+	model.DiscoveryPolicyActionReject: 1,
+	model.DiscoveryPolicyActionReview: 2,
+}
+
+// Target is what a policy is matched against: a discovery, with the data
+// source and categories it refers to.
+type Target struct {
+	Discovery *model.DataDiscovery
+
+	// DataSourceName is the name of the discovery's data source, it's nil
+	// if the data source couldn't be found.
+	DataSourceName *string
+
+	// Categories are the categories the discovery found, for new data
+	// sources these are the categories of all their properties.
+	Categories []model.NewCategoryDiscovery
+}
+
+// compiledPolicy is a policy with its conditions decoded.
+type compiledPolicy struct {
+	policy     *model.DiscoveryPolicy
+	types      map[model.DiscoveryType]bool
+	categories map[string]bool
+	pattern    *regexp.Regexp
+}
+
+func compilePolicy(policy *model.DiscoveryPolicy) (compiledPolicy, error) {
+	res := compiledPolicy{
+		policy:     policy,
+		types:      map[model.DiscoveryType]bool{},
+		categories: map[string]bool{},
+	}
+
+	types, err := policy.DiscoveryTypeList()
+	if err != nil {
+		return compiledPolicy{}, err
+	}
+
+	for _, t := range types {
+		res.types[t] = true
+	}
+
+	categories, err := policy.CategoryIDList()
+	if err != nil {
+		return compiledPolicy{}, err
+	}
+
+	for _, c := range categories {
+		res.categories[c] = true
+	}
+
+	if policy.DataSourcePattern != nil {
+		res.pattern, err = regexp.Compile(*policy.DataSourcePattern)
+		if err != nil {
+			return compiledPolicy{}, fmt.Errorf("invalid data source pattern: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// matches returns true if the target meets all of the policy's
+// conditions. A discovery that didn't find any categories doesn't match
+// a policy with categories or a minimum score.
+func (p compiledPolicy) matches(t Target) bool {
+	if len(p.types) != 0 && !p.types[t.Discovery.Type] {
+		return false
+	}
+
+	if p.policy.SiloDefinitionID != nil && *p.policy.SiloDefinitionID != t.Discovery.SiloDefinitionID {
+		return false
+	}
+
+	if p.pattern != nil && (t.DataSourceName == nil || !p.pattern.MatchString(*t.DataSourceName)) {
+		return false
+	}
+
+	if len(p.categories) != 0 {
+		found := false
+		for _, c := range t.Categories {
+			if p.categories[c.CategoryID] {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if p.policy.MinScore != nil {
+		if len(t.Categories) == 0 {
+			return false
+		}
+
+		for _, c := range t.Categories {
+			if c.Score == nil || *c.Score < *p.policy.MinScore {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// decide returns the policy that handles the target, or nil if no policy
+// matches it.
+func decide(policies []compiledPolicy, t Target) *model.DiscoveryPolicy {
+	var res *model.DiscoveryPolicy
+
+	for _, p := range policies {
+		if !p.matches(t) {
+			continue
+		}
+
+		if res == nil || actionPrecedence[p.policy.Action] > actionPrecedence[res.Action] {
+			res = p.policy
+		}
+	}
+
+	return res
+}
+
+// targetBuilder looks up the data sources of discoveries, caching the
+// results since discoveries from a scan mostly refer to the same ones.
+type targetBuilder struct {
+	db          *gorm.DB
+	dataSources map[string]*string
+	properties  map[string]*string
+}
+
+func (b *targetBuilder) dataSourceName(id string) *string {
+	if name, ok := b.dataSources[id]; ok {
+		return name
+	}
+
+	ds := model.DataSource{}
+	if err := b.db.Where("id = ?", id).First(&ds).Error; err != nil {
+		b.dataSources[id] = nil
+		return nil
+	}
+
+	b.dataSources[id] = &ds.Name
+
+	return &ds.Name
+}
+
+func (b *targetBuilder) propertyDataSourceName(id string) *string {
+	if name, ok := b.properties[id]; ok {
+		return name
+	}
+
+	prop := model.Property{}
+	if err := b.db.Where("id = ?", id).First(&prop).Error; err != nil {
+		b.properties[id] = nil
+		return nil
+	}
+
+	b.properties[id] = b.dataSourceName(prop.DataSourceID)
+
+	return b.properties[id]
+}
+
+func (b *targetBuilder) target(d *model.DataDiscovery) (Target, error) {
+	t := Target{Discovery: d}
+
+	data, err := d.DeserializeData()
+	if err != nil {
+		return Target{}, err
+	}
+
+	switch v := data.(type) {
+	case model.NewDataSourceDiscovery:
+		t.DataSourceName = &v.Name
+		for _, p := range v.Properties {
+			t.Categories = append(t.Categories, p.Categories...)
+		}
+	case model.NewPropertyDiscovery:
+		if v.DataSourceId != nil {
+			t.DataSourceName = b.dataSourceName(*v.DataSourceId)
+		}
+
+		t.Categories = v.Categories
+	case model.NewCategoryDiscovery:
+		if v.PropertyID != nil {
+			t.DataSourceName = b.propertyDataSourceName(*v.PropertyID)
+		}
+
+		t.Categories = []model.NewCategoryDiscovery{v}
+	case model.PropertyMissingDiscovery:
+		t.DataSourceName = b.propertyDataSourceName(v.ID)
+	case model.PropertyChangedDiscovery:
+		t.DataSourceName = b.propertyDataSourceName(v.ID)
+	case model.PrimaryKeySuggestedDiscovery:
+		t.DataSourceName = b.propertyDataSourceName(v.PropertyID)
+		t.Categories = []model.NewCategoryDiscovery{{
+			PropertyID: &v.PropertyID,
+			CategoryID: v.CategoryID,
+		}}
+	case model.DataSourceMissingDiscovery:
+		t.DataSourceName = b.dataSourceName(v.ID)
+	}
+
+	return t, nil
+}
+
+// ApplyPolicies accepts or rejects the discoveries that match one of the
+// workspace's policies, recording the policy that made each decision.
+// Discoveries that don't match a policy, or match a REVIEW policy, are
+// left open. Returns the number of discoveries that were handled.
+func ApplyPolicies(
+	conf *config.BaseConfig,
+	workspaceID string,
+	discoveries []*model.DataDiscovery,
+) (int, []error) {
+	policies := []*model.DiscoveryPolicy{}
+	if err := conf.DB.Where("workspace_id = ?", workspaceID).Order(
+		"created_at",
+	).Find(&policies).Error; err != nil {
+		return 0, []error{err}
+	}
+
+	if len(policies) == 0 {
+		return 0, nil
+	}
+
+	errs := []error{}
+	compiled := make([]compiledPolicy, 0, len(policies))
+
+	for _, p := range policies {
+		c, err := compilePolicy(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policy %s: %w", p.ID, err))
+			continue
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	handled := 0
+
+	if err := conf.DB.Transaction(func(tx *gorm.DB) error {
+		builder := targetBuilder{
+			db:          tx,
+			dataSources: map[string]*string{},
+			properties:  map[string]*string{},
+		}
+
+		accepted := []*model.DataDiscovery{}
+
+		for _, d := range discoveries {
+			t, err := builder.target(d)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			policy := decide(compiled, t)
+			if policy == nil || policy.Action == model.DiscoveryPolicyActionReview {
+				continue
+			}
+
+			action := model.DiscoveryActionAccept
+			if policy.Action == model.DiscoveryPolicyActionReject {
+				action = model.DiscoveryActionReject
+			}
+
+			res, applyErrs := apply(conf, tx, []*model.DataDiscovery{d}, action, policy)
+			handled += len(res)
+			errs = append(errs, applyErrs...)
+
+			if action == model.DiscoveryActionAccept {
+				accepted = append(accepted, res...)
+			}
+		}
+
+		// The discoveries a scan accepts are one batch, so they're recorded
+		// as a single version of the data map.
+		return snapshotDataMaps(tx, accepted)
+	}); err != nil {
+		return 0, append(errs, err)
+	}
+
+	return handled, errs
+}
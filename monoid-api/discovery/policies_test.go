@@ -0,0 +1,152 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy(
+	t *testing.T,
+	action model.DiscoveryPolicyAction,
+	types []model.DiscoveryType,
+	categories []string,
+	mod func(p *model.DiscoveryPolicy),
+) compiledPolicy {
+	typesJSON, err := json.Marshal(types)
+	assert.NoError(t, err)
+
+	categoriesJSON, err := json.Marshal(categories)
+	assert.NoError(t, err)
+
+	p := &model.DiscoveryPolicy{
+		ID:             string(action),
+		Action:         action,
+		DiscoveryTypes: typesJSON,
+		CategoryIDs:    categoriesJSON,
+	}
+
+	if mod != nil {
+		mod(p)
+	}
+
+	c, err := compilePolicy(p)
+	assert.NoError(t, err)
+
+	return c
+}
+
+func TestDecide(t *testing.T) {
+	silo := "silo1"
+	pattern := "^users"
+	minScore := 0.8
+	highScore := 0.9
+	lowScore := 0.5
+	usersTable := "users_v2"
+	ordersTable := "orders"
+
+	acceptFound := testPolicy(t, model.DiscoveryPolicyActionAccept, []model.DiscoveryType{
+		model.DiscoveryTypePropertyFound,
+		model.DiscoveryTypePropertyMissing,
+	}, nil, func(p *model.DiscoveryPolicy) {
+		p.SiloDefinitionID = &silo
+	})
+
+	reviewMissing := testPolicy(t, model.DiscoveryPolicyActionReview, []model.DiscoveryType{
+		model.DiscoveryTypePropertyMissing,
+	}, nil, nil)
+
+	acceptEmails := testPolicy(t, model.DiscoveryPolicyActionAccept, []model.DiscoveryType{
+		model.DiscoveryTypeCategoryFound,
+	}, []string{"email"}, func(p *model.DiscoveryPolicy) {
+		p.DataSourcePattern = &pattern
+		p.MinScore = &minScore
+	})
+
+	policies := []compiledPolicy{acceptFound, reviewMissing, acceptEmails}
+
+	tests := []struct {
+		name     string
+		target   Target
+		expected *model.DiscoveryPolicy
+	}{
+		{
+			name: "property found in silo",
+			target: Target{Discovery: &model.DataDiscovery{
+				Type:             model.DiscoveryTypePropertyFound,
+				SiloDefinitionID: silo,
+			}},
+			expected: acceptFound.policy,
+		},
+		{
+			name: "property found in another silo",
+			target: Target{Discovery: &model.DataDiscovery{
+				Type:             model.DiscoveryTypePropertyFound,
+				SiloDefinitionID: "silo2",
+			}},
+		},
+		{
+			name: "review takes precedence",
+			target: Target{Discovery: &model.DataDiscovery{
+				Type:             model.DiscoveryTypePropertyMissing,
+				SiloDefinitionID: silo,
+			}},
+			expected: reviewMissing.policy,
+		},
+		{
+			name: "category with high score",
+			target: Target{
+				Discovery:      &model.DataDiscovery{Type: model.DiscoveryTypeCategoryFound},
+				DataSourceName: &usersTable,
+				Categories:     []model.NewCategoryDiscovery{{CategoryID: "email", Score: &highScore}},
+			},
+			expected: acceptEmails.policy,
+		},
+		{
+			name: "category with low score",
+			target: Target{
+				Discovery:      &model.DataDiscovery{Type: model.DiscoveryTypeCategoryFound},
+				DataSourceName: &usersTable,
+				Categories:     []model.NewCategoryDiscovery{{CategoryID: "email", Score: &lowScore}},
+			},
+		},
+		{
+			name: "category without a score",
+			target: Target{
+				Discovery:      &model.DataDiscovery{Type: model.DiscoveryTypeCategoryFound},
+				DataSourceName: &usersTable,
+				Categories:     []model.NewCategoryDiscovery{{CategoryID: "email"}},
+			},
+		},
+		{
+			name: "category in another data source",
+			target: Target{
+				Discovery:      &model.DataDiscovery{Type: model.DiscoveryTypeCategoryFound},
+				DataSourceName: &ordersTable,
+				Categories:     []model.NewCategoryDiscovery{{CategoryID: "email", Score: &highScore}},
+			},
+		},
+		{
+			name: "other category",
+			target: Target{
+				Discovery:      &model.DataDiscovery{Type: model.DiscoveryTypeCategoryFound},
+				DataSourceName: &usersTable,
+				Categories:     []model.NewCategoryDiscovery{{CategoryID: "phone", Score: &highScore}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, decide(policies, test.target))
+		})
+	}
+}
+
+func TestCompilePolicyInvalidPattern(t *testing.T) {
+	pattern := "users("
+	_, err := compilePolicy(&model.DiscoveryPolicy{DataSourcePattern: &pattern})
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"gorm.io/gorm"
+)
+
+// ErrNotDecided is returned when reverting a discovery that hasn't been
+// accepted or rejected.
+var ErrNotDecided = errors.New("discovery has not been accepted or rejected")
+
+// ErrNoEffect is returned when reverting a decision that was made before
+// the effects of decisions were recorded, and needs them to be reverted.
+var ErrNoEffect = errors.New("decision cannot be reverted")
+
+// Revert undoes the latest decision on a discovery and re-opens it.
+// Reverting an accepted discovery undoes its change to the data map: the
+// objects it created are deleted, the objects it deleted are restored, and
+// the categories, type or primary key it set are reset. The revert is
+// recorded as an OPEN decision that refers to the reverted one.
+func Revert(conf *config.BaseConfig, discovery *model.DataDiscovery) (*model.DataDiscovery, error) {
+	if discovery.Status == model.DiscoveryStatusOpen {
+		return nil, ErrNotDecided
+	}
+
+	decision := model.DiscoveryDecision{}
+	if err := conf.DB.Where("data_discovery_id = ?", discovery.ID).Order(
+		"created_at desc",
+	).First(&decision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotDecided
+		}
+
+		return nil, err
+	}
+
+	if decision.Status == model.DiscoveryStatusOpen {
+		return nil, ErrNotDecided
+	}
+
+	conf.AnalyticsIngestor.Track("discoveryRevert", nil, map[string]interface{}{
+		"status": decision.Status,
+		"siloId": discovery.SiloDefinitionID,
+	})
+
+	if err := conf.DB.Transaction(func(tx *gorm.DB) error {
+		if decision.Status == model.DiscoveryStatusAccepted {
+			if err := revertAccepted(tx, discovery, &decision); err != nil {
+				return err
+			}
+
+			if err := snapshotDataMaps(tx, []*model.DataDiscovery{discovery}); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(discovery).Update("status", model.DiscoveryStatusOpen).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.DiscoveryDecision{
+			ID:                 uuid.NewString(),
+			DataDiscoveryID:    discovery.ID,
+			Status:             model.DiscoveryStatusOpen,
+			RevertedDecisionID: &decision.ID,
+		}).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	return discovery, nil
+}
+
+// revertAccepted undoes the change an accepted discovery made to the data
+// map.
+func revertAccepted(
+	tx *gorm.DB,
+	discovery *model.DataDiscovery,
+	decision *model.DiscoveryDecision,
+) error {
+	effect, err := decision.DecodeEffect()
+	if err != nil {
+		return err
+	}
+
+	switch discovery.Type {
+	case model.DiscoveryTypeCategoryFound:
+		data := model.NewCategoryDiscovery{}
+		if err := json.Unmarshal(discovery.Data, &data); err != nil {
+			return err
+		}
+
+		if data.PropertyID == nil {
+			return fmt.Errorf("category discovery %s has no property", discovery.ID)
+		}
+
+		// Keep the category if the property already had it. Decisions made
+		// before effects were recorded always remove it.
+		if effect != nil && !effect.CategoryAdded {
+			return nil
+		}
+
+		return tx.Model(&model.Property{ID: *data.PropertyID}).Association("Categories").Delete(
+			&model.Category{ID: data.CategoryID},
+		)
+	case model.DiscoveryTypeDataSourceFound:
+		if effect == nil || effect.DataSourceID == nil {
+			return ErrNoEffect
+		}
+
+		return model.DeleteDataSource(*effect.DataSourceID, tx)
+	case model.DiscoveryTypePropertyFound:
+		if effect == nil || len(effect.PropertyIDs) == 0 {
+			return ErrNoEffect
+		}
+
+		for _, id := range effect.PropertyIDs {
+			if err := model.DeleteProperty(id, tx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case model.DiscoveryTypeDataSourceMissing:
+		if effect == nil || effect.DataSourceID == nil {
+			return ErrNoEffect
+		}
+
+		if err := tx.Unscoped().Model(&model.DataSource{}).Where(
+			"id = ?", *effect.DataSourceID,
+		).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return restoreProperties(tx, effect)
+	case model.DiscoveryTypePropertyMissing:
+		data := model.PropertyMissingDiscovery{}
+		if err := json.Unmarshal(discovery.Data, &data); err != nil {
+			return err
+		}
+
+		// Decisions made before effects were recorded can still restore
+		// the property, without its categories.
+		if effect == nil {
+			effect = &model.DiscoveryEffect{PropertyIDs: []string{data.ID}}
+		}
+
+		return restoreProperties(tx, effect)
+	case model.DiscoveryTypePropertyChanged:
+		data := model.PropertyChangedDiscovery{}
+		if err := json.Unmarshal(discovery.Data, &data); err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Property{ID: data.ID}).Updates(map[string]interface{}{
+			"type":   data.OldType,
+			"format": data.OldFormat,
+		}).Error
+	case model.DiscoveryTypePrimaryKeySuggested:
+		data := model.PrimaryKeySuggestedDiscovery{}
+		if err := json.Unmarshal(discovery.Data, &data); err != nil {
+			return err
+		}
+
+		// Suggestions are only accepted for properties that weren't linked
+		// to a key.
+		return tx.Model(&model.Property{ID: data.PropertyID}).Update(
+			"user_primary_key_id", nil,
+		).Error
+	}
+
+	return fmt.Errorf("unknown discovery type: %v", discovery.Type)
+}
+
+// restoreProperties un-deletes the properties in effect, and adds back
+// their categories.
+func restoreProperties(tx *gorm.DB, effect *model.DiscoveryEffect) error {
+	if len(effect.PropertyIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Unscoped().Model(&model.Property{}).Where(
+		"id IN ?", effect.PropertyIDs,
+	).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+
+	for propertyID, categoryIDs := range effect.Categories {
+		if len(categoryIDs) == 0 {
+			continue
+		}
+
+		if err := tx.Model(&model.Property{ID: propertyID}).Association("Categories").Append(
+			categoriesForIDs(categoryIDs),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func categoriesForIDs(ids []string) []*model.Category {
+	categories := make([]*model.Category, 0, len(ids))
+	for _, id := range ids {
+		categories = append(categories, &model.Category{ID: id})
+	}
+
+	return categories
+}
@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletedPropertiesEffect(t *testing.T) {
+	effect := deletedPropertiesEffect([]*model.Property{
+		{ID: "p1", Categories: []*model.Category{{ID: "email"}, {ID: "phone"}}},
+		{ID: "p2"},
+	})
+
+	data, err := json.Marshal(effect)
+	assert.NoError(t, err)
+
+	decision := model.DiscoveryDecision{Effect: data}
+	decoded, err := decision.DecodeEffect()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p1", "p2"}, decoded.PropertyIDs)
+	assert.Equal(t, map[string][]string{
+		"p1": {"email", "phone"},
+		"p2": {},
+	}, decoded.Categories)
+
+	// Decisions made before effects were recorded don't have one.
+	decoded, err = (&model.DiscoveryDecision{}).DecodeEffect()
+	assert.NoError(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestRevertOpenDiscovery(t *testing.T) {
+	_, err := Revert(&config.BaseConfig{}, &model.DataDiscovery{Status: model.DiscoveryStatusOpen})
+	assert.ErrorIs(t, err, ErrNotDecided)
+}
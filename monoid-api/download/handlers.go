@@ -30,6 +30,15 @@ func (dh *DownloadHandler) HandleDownload(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if df.PurgedAt != nil {
+		w.WriteHeader(http.StatusGone)
+		if _, err := w.Write([]byte("This file has expired and is no longer available.")); err != nil {
+			log.Err(err).Msg("Error writing response")
+		}
+
+		return
+	}
+
 	f, err := dh.Conf.FileStore.NewReader(context.Background(), df.StoragePath, false)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
@@ -0,0 +1,84 @@
+package download
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/filestore/localstore"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"gorm.io/gorm"
+)
+
+type downloadTestSuite struct {
+	suite.Suite
+
+	container testcontainers.Container
+	db        *gorm.DB
+}
+
+func (s *downloadTestSuite) SetupSuite() {
+	container, db, err := testutil.SetupDB()
+	if err != nil {
+		s.T().Skipf("could not start postgres: %v", err)
+	}
+
+	s.container = container
+	s.db = db
+}
+
+func (s *downloadTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+func (s *downloadTestSuite) download(df model.DownloadableFile, handler *DownloadHandler) *httptest.ResponseRecorder {
+	s.Require().NoError(s.db.Create(&df).Error)
+
+	req := httptest.NewRequest(http.MethodGet, "/downloads/"+df.ID, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": df.ID})
+
+	rec := httptest.NewRecorder()
+	handler.HandleDownload(rec, req)
+
+	return rec
+}
+
+func (s *downloadTestSuite) TestDownload() {
+	fs := localstore.NewLocalFileStore(s.T().TempDir())
+	handler := &DownloadHandler{Conf: &config.BaseConfig{DB: s.db, FileStore: fs}}
+
+	wr, fp, err := fs.NewWriter(context.Background(), "result.tar.gz", false)
+	s.Require().NoError(err)
+	_, err = wr.Write([]byte("results"))
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	rec := s.download(model.DownloadableFile{ID: uuid.NewString(), StoragePath: fp}, handler)
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("results", rec.Body.String())
+}
+
+func (s *downloadTestSuite) TestDownloadPurged() {
+	handler := &DownloadHandler{Conf: &config.BaseConfig{
+		DB:        s.db,
+		FileStore: localstore.NewLocalFileStore(s.T().TempDir()),
+	}}
+
+	purgedAt := time.Now()
+	rec := s.download(model.DownloadableFile{ID: uuid.NewString(), PurgedAt: &purgedAt}, handler)
+	s.Equal(http.StatusGone, rec.Code)
+}
+
+func TestDownloadSuite(t *testing.T) {
+	suite.Run(t, new(downloadTestSuite))
+}
@@ -0,0 +1,165 @@
+package azurestore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/monoid-privacy/monoid/filestore"
+	"github.com/monoid-privacy/monoid/filestore/segwriter"
+)
+
+// maxAppendBlockSize is the largest block that can be appended to an
+// append blob in a single call.
+const maxAppendBlockSize = 4 * 1024 * 1024
+
+type azureBlobStore struct {
+	client *container.Client
+}
+
+// NewAzureBlobStore creates a file store that keeps objects in an Azure
+// Blob Storage container. Regular objects are uploaded as block blobs, and
+// segmented files (e.g. job logs) are written to append blobs, so they can
+// be read while they are still being written.
+func NewAzureBlobStore(client *container.Client) filestore.FileStore {
+	return &azureBlobStore{
+		client: client,
+	}
+}
+
+// blockBlobWriter streams everything written to it to a block blob.
+type blockBlobWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *blockBlobWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload, and returns once the blob has been
+// committed.
+func (w *blockBlobWriter) Close() error {
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+
+	return <-w.done
+}
+
+// appendBlockWriter buffers everything written to it, and appends it to an
+// append blob when it is closed.
+type appendBlockWriter struct {
+	ctx    context.Context
+	client *appendblob.Client
+	buf    bytes.Buffer
+}
+
+func (w *appendBlockWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *appendBlockWriter) Close() error {
+	for w.buf.Len() > 0 {
+		block := w.buf.Next(maxAppendBlockSize)
+
+		if _, err := w.client.AppendBlock(
+			w.ctx,
+			streaming.NopCloser(bytes.NewReader(block)),
+			nil,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (as *azureBlobStore) NewWriter(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) (io.WriteCloser, string, error) {
+	if !segmentFile {
+		pr, pw := io.Pipe()
+		done := make(chan error, 1)
+		client := as.client.NewBlockBlobClient(objectName)
+
+		go func() {
+			_, err := client.UploadStream(ctx, pr, &blockblob.UploadStreamOptions{})
+			pr.CloseWithError(err)
+			done <- err
+		}()
+
+		return &blockBlobWriter{pw: pw, done: done}, objectName, nil
+	}
+
+	client := as.client.NewAppendBlobClient(objectName)
+	if _, err := client.Create(ctx, nil); err != nil {
+		return nil, "", err
+	}
+
+	sw := segwriter.NewSegmentedWriter(func(i int) io.WriteCloser {
+		return &appendBlockWriter{
+			ctx:    ctx,
+			client: client,
+		}
+	}, 1*time.Minute)
+
+	return sw, objectName, nil
+}
+
+func (as *azureBlobStore) NewReader(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) (io.ReadCloser, error) {
+	res, err := as.client.NewBlobClient(objectName).DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+func (as *azureBlobStore) Delete(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) error {
+	_, err := as.client.NewBlobClient(objectName).Delete(ctx, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (as *azureBlobStore) List(ctx context.Context, prefix string) ([]string, error) {
+	pager := as.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+
+	res := []string{}
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			if item.Name != nil {
+				res = append(res, *item.Name)
+			}
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package azurestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// azuriteKey is the well known account key of the Azurite emulator.
+const azuriteKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+type azureStoreTestSuite struct {
+	suite.Suite
+
+	container testcontainers.Container
+	client    *container.Client
+}
+
+func (s *azureStoreTestSuite) SetupSuite() {
+	ctx := context.Background()
+
+	azurite, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "mcr.microsoft.com/azure-storage/azurite:latest",
+			Cmd:          []string{"azurite-blob", "--blobHost", "0.0.0.0"},
+			ExposedPorts: []string{"10000/tcp"},
+			WaitingFor:   wait.ForListeningPort("10000/tcp"),
+			AutoRemove:   true,
+		},
+		Started: true,
+	})
+
+	if err != nil {
+		s.T().Skip(fmt.Sprintf("could not start azurite: %v", err))
+	}
+
+	s.container = azurite
+
+	endpoint, err := azurite.PortEndpoint(ctx, "10000/tcp", "http")
+	s.Require().NoError(err)
+
+	s.client, err = container.NewClientFromConnectionString(
+		fmt.Sprintf(
+			"DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=%s;BlobEndpoint=%s/devstoreaccount1;",
+			azuriteKey,
+			endpoint,
+		),
+		"monoid-test",
+		nil,
+	)
+	s.Require().NoError(err)
+
+	_, err = s.client.Create(ctx, nil)
+	s.Require().NoError(err)
+}
+
+func (s *azureStoreTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+func (s *azureStoreTestSuite) TestBlockBlob() {
+	ctx := context.Background()
+	store := NewAzureBlobStore(s.client)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+
+	wr, fp, err := store.NewWriter(ctx, "request.tar.gz", false)
+	s.Require().NoError(err)
+
+	_, err = wr.Write(data)
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	rd, err := store.NewReader(ctx, fp, false)
+	s.Require().NoError(err)
+
+	res, err := io.ReadAll(rd)
+	s.Require().NoError(err)
+	s.Require().NoError(rd.Close())
+
+	s.Equal(data, res)
+}
+
+func (s *azureStoreTestSuite) TestAppendBlob() {
+	ctx := context.Background()
+	store := NewAzureBlobStore(s.client)
+
+	wr, fp, err := store.NewWriter(ctx, "log", true)
+	s.Require().NoError(err)
+
+	_, err = wr.Write([]byte("line 1\nline 2\n"))
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	s.Eventually(func() bool {
+		rd, err := store.NewReader(ctx, fp, true)
+		if err != nil {
+			return false
+		}
+
+		defer rd.Close()
+
+		res, err := io.ReadAll(rd)
+		return err == nil && string(res) == "line 1\nline 2\n"
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestAzureStoreSuite(t *testing.T) {
+	suite.Run(t, new(azureStoreTestSuite))
+}
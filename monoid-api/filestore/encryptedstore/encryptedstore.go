@@ -1,25 +1,45 @@
-package localstore
+package encryptedstore
 
 import (
+	"bytes"
 	"context"
-	"crypto/rand"
-	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
 	"io"
 
 	"github.com/minio/sio"
 	"github.com/monoid-privacy/monoid/filestore"
-	"golang.org/x/crypto/hkdf"
+	"github.com/monoid-privacy/monoid/kms"
+)
+
+// headerMagic prefixes every object written by the encrypted file store.
+var headerMagic = []byte("MNEF")
+
+const (
+	// formatStream objects are encrypted as a single DARE stream, which
+	// detects truncation, but can only be read once the writer is closed.
+	formatStream byte = 1
+
+	// formatFramed objects are encrypted as a sequence of independently
+	// sealed frames, one per write, so segmented files (e.g. job logs)
+	// can be read while they are still being written.
+	formatFramed byte = 2
 )
 
 type encryptedFileStore struct {
-	filestore filestore.FileStore
-	key       []byte
+	filestore   filestore.FileStore
+	keyProvider kms.KeyProvider
 }
 
-func NewEncryptedFileStore(filestore filestore.FileStore, key []byte) filestore.FileStore {
+// NewEncryptedFileStore wraps filestore so that all objects are encrypted
+// before they are written, and decrypted when they are read. Each object
+// is encrypted with its own data key, which is wrapped by keyProvider and
+// stored in a header at the start of the object. Objects without the
+// header are read as plaintext.
+func NewEncryptedFileStore(filestore filestore.FileStore, keyProvider kms.KeyProvider) filestore.FileStore {
 	return &encryptedFileStore{
-		filestore: filestore,
-		key:       key,
+		filestore:   filestore,
+		keyProvider: keyProvider,
 	}
 }
 
@@ -28,6 +48,15 @@ func (fs *encryptedFileStore) NewWriter(
 	objectName string,
 	segmentFile bool,
 ) (wr io.WriteCloser, fp string, err error) {
+	dataKey, wrapped, err := kms.NewDataKey(ctx, fs.keyProvider)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(wrapped) > 0xFFFF {
+		return nil, "", fmt.Errorf("wrapped key is too long (%d bytes)", len(wrapped))
+	}
+
 	writer, filePath, err := fs.filestore.NewWriter(ctx, objectName, segmentFile)
 	if err != nil {
 		return nil, "", err
@@ -35,31 +64,120 @@ func (fs *encryptedFileStore) NewWriter(
 
 	defer func() {
 		if err != nil {
-			wr.Close()
+			writer.Close()
 			fp = ""
 			wr = nil
 		}
 	}()
 
-	var nonce [32]byte
-	if _, err = io.ReadFull(rand.Reader, nonce[:]); err != nil {
-		return nil, "", err
+	format := formatStream
+	if segmentFile {
+		format = formatFramed
 	}
 
-	var key [32]byte
-	kdf := hkdf.New(sha256.New, key[:], nonce[:], nil)
-	if _, err = io.ReadFull(kdf, key[:]); err != nil {
+	header := make([]byte, len(headerMagic)+3, len(headerMagic)+3+len(wrapped))
+	copy(header, headerMagic)
+	header[len(headerMagic)] = format
+	binary.BigEndian.PutUint16(header[len(headerMagic)+1:], uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	if _, err = writer.Write(header); err != nil {
 		return nil, "", err
 	}
 
-	writer, err = sio.EncryptWriter(writer, sio.Config{Key: key[:]})
+	if format == formatFramed {
+		wr, err = newFrameWriter(writer, dataKey)
+	} else {
+		wr, err = sio.EncryptWriter(writer, sio.Config{
+			MinVersion: sio.Version20,
+			Key:        dataKey,
+		})
+	}
+
 	if err != nil {
 		return nil, "", err
 	}
 
-	return writer, filePath, nil
+	return wr, filePath, nil
+}
+
+func (fs *encryptedFileStore) NewReader(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) (rd io.ReadCloser, err error) {
+	reader, err := fs.filestore.NewReader(ctx, objectName, segmentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			reader.Close()
+			rd = nil
+		}
+	}()
+
+	header := make([]byte, len(headerMagic)+3)
+	n, err := io.ReadFull(reader, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("error reading encryption header: %v", err)
+	}
+
+	// Objects written before encryption was turned on don't have the
+	// header, they're returned as they are so they can still be read.
+	if n < len(header) || string(header[:len(headerMagic)]) != string(headerMagic) {
+		return &readCloser{
+			Reader: io.MultiReader(bytes.NewReader(header[:n]), reader),
+			Closer: reader,
+		}, nil
+	}
+
+	format := header[len(headerMagic)]
+	wrapped := make([]byte, binary.BigEndian.Uint16(header[len(headerMagic)+1:]))
+
+	if _, err = io.ReadFull(reader, wrapped); err != nil {
+		return nil, fmt.Errorf("error reading encryption header: %v", err)
+	}
+
+	dataKey, err := fs.keyProvider.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	var decReader io.Reader
+
+	switch format {
+	case formatStream:
+		decReader, err = sio.DecryptReader(reader, sio.Config{
+			MinVersion: sio.Version20,
+			Key:        dataKey,
+		})
+	case formatFramed:
+		decReader, err = newFrameReader(reader, dataKey)
+	default:
+		err = fmt.Errorf("unknown encryption format %d", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &readCloser{
+		Reader: decReader,
+		Closer: reader,
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (fs *encryptedFileStore) Delete(ctx context.Context, objectName string, segmentFile bool) error {
+	return fs.filestore.Delete(ctx, objectName, segmentFile)
 }
 
-func (ls *encryptedFileStore) NewReader(ctx context.Context, objectName string, segmentFile bool) (io.ReadCloser, error) {
-	return ls.filestore.NewReader(ctx, objectName, segmentFile)
+func (fs *encryptedFileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return fs.filestore.List(ctx, prefix)
 }
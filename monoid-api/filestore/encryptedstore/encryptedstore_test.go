@@ -0,0 +1,115 @@
+package encryptedstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/filestore/localstore"
+	"github.com/monoid-privacy/monoid/kms/localkms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEncKey = "Tc7ILcxCi68Xk7646IrNBYmbMzbWNU+s94fnZMJ1zzk="
+
+func newTestStore(t *testing.T) (string, *encryptedFileStore) {
+	key, err := base64.StdEncoding.DecodeString(testEncKey)
+	require.NoError(t, err)
+
+	kp, err := localkms.NewLocalKeyProvider(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	fs := NewEncryptedFileStore(localstore.NewLocalFileStore(dir), kp)
+
+	return dir, fs.(*encryptedFileStore)
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir, fs := newTestStore(t)
+
+	// Larger than a single DARE package, so multiple packages are written.
+	data := bytes.Repeat([]byte("monoid test data\n"), 10000)
+
+	for _, segmentFile := range []bool{false, true} {
+		wr, fp, err := fs.NewWriter(ctx, "object", segmentFile)
+		require.NoError(t, err)
+
+		_, err = wr.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, wr.Close())
+
+		raw, err := os.ReadFile(filepath.Join(dir, fp))
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(raw, []byte("monoid test data")))
+
+		rd, err := fs.NewReader(ctx, fp, segmentFile)
+		require.NoError(t, err)
+
+		res, err := io.ReadAll(rd)
+		require.NoError(t, err)
+		require.NoError(t, rd.Close())
+
+		assert.Equal(t, data, res)
+	}
+}
+
+func TestPartialSegmentFile(t *testing.T) {
+	ctx := context.Background()
+	dir, fs := newTestStore(t)
+
+	wr, fp, err := fs.NewWriter(ctx, "log", true)
+	require.NoError(t, err)
+
+	_, err = wr.Write([]byte("line 1\n"))
+	require.NoError(t, err)
+	_, err = wr.Write([]byte("line 2\n"))
+	require.NoError(t, err)
+	require.NoError(t, wr.Close())
+
+	// Simulate a reader that sees the file while the last frame is
+	// still being written.
+	path := filepath.Join(dir, fp)
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw[:len(raw)-3], 0600))
+
+	rd, err := fs.NewReader(ctx, fp, true)
+	require.NoError(t, err)
+
+	res, err := io.ReadAll(rd)
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\n", string(res))
+}
+
+func TestUnencryptedObject(t *testing.T) {
+	ctx := context.Background()
+	dir, fs := newTestStore(t)
+
+	// Objects written before encryption was turned on, including ones
+	// shorter than the header, are read as they are.
+	for _, data := range []string{"plaintext job log\n", "ab", ""} {
+		plain := localstore.NewLocalFileStore(dir)
+		wr, fp, err := plain.NewWriter(ctx, "old", false)
+		require.NoError(t, err)
+
+		_, err = wr.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, wr.Close())
+
+		rd, err := fs.NewReader(ctx, fp, false)
+		require.NoError(t, err)
+
+		res, err := io.ReadAll(rd)
+		require.NoError(t, err)
+		require.NoError(t, rd.Close())
+
+		assert.Equal(t, data, string(res))
+	}
+}
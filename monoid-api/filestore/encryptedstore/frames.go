@@ -0,0 +1,142 @@
+package encryptedstore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/monoid-privacy/monoid/kms"
+)
+
+// maxFrameSize is the maximum amount of plaintext sealed in a single frame.
+const maxFrameSize = 64 * 1024
+
+// frameWriter seals every write as its own frame, so a reader can decrypt
+// everything that has been flushed so far. Each frame is:
+//
+//	ciphertext length (4 bytes) | nonce | ciphertext
+//
+// The frame index is used as the additional data, so frames can't be
+// reordered or dropped from the middle of the stream.
+type frameWriter struct {
+	dst   io.WriteCloser
+	gcm   cipher.AEAD
+	index uint64
+}
+
+func newFrameWriter(dst io.WriteCloser, key []byte) (io.WriteCloser, error) {
+	gcm, err := kms.NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &frameWriter{dst: dst, gcm: gcm}, nil
+}
+
+func frameAD(index uint64) []byte {
+	ad := make([]byte, 8)
+	binary.BigEndian.PutUint64(ad, index)
+
+	return ad
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	n := 0
+
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxFrameSize {
+			chunk = chunk[:maxFrameSize]
+		}
+
+		nonceSize := w.gcm.NonceSize()
+		frame := make([]byte, 4+nonceSize, 4+nonceSize+len(chunk)+w.gcm.Overhead())
+		if _, err := io.ReadFull(rand.Reader, frame[4:]); err != nil {
+			return n, err
+		}
+
+		frame = w.gcm.Seal(frame, frame[4:], chunk, frameAD(w.index))
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-4-nonceSize))
+
+		if _, err := w.dst.Write(frame); err != nil {
+			return n, err
+		}
+
+		w.index++
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return n, nil
+}
+
+func (w *frameWriter) Close() error {
+	return w.dst.Close()
+}
+
+type frameReader struct {
+	src   io.Reader
+	gcm   cipher.AEAD
+	index uint64
+	buf   bytes.Buffer
+}
+
+func newFrameReader(src io.Reader, key []byte) (io.Reader, error) {
+	gcm, err := kms.NewGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &frameReader{src: src, gcm: gcm}, nil
+}
+
+// readFrame decrypts the next frame into the buffer. A frame that has
+// only been partially written is treated as the end of the stream, since
+// segmented files are read while they are being written.
+func (r *frameReader) readFrame() error {
+	header := make([]byte, 4+r.gcm.NonceSize())
+	if _, err := io.ReadFull(r.src, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size > maxFrameSize+uint32(r.gcm.Overhead()) {
+		return fmt.Errorf("invalid frame size %d", size)
+	}
+
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(r.src, ciphertext); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+
+		return err
+	}
+
+	plaintext, err := r.gcm.Open(nil, header[4:], ciphertext, frameAD(r.index))
+	if err != nil {
+		return err
+	}
+
+	r.index++
+	r.buf.Write(plaintext)
+
+	return nil
+}
+
+func (r *frameReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if err := r.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	return r.buf.Read(p)
+}
@@ -7,7 +7,7 @@ import (
 
 type FileStore interface {
 	// NewWriter returns an io.Writer for the file handle you can write to
-	// and the path to access the file. Set fullPath to true if the objectName
+	// and the path to access the file. Use WithFullPath if the objectName
 	// is the full path returned by a previous NewWriter call.
 	NewWriter(
 		ctx context.Context,
@@ -16,4 +16,41 @@ type FileStore interface {
 	) (io.WriteCloser, string, error)
 
 	NewReader(ctx context.Context, objectName string, segmentFile bool) (io.ReadCloser, error)
+
+	// Delete removes the object (and all of its segments if segmentFile
+	// is true). Deleting an object that doesn't exist is not an error.
+	Delete(ctx context.Context, objectName string, segmentFile bool) error
+
+	// List returns the names of all objects whose name starts with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+type workspaceIDKey struct{}
+
+// WithWorkspaceID returns a context that tells file stores which workspace
+// the objects created with it belong to. Stores that support per-workspace
+// prefixes use it to decide where new objects are written.
+func WithWorkspaceID(ctx context.Context, workspaceID string) context.Context {
+	return context.WithValue(ctx, workspaceIDKey{}, workspaceID)
+}
+
+// WorkspaceIDFromContext returns the workspace id set by WithWorkspaceID.
+func WorkspaceIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(workspaceIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+type fullPathKey struct{}
+
+// WithFullPath returns a context that tells file stores the object name
+// passed to NewWriter is the full path returned by a previous NewWriter
+// call, so it's used as is instead of being resolved again (e.g. prefixed).
+func WithFullPath(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fullPathKey{}, true)
+}
+
+// IsFullPath returns true if the context was created with WithFullPath.
+func IsFullPath(ctx context.Context) bool {
+	full, _ := ctx.Value(fullPathKey{}).(bool)
+	return full
 }
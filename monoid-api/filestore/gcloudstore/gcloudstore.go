@@ -2,6 +2,7 @@ package gcloudstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -58,7 +59,7 @@ func (ls *googleCloudStore) NewReader(
 	}
 
 	iter := ls.client.Bucket(ls.bucket).Objects(ctx, &storage.Query{
-		Prefix: objectName,
+		Prefix: objectName + "/",
 	})
 
 	r, w := io.Pipe()
@@ -93,3 +94,52 @@ func (ls *googleCloudStore) NewReader(
 
 	return r, nil
 }
+
+func (ls *googleCloudStore) Delete(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) error {
+	names := []string{objectName}
+
+	if segmentFile {
+		segments, err := ls.List(ctx, objectName+"/")
+		if err != nil {
+			return err
+		}
+
+		names = append(names, segments...)
+	}
+
+	for _, name := range names {
+		err := ls.client.Bucket(ls.bucket).Object(name).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ls *googleCloudStore) List(ctx context.Context, prefix string) ([]string, error) {
+	iter := ls.client.Bucket(ls.bucket).Objects(ctx, &storage.Query{
+		Prefix: prefix,
+	})
+
+	res := []string{}
+
+	for {
+		obj, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, obj.Name)
+	}
+
+	return res, nil
+}
@@ -2,7 +2,9 @@ package localstore
 
 import (
 	"context"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -50,3 +52,44 @@ func (ls *localFileStore) NewReader(ctx context.Context, objectName string, segm
 
 	return f, nil
 }
+
+func (ls *localFileStore) Delete(ctx context.Context, objectName string, segmentFile bool) error {
+	fp := safeJoin(ls.RootDir, objectName)
+
+	if err := os.Remove(fp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (ls *localFileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	res := []string{}
+
+	err := filepath.WalkDir(ls.RootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		name, err := filepath.Rel(ls.RootDir, path)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, name)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
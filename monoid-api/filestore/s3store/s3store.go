@@ -0,0 +1,260 @@
+package s3store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/monoid-privacy/monoid/filestore"
+	"github.com/monoid-privacy/monoid/filestore/segwriter"
+	"github.com/rs/zerolog/log"
+)
+
+// workspacePlaceholder is replaced with the workspace id of the context
+// passed to NewWriter when building an object's key.
+const workspacePlaceholder = "{workspace}"
+
+// defaultWorkspace is used in place of the workspace id when the context
+// doesn't have one.
+const defaultWorkspace = "shared"
+
+// S3StoreOptions configures how objects are written to the bucket.
+type S3StoreOptions struct {
+	// Prefix is prepended to every object key. It may contain {workspace},
+	// which is replaced with the workspace id of the object.
+	Prefix string
+
+	// ServerSideEncryption is the server-side encryption algorithm
+	// (AES256 or aws:kms). No encryption header is sent if it's empty.
+	ServerSideEncryption types.ServerSideEncryption
+
+	// SSEKMSKeyID is the KMS key used when ServerSideEncryption is aws:kms.
+	SSEKMSKeyID string
+
+	// PartSize is the size of each part of a multipart upload. The
+	// uploader's default is used if it's 0.
+	PartSize int64
+}
+
+type s3Store struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+	bucket   string
+	opts     S3StoreOptions
+}
+
+// NewS3Store creates a file store that keeps objects in an S3 (or S3
+// compatible) bucket. Objects larger than the part size are uploaded with
+// multipart uploads.
+func NewS3Store(client *s3.Client, bucket string, opts S3StoreOptions) filestore.FileStore {
+	return &s3Store{
+		client: client,
+		uploader: manager.NewUploader(client, func(u *manager.Uploader) {
+			if opts.PartSize != 0 {
+				u.PartSize = opts.PartSize
+			}
+		}),
+		bucket: bucket,
+		opts:   opts,
+	}
+}
+
+// objectKey returns the key that objectName should be written to. Names
+// passed with filestore.WithFullPath were returned by a previous call to
+// NewWriter, so they are already prefixed and are returned as is.
+func (s *s3Store) objectKey(ctx context.Context, objectName string) string {
+	if s.opts.Prefix == "" || filestore.IsFullPath(ctx) {
+		return objectName
+	}
+
+	workspaceID, ok := filestore.WorkspaceIDFromContext(ctx)
+	if !ok {
+		workspaceID = defaultWorkspace
+	}
+
+	return strings.ReplaceAll(s.opts.Prefix, workspacePlaceholder, workspaceID) + objectName
+}
+
+// uploadWriter streams everything written to it to a single object.
+type uploadWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (s *s3Store) newUploadWriter(ctx context.Context, key string) io.WriteCloser {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   pr,
+	}
+
+	if s.opts.ServerSideEncryption != "" {
+		input.ServerSideEncryption = s.opts.ServerSideEncryption
+	}
+
+	if s.opts.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.opts.SSEKMSKeyID)
+	}
+
+	go func() {
+		_, err := s.uploader.Upload(ctx, input)
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return &uploadWriter{
+		pw:   pw,
+		done: done,
+	}
+}
+
+func (w *uploadWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload, and returns once the object has been
+// written.
+func (w *uploadWriter) Close() error {
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+
+	return <-w.done
+}
+
+func (s *s3Store) NewWriter(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) (io.WriteCloser, string, error) {
+	key := s.objectKey(ctx, objectName)
+
+	if !segmentFile {
+		return s.newUploadWriter(ctx, key), key, nil
+	}
+
+	sw := segwriter.NewSegmentedWriter(func(i int) io.WriteCloser {
+		return s.newUploadWriter(ctx, fmt.Sprintf("%s/%06d", key, i))
+	}, 1*time.Minute)
+
+	return sw, key, nil
+}
+
+func (s *s3Store) NewReader(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) (io.ReadCloser, error) {
+	if !segmentFile {
+		res, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objectName),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return res.Body, nil
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(objectName + "/"),
+	})
+
+	r, w := io.Pipe()
+	go func() {
+		defer w.Close()
+
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				log.Err(err).Msg("Error listing objects")
+				return
+			}
+
+			for _, obj := range page.Contents {
+				res, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+					Bucket: aws.String(s.bucket),
+					Key:    obj.Key,
+				})
+
+				if err != nil {
+					log.Err(err).Msg("Error getting object")
+					return
+				}
+
+				_, err = io.Copy(w, res.Body)
+				res.Body.Close()
+
+				if err != nil {
+					log.Err(err).Msg("Error copying")
+					return
+				}
+			}
+		}
+	}()
+
+	return r, nil
+}
+
+func (s *s3Store) Delete(
+	ctx context.Context,
+	objectName string,
+	segmentFile bool,
+) error {
+	names := []string{objectName}
+
+	if segmentFile {
+		segments, err := s.List(ctx, objectName+"/")
+		if err != nil {
+			return err
+		}
+
+		names = append(names, segments...)
+	}
+
+	// S3 doesn't return an error when deleting keys that don't exist.
+	for _, name := range names {
+		if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(name),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *s3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	res := []string{}
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range page.Contents {
+			res = append(res, aws.ToString(obj.Key))
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,167 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/monoid-privacy/monoid/filestore"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const testBucket = "monoid-test"
+
+type s3StoreTestSuite struct {
+	suite.Suite
+
+	container testcontainers.Container
+	client    *s3.Client
+}
+
+func (s *s3StoreTestSuite) SetupSuite() {
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "minio/minio:latest",
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{"9000/tcp"},
+			WaitingFor:   wait.ForHTTP("/minio/health/live").WithPort("9000/tcp"),
+			AutoRemove:   true,
+			Env: map[string]string{
+				"MINIO_ROOT_USER":     "minioadmin",
+				"MINIO_ROOT_PASSWORD": "minioadmin",
+			},
+		},
+		Started: true,
+	})
+
+	if err != nil {
+		s.T().Skip(fmt.Sprintf("could not start minio: %v", err))
+	}
+
+	s.container = container
+
+	endpoint, err := container.PortEndpoint(ctx, "9000/tcp", "http")
+	s.Require().NoError(err)
+
+	s.client = s3.New(s3.Options{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("minioadmin", "minioadmin", ""),
+		EndpointResolver: s3.EndpointResolverFromURL(endpoint),
+		UsePathStyle:     true,
+	})
+
+	_, err = s.client.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: aws.String(testBucket),
+	})
+	s.Require().NoError(err)
+}
+
+func (s *s3StoreTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+func (s *s3StoreTestSuite) TestMultipartUpload() {
+	store := NewS3Store(s.client, testBucket, S3StoreOptions{
+		Prefix:   "workspaces/{workspace}/",
+		PartSize: 5 * 1024 * 1024,
+	})
+
+	ctx := filestore.WithWorkspaceID(context.Background(), "ws1")
+
+	// Large enough to be uploaded in multiple parts.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024*1024)
+
+	wr, key, err := store.NewWriter(ctx, "request.tar.gz", false)
+	s.Require().NoError(err)
+	s.Equal("workspaces/ws1/request.tar.gz", key)
+
+	_, err = wr.Write(data)
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	rd, err := store.NewReader(context.Background(), key, false)
+	s.Require().NoError(err)
+
+	res, err := io.ReadAll(rd)
+	s.Require().NoError(err)
+	s.Require().NoError(rd.Close())
+
+	s.Equal(data, res)
+}
+
+func (s *s3StoreTestSuite) TestSegmentFile() {
+	store := NewS3Store(s.client, testBucket, S3StoreOptions{
+		Prefix: "workspaces/{workspace}/",
+	})
+
+	ctx := filestore.WithWorkspaceID(context.Background(), "ws1")
+
+	wr, key, err := store.NewWriter(ctx, "log", false)
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	// Writing segments to a key that was already returned by NewWriter
+	// must not add the prefix again.
+	wr, segKey, err := store.NewWriter(filestore.WithFullPath(context.Background()), key, true)
+	s.Require().NoError(err)
+	s.Equal(key, segKey)
+
+	_, err = wr.Write([]byte("line 1\nline 2\n"))
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	s.Eventually(func() bool {
+		rd, err := store.NewReader(context.Background(), segKey, true)
+		if err != nil {
+			return false
+		}
+
+		defer rd.Close()
+
+		res, err := io.ReadAll(rd)
+		return err == nil && strings.Contains(string(res), "line 1\nline 2\n")
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestS3StoreSuite(t *testing.T) {
+	suite.Run(t, new(s3StoreTestSuite))
+}
+
+func TestObjectKey(t *testing.T) {
+	ws := filestore.WithWorkspaceID(context.Background(), "ws1")
+
+	for _, c := range []struct {
+		prefix string
+		ctx    context.Context
+		name   string
+		key    string
+	}{
+		{"workspaces/{workspace}/", ws, "log", "workspaces/ws1/log"},
+		{"{workspace}/logs/", ws, "log", "ws1/logs/log"},
+		{"{workspace}/logs/", context.Background(), "log", "shared/logs/log"},
+
+		// Names that look prefixed are still prefixed unless they're
+		// marked as full paths.
+		{"workspaces/", ws, "workspaces/log", "workspaces/workspaces/log"},
+		{"{workspace}/logs/", filestore.WithFullPath(context.Background()), "ws1/logs/log", "ws1/logs/log"},
+		{"", ws, "log", "log"},
+	} {
+		store := NewS3Store(nil, testBucket, S3StoreOptions{Prefix: c.prefix}).(*s3Store)
+		if key := store.objectKey(c.ctx, c.name); key != c.key {
+			t.Errorf("objectKey(%q) with prefix %q = %q, want %q", c.name, c.prefix, key, c.key)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package jsonschema
+
+import (
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// nullableTypeHook decodes a list of types (e.g. ["string", "null"]) into
+// the first type that isn't null.
+func nullableTypeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to.Kind() != reflect.String || from.Kind() != reflect.Slice {
+		return data, nil
+	}
+
+	types, ok := data.([]interface{})
+	if !ok {
+		return data, nil
+	}
+
+	for _, t := range types {
+		if s, ok := t.(string); ok && s != "null" {
+			return s, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Decode decodes a schema that was parsed as a generic map (e.g. from a
+// monoid protocol message) into schema, using the JSON names of the
+// fields, so keywords like $ref and $defs are kept.
+func Decode(input interface{}, schema *Schema) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:    "json",
+		DecodeHook: nullableTypeHook,
+		Result:     schema,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(input)
+}
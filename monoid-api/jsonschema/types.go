@@ -15,6 +15,8 @@ type Schema struct {
 	DynamicRef  string             `json:"$dynamicRef,omitempty"` // section 8.2.3.2
 	Definitions map[string]*Schema `json:"$defs,omitempty"`       // section 8.2.4
 	Comments    string             `json:"$comment,omitempty"`    // section 8.3
+	// Draft-07 and earlier name $defs "definitions".
+	LegacyDefinitions map[string]*Schema `json:"definitions,omitempty"`
 	// RFC draft-bhutton-json-schema-00 section 10.2.1 (Sub-schemas with logic)
 	AllOf []*Schema `json:"allOf,omitempty"` // section 10.2.1.1
 	AnyOf []*Schema `json:"anyOf,omitempty"` // section 10.2.1.2
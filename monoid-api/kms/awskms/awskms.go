@@ -0,0 +1,77 @@
+package awskms
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	kmsapi "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/monoid-privacy/monoid/kms"
+)
+
+// encryptionContext is bound to every key wrapped by the provider, so
+// ciphertexts from other applications using the same KMS key can't be
+// unwrapped as monoid data keys.
+var encryptionContext = map[string]string{
+	"application": "monoid",
+}
+
+type awsKeyProvider struct {
+	client *kmsapi.Client
+	keyID  string
+}
+
+// NewAWSKeyProvider creates a key provider that wraps data keys using the
+// AWS KMS key identified by keyID (a key id, ARN or alias).
+func NewAWSKeyProvider(client *kmsapi.Client, keyID string) kms.KeyProvider {
+	return &awsKeyProvider{
+		client: client,
+		keyID:  keyID,
+	}
+}
+
+// NewAWSKeyProviderFromEnv creates a key provider using the default AWS
+// credential chain. If endpoint is non-empty, requests are sent to it
+// instead of the AWS endpoint, which is used to point at a KMS emulator.
+func NewAWSKeyProviderFromEnv(ctx context.Context, keyID string, endpoint string) (kms.KeyProvider, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := kmsapi.NewFromConfig(cfg, func(o *kmsapi.Options) {
+		if endpoint != "" {
+			o.EndpointResolver = kmsapi.EndpointResolverFromURL(endpoint)
+		}
+	})
+
+	return NewAWSKeyProvider(client, keyID), nil
+}
+
+func (a *awsKeyProvider) WrapKey(ctx context.Context, plaintext []byte) ([]byte, error) {
+	res, err := a.client.Encrypt(ctx, &kmsapi.EncryptInput{
+		KeyId:             aws.String(a.keyID),
+		Plaintext:         plaintext,
+		EncryptionContext: encryptionContext,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.CiphertextBlob, nil
+}
+
+func (a *awsKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	res, err := a.client.Decrypt(ctx, &kmsapi.DecryptInput{
+		KeyId:             aws.String(a.keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: encryptionContext,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Plaintext, nil
+}
@@ -0,0 +1,70 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	kmsapi "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/monoid-privacy/monoid/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// setupEmulator starts a local-kms container, and returns a client
+// pointed at it.
+func setupEmulator(ctx context.Context) (testcontainers.Container, *kmsapi.Client, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "nsmithuk/local-kms:latest",
+			ExposedPorts: []string{"8080/tcp"},
+			WaitingFor:   wait.ForListeningPort("8080/tcp"),
+			AutoRemove:   true,
+		},
+		Started: true,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	endpoint, err := container.PortEndpoint(ctx, "8080/tcp", "http")
+	if err != nil {
+		container.Terminate(ctx)
+		return nil, nil, err
+	}
+
+	client := kmsapi.New(kmsapi.Options{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("test", "test", ""),
+		EndpointResolver: kmsapi.EndpointResolverFromURL(endpoint),
+	})
+
+	return container, client, nil
+}
+
+func TestAWSKeyProvider(t *testing.T) {
+	ctx := context.Background()
+
+	container, client, err := setupEmulator(ctx)
+	if err != nil {
+		t.Skip(fmt.Sprintf("could not start KMS emulator: %v", err))
+	}
+
+	defer container.Terminate(ctx)
+
+	key, err := client.CreateKey(ctx, &kmsapi.CreateKeyInput{})
+	require.NoError(t, err)
+
+	kp := NewAWSKeyProvider(client, *key.KeyMetadata.KeyId)
+
+	sealed, err := kms.Seal(ctx, kp, []byte("secret value"))
+	require.NoError(t, err)
+
+	opened, err := kms.Open(ctx, kp, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret value", string(opened))
+}
@@ -0,0 +1,46 @@
+package kms
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+type cachingKeyProvider struct {
+	provider KeyProvider
+	cache    *lru.Cache
+}
+
+// NewCachingKeyProvider wraps provider so that the results of UnwrapKey
+// are kept in memory for up to size keys. This avoids a round trip to
+// remote key management services every time a record is read.
+func NewCachingKeyProvider(provider KeyProvider, size int) (KeyProvider, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachingKeyProvider{
+		provider: provider,
+		cache:    cache,
+	}, nil
+}
+
+func (c *cachingKeyProvider) WrapKey(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return c.provider.WrapKey(ctx, plaintext)
+}
+
+func (c *cachingKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if key, ok := c.cache.Get(string(wrapped)); ok {
+		return key.([]byte), nil
+	}
+
+	key, err := c.provider.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Add(string(wrapped), key)
+
+	return key, nil
+}
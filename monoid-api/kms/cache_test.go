@@ -0,0 +1,62 @@
+package kms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	inner := &testKeyProvider{}
+
+	kp, err := NewCachingKeyProvider(inner, 1)
+	require.NoError(t, err)
+
+	first, err := Seal(ctx, kp, []byte("first"))
+	require.NoError(t, err)
+
+	second, err := Seal(ctx, kp, []byte("second"))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		opened, err := Open(ctx, kp, first)
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(opened))
+	}
+
+	// Only the first read unwraps the key, the rest hit the cache.
+	assert.Equal(t, 1, inner.unwrapCalls)
+
+	// The cache holds a single key, so reading the second value evicts
+	// the first one.
+	opened, err := Open(ctx, kp, second)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(opened))
+	assert.Equal(t, 2, inner.unwrapCalls)
+
+	_, err = Open(ctx, kp, first)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.unwrapCalls)
+}
+
+func TestCachingKeyProviderErrors(t *testing.T) {
+	ctx := context.Background()
+	inner := &testKeyProvider{}
+
+	kp, err := NewCachingKeyProvider(inner, 10)
+	require.NoError(t, err)
+
+	// Failed unwraps aren't cached.
+	for i := 0; i < 2; i++ {
+		_, err := kp.UnwrapKey(ctx, []byte("not wrapped"))
+		assert.Error(t, err)
+	}
+
+	assert.Equal(t, 2, inner.unwrapCalls)
+
+	_, err = NewCachingKeyProvider(inner, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// envelopeMagic prefixes every value encrypted with Seal so that it can
+// be distinguished from values encrypted directly with a raw key.
+var envelopeMagic = []byte("MNE1")
+
+// IsEnvelope returns true if data looks like it was produced by Seal.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, envelopeMagic)
+}
+
+// NewGCM returns an AES-GCM AEAD using key.
+func NewGCM(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(c)
+}
+
+// Seal encrypts plaintext with a new data key, and returns the
+// envelope, which contains the wrapped data key, the nonce and the
+// ciphertext. The format is:
+//
+//	magic (4 bytes) | wrapped key length (2 bytes) | wrapped key | nonce | ciphertext
+func Seal(ctx context.Context, kp KeyProvider, plaintext []byte) ([]byte, error) {
+	dataKey, wrapped, err := NewDataKey(ctx, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) > 0xFFFF {
+		return nil, fmt.Errorf("wrapped key is too long (%d bytes)", len(wrapped))
+	}
+
+	gcm, err := NewGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(envelopeMagic)+2+len(wrapped)+len(nonce)+len(plaintext)+gcm.Overhead())
+	res = append(res, envelopeMagic...)
+	res = append(res, 0, 0)
+	binary.BigEndian.PutUint16(res[len(envelopeMagic):], uint16(len(wrapped)))
+	res = append(res, wrapped...)
+	res = append(res, nonce...)
+
+	return gcm.Seal(res, nonce, plaintext, nil), nil
+}
+
+// Open decrypts an envelope created by Seal.
+func Open(ctx context.Context, kp KeyProvider, data []byte) ([]byte, error) {
+	if !IsEnvelope(data) {
+		return nil, fmt.Errorf("data is not an encrypted envelope")
+	}
+
+	rest := data[len(envelopeMagic):]
+	if len(rest) < 2 {
+		return nil, fmt.Errorf("envelope is truncated")
+	}
+
+	wrappedLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+
+	if len(rest) < wrappedLen {
+		return nil, fmt.Errorf("envelope is truncated")
+	}
+
+	dataKey, err := kp.UnwrapKey(ctx, rest[:wrappedLen])
+	if err != nil {
+		return nil, err
+	}
+
+	rest = rest[wrappedLen:]
+
+	gcm, err := NewGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("envelope is truncated")
+	}
+
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,106 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeyProvider "wraps" keys by prefixing them, and counts the calls to
+// UnwrapKey.
+type testKeyProvider struct {
+	unwrapCalls int
+}
+
+var testWrapPrefix = []byte("wrapped:")
+
+func (p *testKeyProvider) WrapKey(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return append(append([]byte{}, testWrapPrefix...), plaintext...), nil
+}
+
+func (p *testKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	p.unwrapCalls++
+
+	if !bytes.HasPrefix(wrapped, testWrapPrefix) {
+		return nil, fmt.Errorf("key was not wrapped by this provider")
+	}
+
+	return wrapped[len(testWrapPrefix):], nil
+}
+
+func TestOpenTruncated(t *testing.T) {
+	ctx := context.Background()
+	kp := &testKeyProvider{}
+
+	sealed, err := Seal(ctx, kp, []byte("secret value"))
+	require.NoError(t, err)
+
+	wrappedLen := len(testWrapPrefix) + DataKeySize
+
+	for _, n := range []int{
+		len(envelopeMagic),                    // no key length
+		len(envelopeMagic) + 1,                // partial key length
+		len(envelopeMagic) + 2 + wrappedLen/2, // partial wrapped key
+		len(envelopeMagic) + 2 + wrappedLen,   // no nonce
+		len(envelopeMagic) + 2 + wrappedLen + 4,
+	} {
+		_, err := Open(ctx, kp, sealed[:n])
+		assert.Error(t, err, "length %d", n)
+	}
+
+	// Dropping the end of the ciphertext fails authentication.
+	_, err = Open(ctx, kp, sealed[:len(sealed)-1])
+	assert.Error(t, err)
+}
+
+func TestOpenCorrupted(t *testing.T) {
+	ctx := context.Background()
+	kp := &testKeyProvider{}
+
+	sealed, err := Seal(ctx, kp, []byte("secret value"))
+	require.NoError(t, err)
+
+	// A wrapped key length longer than the envelope.
+	corrupt := append([]byte{}, sealed...)
+	corrupt[len(envelopeMagic)] = 0xFF
+	_, err = Open(ctx, kp, corrupt)
+	assert.Error(t, err)
+
+	// A wrapped key the provider can't unwrap.
+	corrupt = append([]byte{}, sealed...)
+	corrupt[len(envelopeMagic)+2] ^= 0xFF
+	_, err = Open(ctx, kp, corrupt)
+	assert.Error(t, err)
+
+	// A modified nonce.
+	corrupt = append([]byte{}, sealed...)
+	corrupt[len(envelopeMagic)+2+len(testWrapPrefix)+DataKeySize] ^= 0xFF
+	_, err = Open(ctx, kp, corrupt)
+	assert.Error(t, err)
+
+	opened, err := Open(ctx, kp, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret value", string(opened))
+}
+
+func TestOpenWrongMagic(t *testing.T) {
+	ctx := context.Background()
+	kp := &testKeyProvider{}
+
+	sealed, err := Seal(ctx, kp, []byte("secret value"))
+	require.NoError(t, err)
+
+	sealed[0] = 'X'
+	assert.False(t, IsEnvelope(sealed))
+
+	_, err = Open(ctx, kp, sealed)
+	assert.Error(t, err)
+	assert.Zero(t, kp.unwrapCalls)
+
+	_, err = Open(ctx, kp, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package kms
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+)
+
+// DataKeySize is the size (in bytes) of the data keys generated by
+// NewDataKey. Data keys are used as AES-256 keys.
+const DataKeySize = 32
+
+// KeyProvider wraps and unwraps data keys with a master key that
+// is never exposed to the caller. Data keys are used to encrypt the
+// actual data (a single record, an object in the file store, or all the
+// data for a workspace), and only the wrapped form is persisted alongside
+// the encrypted data.
+type KeyProvider interface {
+	// WrapKey encrypts the plaintext data key with the provider's
+	// master key, and returns the wrapped key.
+	WrapKey(ctx context.Context, plaintext []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a key that was previously returned by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// NewDataKey generates a new random data key, and returns both the
+// plaintext key and the key wrapped by the provider.
+func NewDataKey(ctx context.Context, kp KeyProvider) (plaintext []byte, wrapped []byte, err error) {
+	plaintext = make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err = kp.WrapKey(ctx, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plaintext, wrapped, nil
+}
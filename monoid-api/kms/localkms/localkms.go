@@ -0,0 +1,139 @@
+package localkms
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/monoid-privacy/monoid/kms"
+)
+
+// keyIDSize is the number of bytes of the master key's hash that are
+// stored with a wrapped key to identify the master key that wrapped it.
+const keyIDSize = 8
+
+type masterKey struct {
+	id  []byte
+	key []byte
+}
+
+type localKeyProvider struct {
+	keys []masterKey
+}
+
+// NewLocalKeyProvider creates a key provider that wraps data keys with
+// AES-GCM using the given master keys. The first key is used to wrap new
+// data keys, and the rest are only used to unwrap keys that were wrapped
+// before a key rotation.
+func NewLocalKeyProvider(masterKeys ...[]byte) (kms.KeyProvider, error) {
+	if len(masterKeys) == 0 {
+		return nil, fmt.Errorf("at least one master key is required")
+	}
+
+	keys := make([]masterKey, len(masterKeys))
+	for i, k := range masterKeys {
+		switch len(k) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("master keys must be 16, 24 or 32 bytes, got %d", len(k))
+		}
+
+		id := sha256.Sum256(k)
+		keys[i] = masterKey{
+			id:  id[:keyIDSize],
+			key: k,
+		}
+	}
+
+	return &localKeyProvider{keys: keys}, nil
+}
+
+// NewLocalKeyProviderFromFile creates a key provider from a key file. Each
+// non-empty line in the file that doesn't start with # is a base64 encoded
+// master key. The first key in the file is the active key.
+func NewLocalKeyProviderFromFile(path string) (kms.KeyProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	keys := [][]byte{}
+	sc := bufio.NewScanner(f)
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding key file: %v", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewLocalKeyProvider(keys...)
+}
+
+func (l *localKeyProvider) WrapKey(ctx context.Context, plaintext []byte) ([]byte, error) {
+	active := l.keys[0]
+
+	gcm, err := kms.NewGCM(active.key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, keyIDSize+len(nonce)+len(plaintext)+gcm.Overhead())
+	res = append(res, active.id...)
+	res = append(res, nonce...)
+
+	return gcm.Seal(res, nonce, plaintext, active.id), nil
+}
+
+func (l *localKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < keyIDSize {
+		return nil, fmt.Errorf("wrapped key is truncated")
+	}
+
+	id := wrapped[:keyIDSize]
+
+	for _, k := range l.keys {
+		if !bytes.Equal(k.id, id) {
+			continue
+		}
+
+		gcm, err := kms.NewGCM(k.key)
+		if err != nil {
+			return nil, err
+		}
+
+		rest := wrapped[keyIDSize:]
+		if len(rest) < gcm.NonceSize() {
+			return nil, fmt.Errorf("wrapped key is truncated")
+		}
+
+		return gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], k.id)
+	}
+
+	return nil, fmt.Errorf("no master key found for wrapped key")
+}
@@ -0,0 +1,78 @@
+package localkms
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKey1 = "Tc7ILcxCi68Xk7646IrNBYmbMzbWNU+s94fnZMJ1zzk="
+	testKey2 = "3uZ9ZrIJw8hDnGQ3l3x0m2cYbXk3k8m9rKcH6q2Jq6Y="
+)
+
+func mustDecode(t *testing.T, k string) []byte {
+	b, err := base64.StdEncoding.DecodeString(k)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+
+	kp, err := NewLocalKeyProvider(mustDecode(t, testKey1))
+	require.NoError(t, err)
+
+	sealed, err := kms.Seal(ctx, kp, []byte("secret value"))
+	require.NoError(t, err)
+	assert.True(t, kms.IsEnvelope(sealed))
+	assert.NotContains(t, string(sealed), "secret value")
+
+	opened, err := kms.Open(ctx, kp, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret value", string(opened))
+
+	// Tampering with the ciphertext must be detected.
+	sealed[len(sealed)-1] ^= 0xFF
+	_, err = kms.Open(ctx, kp, sealed)
+	assert.Error(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	oldKP, err := NewLocalKeyProvider(mustDecode(t, testKey1))
+	require.NoError(t, err)
+
+	sealed, err := kms.Seal(ctx, oldKP, []byte("secret value"))
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(
+		keyFile,
+		[]byte("# active key\n"+testKey2+"\n\n"+testKey1+"\n"),
+		0600,
+	))
+
+	newKP, err := NewLocalKeyProviderFromFile(keyFile)
+	require.NoError(t, err)
+
+	opened, err := kms.Open(ctx, newKP, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret value", string(opened))
+
+	// New data keys are wrapped with the first key in the file, so the
+	// old provider can't unwrap them.
+	sealed, err = kms.Seal(ctx, newKP, []byte("secret value"))
+	require.NoError(t, err)
+
+	_, err = kms.Open(ctx, oldKP, sealed)
+	assert.Error(t, err)
+}
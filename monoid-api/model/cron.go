@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CronFields are the fields of a parsed cron expression.
+type CronFields struct {
+	Minute     string
+	Hour       string
+	DayOfMonth string
+	Month      string
+	DayOfWeek  string
+}
+
+var cronFieldRegex = regexp.MustCompile(`^(\*|\d+(-\d+)?)(/\d+)?(,(\*|\d+(-\d+)?)(/\d+)?)*$`)
+
+// ParseCron splits a five field cron expression into its fields. Each
+// field is a comma separated list of values, ranges (1-5) or *, with an
+// optional step (*/15).
+func ParseCron(cron string) (CronFields, error) {
+	fields := strings.Fields(cron)
+	if len(fields) != 5 {
+		return CronFields{}, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+
+	for _, f := range fields {
+		if !cronFieldRegex.MatchString(f) {
+			return CronFields{}, fmt.Errorf("invalid cron field %q", f)
+		}
+	}
+
+	return CronFields{
+		Minute:     fields[0],
+		Hour:       fields[1],
+		DayOfMonth: fields[2],
+		Month:      fields[3],
+		DayOfWeek:  fields[4],
+	}, nil
+}
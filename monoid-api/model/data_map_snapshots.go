@@ -0,0 +1,364 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// SnapshotReasonDiscoveries is the reason for snapshots taken after
+	// discoveries are accepted.
+	SnapshotReasonDiscoveries = "discoveries"
+
+	// SnapshotReasonManualEdit is the reason for snapshots taken after the
+	// data map is edited by hand.
+	SnapshotReasonManualEdit = "manual_edit"
+)
+
+// DataMapSnapshot is an immutable version of the data map of a silo: its
+// data sources, their properties, and the properties' categories.
+// Versions are numbered from 1 for each silo.
+type DataMapSnapshot struct {
+	ID               string         `json:"id"`
+	SiloDefinitionID string         `json:"siloDefinitionId" gorm:"uniqueIndex:idx_data_map_snapshot_version"`
+	SiloDefinition   SiloDefinition `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Version          int            `json:"version" gorm:"uniqueIndex:idx_data_map_snapshot_version"`
+	Reason           string         `json:"reason"`
+	Data             datatypes.JSON `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DataSourceSnapshot is a data source in a data map snapshot.
+type DataSourceSnapshot struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Group       *string            `json:"group"`
+	Description *string            `json:"description"`
+	Properties  []PropertySnapshot `json:"properties"`
+}
+
+// PropertySnapshot is a property in a data map snapshot.
+type PropertySnapshot struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Type        *string  `json:"type"`
+	Format      *string  `json:"format"`
+	CategoryIDs []string `json:"categoryIds"`
+
+	UserPrimaryKeyID *string `json:"userPrimaryKeyId"`
+}
+
+// DataSources returns the data sources in the snapshot.
+func (s *DataMapSnapshot) DataSources() ([]DataSourceSnapshot, error) {
+	res := []DataSourceSnapshot{}
+	if err := json.Unmarshal(s.Data, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// currentDataMap returns the data map of a silo, sorted so that the same
+// data map always serializes the same way.
+func currentDataMap(siloID string, db *gorm.DB) ([]DataSourceSnapshot, error) {
+	dataSources := []DataSource{}
+	if err := db.Where("silo_definition_id = ?", siloID).Preload(
+		"Properties.Categories",
+	).Order("id").Find(&dataSources).Error; err != nil {
+		return nil, err
+	}
+
+	res := make([]DataSourceSnapshot, 0, len(dataSources))
+
+	for _, ds := range dataSources {
+		props := make([]PropertySnapshot, 0, len(ds.Properties))
+
+		for _, p := range ds.Properties {
+			cats := make([]string, 0, len(p.Categories))
+			for _, c := range p.Categories {
+				cats = append(cats, c.ID)
+			}
+
+			sort.Strings(cats)
+
+			props = append(props, PropertySnapshot{
+				ID:          p.ID,
+				Name:        p.Name,
+				Type:        p.Type,
+				Format:      p.Format,
+				CategoryIDs: cats,
+
+				UserPrimaryKeyID: p.UserPrimaryKeyID,
+			})
+		}
+
+		sort.Slice(props, func(i, j int) bool {
+			return props[i].ID < props[j].ID
+		})
+
+		res = append(res, DataSourceSnapshot{
+			ID:          ds.ID,
+			Name:        ds.Name,
+			Group:       ds.Group,
+			Description: ds.Description,
+			Properties:  props,
+		})
+	}
+
+	return res, nil
+}
+
+// CreateDataMapSnapshot records the current data map of a silo as a new
+// version. If the data map hasn't changed since the latest version, no
+// version is created and the latest one is returned.
+func CreateDataMapSnapshot(siloID string, reason string, db *gorm.DB) (*DataMapSnapshot, error) {
+	res := DataMapSnapshot{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the silo so that concurrent snapshots get different versions.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(
+			"id = ?", siloID,
+		).First(&SiloDefinition{}).Error; err != nil {
+			return err
+		}
+
+		dataMap, err := currentDataMap(siloID, tx)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(dataMap)
+		if err != nil {
+			return err
+		}
+
+		latest := DataMapSnapshot{}
+		err = tx.Where("silo_definition_id = ?", siloID).Order("version desc").First(&latest).Error
+
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil {
+			// The stored data is re-serialized to compare it, since the
+			// database doesn't keep the formatting of JSON columns.
+			latestMap, err := latest.DataSources()
+			if err != nil {
+				return err
+			}
+
+			latestData, err := json.Marshal(latestMap)
+			if err != nil {
+				return err
+			}
+
+			if bytes.Equal(latestData, data) {
+				res = latest
+				return nil
+			}
+		}
+
+		res = DataMapSnapshot{
+			ID:               uuid.NewString(),
+			SiloDefinitionID: siloID,
+			Version:          latest.Version + 1,
+			Reason:           reason,
+			Data:             data,
+		}
+
+		return tx.Create(&res).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// DataMapSnapshotAt returns the version of a silo's data map at a time, or
+// nil if the silo didn't have a snapshot yet.
+func DataMapSnapshotAt(siloID string, t time.Time, db *gorm.DB) (*DataMapSnapshot, error) {
+	res := DataMapSnapshot{}
+	if err := db.Where("silo_definition_id = ?", siloID).Where(
+		"created_at <= ?", t,
+	).Order("version desc").First(&res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// DataMapChange is a difference between two versions of a data map.
+// Properties of data sources that were added or removed aren't listed
+// separately.
+type DataMapChange struct {
+	Type               DataMapChangeType `json:"type"`
+	DataSourceID       string            `json:"dataSourceId"`
+	DataSourceName     string            `json:"dataSourceName"`
+	PropertyID         *string           `json:"propertyId"`
+	PropertyName       *string           `json:"propertyName"`
+	AddedCategoryIDs   []string          `json:"addedCategoryIds"`
+	RemovedCategoryIDs []string          `json:"removedCategoryIds"`
+
+	// The type and format of the property before and after a TYPE_CHANGED
+	// change.
+	OldType   *string `json:"oldType"`
+	NewType   *string `json:"newType"`
+	OldFormat *string `json:"oldFormat"`
+	NewFormat *string `json:"newFormat"`
+
+	// The user primary key the property was linked to before and after a
+	// PRIMARY_KEY_CHANGED change.
+	OldUserPrimaryKeyID *string `json:"oldUserPrimaryKeyId"`
+	NewUserPrimaryKeyID *string `json:"newUserPrimaryKeyId"`
+}
+
+// DiffDataMaps returns the changes from one version of a data map to
+// another.
+func DiffDataMaps(from []DataSourceSnapshot, to []DataSourceSnapshot) []DataMapChange {
+	fromSources := map[string]DataSourceSnapshot{}
+	for _, ds := range from {
+		fromSources[ds.ID] = ds
+	}
+
+	toSources := map[string]bool{}
+	res := []DataMapChange{}
+
+	change := func(t DataMapChangeType, ds DataSourceSnapshot, p *PropertySnapshot) DataMapChange {
+		c := DataMapChange{
+			Type:               t,
+			DataSourceID:       ds.ID,
+			DataSourceName:     ds.Name,
+			AddedCategoryIDs:   []string{},
+			RemovedCategoryIDs: []string{},
+		}
+
+		if p != nil {
+			c.PropertyID = &p.ID
+			c.PropertyName = &p.Name
+		}
+
+		return c
+	}
+
+	for _, ds := range to {
+		toSources[ds.ID] = true
+
+		prev, ok := fromSources[ds.ID]
+		if !ok {
+			res = append(res, change(DataMapChangeTypeDataSourceAdded, ds, nil))
+			continue
+		}
+
+		prevProps := map[string]PropertySnapshot{}
+		for _, p := range prev.Properties {
+			prevProps[p.ID] = p
+		}
+
+		props := map[string]bool{}
+
+		for i, p := range ds.Properties {
+			props[p.ID] = true
+
+			prevProp, ok := prevProps[p.ID]
+			if !ok {
+				res = append(res, change(DataMapChangeTypePropertyAdded, ds, &ds.Properties[i]))
+				continue
+			}
+
+			if !equalStringPtrs(prevProp.Type, p.Type) || !equalStringPtrs(prevProp.Format, p.Format) {
+				c := change(DataMapChangeTypeTypeChanged, ds, &ds.Properties[i])
+				c.OldType = prevProp.Type
+				c.NewType = p.Type
+				c.OldFormat = prevProp.Format
+				c.NewFormat = p.Format
+				res = append(res, c)
+			}
+
+			if !equalStringPtrs(prevProp.UserPrimaryKeyID, p.UserPrimaryKeyID) {
+				c := change(DataMapChangeTypePrimaryKeyChanged, ds, &ds.Properties[i])
+				c.OldUserPrimaryKeyID = prevProp.UserPrimaryKeyID
+				c.NewUserPrimaryKeyID = p.UserPrimaryKeyID
+				res = append(res, c)
+			}
+
+			added, removed := diffStrings(prevProp.CategoryIDs, p.CategoryIDs)
+			if len(added) == 0 && len(removed) == 0 {
+				continue
+			}
+
+			c := change(DataMapChangeTypeCategoriesChanged, ds, &ds.Properties[i])
+			c.AddedCategoryIDs = added
+			c.RemovedCategoryIDs = removed
+			res = append(res, c)
+		}
+
+		for i, p := range prev.Properties {
+			if !props[p.ID] {
+				res = append(res, change(DataMapChangeTypePropertyRemoved, ds, &prev.Properties[i]))
+			}
+		}
+	}
+
+	for _, ds := range from {
+		if !toSources[ds.ID] {
+			res = append(res, change(DataMapChangeTypeDataSourceRemoved, ds, nil))
+		}
+	}
+
+	return res
+}
+
+// equalStringPtrs returns true if a and b are both nil, or point to the
+// same string.
+func equalStringPtrs(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// diffStrings returns the strings that are only in b, and the strings that
+// are only in a.
+func diffStrings(a []string, b []string) ([]string, []string) {
+	inA := map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+
+	inB := map[string]bool{}
+	for _, s := range b {
+		inB[s] = true
+	}
+
+	added := []string{}
+	for _, s := range b {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+
+	removed := []string{}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
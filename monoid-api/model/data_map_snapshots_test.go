@@ -0,0 +1,113 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDataMaps(t *testing.T) {
+	from := []DataSourceSnapshot{
+		{ID: "ds1", Name: "users", Properties: []PropertySnapshot{
+			{ID: "p1", Name: "email", CategoryIDs: []string{"email"}},
+			{ID: "p2", Name: "phone", CategoryIDs: []string{}},
+			{ID: "p3", Name: "notes", CategoryIDs: []string{}},
+		}},
+		{ID: "ds2", Name: "orders"},
+	}
+
+	to := []DataSourceSnapshot{
+		{ID: "ds1", Name: "users", Properties: []PropertySnapshot{
+			{ID: "p1", Name: "email", CategoryIDs: []string{"email"}},
+			{ID: "p2", Name: "phone", CategoryIDs: []string{"phone"}},
+			{ID: "p4", Name: "address", CategoryIDs: []string{}},
+		}},
+		{ID: "ds3", Name: "events"},
+	}
+
+	changes := DiffDataMaps(from, to)
+
+	type change struct {
+		Type     DataMapChangeType
+		Source   string
+		Property string
+		Added    []string
+		Removed  []string
+	}
+
+	res := []change{}
+	for _, c := range changes {
+		prop := ""
+		if c.PropertyName != nil {
+			prop = *c.PropertyName
+		}
+
+		res = append(res, change{c.Type, c.DataSourceName, prop, c.AddedCategoryIDs, c.RemovedCategoryIDs})
+	}
+
+	assert.Equal(t, []change{
+		{DataMapChangeTypeCategoriesChanged, "users", "phone", []string{"phone"}, []string{}},
+		{DataMapChangeTypePropertyAdded, "users", "address", []string{}, []string{}},
+		{DataMapChangeTypePropertyRemoved, "users", "notes", []string{}, []string{}},
+		{DataMapChangeTypeDataSourceAdded, "events", "", []string{}, []string{}},
+		{DataMapChangeTypeDataSourceRemoved, "orders", "", []string{}, []string{}},
+	}, res)
+
+	assert.Empty(t, DiffDataMaps(to, to))
+}
+
+func TestDiffDataMapsTypeChanged(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	from := []DataSourceSnapshot{
+		{ID: "ds1", Name: "users", Properties: []PropertySnapshot{
+			{ID: "p1", Name: "created", Type: str("string"), CategoryIDs: []string{}},
+			{ID: "p2", Name: "age", Type: str("integer"), CategoryIDs: []string{}},
+		}},
+	}
+
+	to := []DataSourceSnapshot{
+		{ID: "ds1", Name: "users", Properties: []PropertySnapshot{
+			{ID: "p1", Name: "created", Type: str("string"), Format: str("date-time"), CategoryIDs: []string{}},
+			{ID: "p2", Name: "age", Type: str("integer"), CategoryIDs: []string{}},
+		}},
+	}
+
+	changes := DiffDataMaps(from, to)
+	assert.Len(t, changes, 1)
+
+	c := changes[0]
+	assert.Equal(t, DataMapChangeTypeTypeChanged, c.Type)
+	assert.Equal(t, "created", *c.PropertyName)
+	assert.Equal(t, str("string"), c.OldType)
+	assert.Equal(t, str("string"), c.NewType)
+	assert.Nil(t, c.OldFormat)
+	assert.Equal(t, str("date-time"), c.NewFormat)
+}
+
+func TestDiffDataMapsPrimaryKeyChanged(t *testing.T) {
+	pk := "pk1"
+
+	from := []DataSourceSnapshot{
+		{ID: "ds1", Name: "users", Properties: []PropertySnapshot{
+			{ID: "p1", Name: "email", CategoryIDs: []string{}},
+		}},
+	}
+
+	to := []DataSourceSnapshot{
+		{ID: "ds1", Name: "users", Properties: []PropertySnapshot{
+			{ID: "p1", Name: "email", CategoryIDs: []string{}, UserPrimaryKeyID: &pk},
+		}},
+	}
+
+	changes := DiffDataMaps(from, to)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, DataMapChangeTypePrimaryKeyChanged, changes[0].Type)
+	assert.Nil(t, changes[0].OldUserPrimaryKeyID)
+	assert.Equal(t, &pk, changes[0].NewUserPrimaryKeyID)
+
+	changes = DiffDataMaps(to, from)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, &pk, changes[0].OldUserPrimaryKeyID)
+	assert.Nil(t, changes[0].NewUserPrimaryKeyID)
+}
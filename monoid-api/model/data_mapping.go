@@ -169,6 +169,12 @@ type Property struct {
 	UserPrimaryKeyID *string
 	UserPrimaryKey   *UserPrimaryKey `gorm:"constraint:OnUpdate:CASCADE;"`
 
+	// Type and Format are the JSON schema type and format of the property
+	// the last time it was discovered, they're nil for properties that
+	// haven't been scanned.
+	Type   *string `json:"type"`
+	Format *string `json:"format"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
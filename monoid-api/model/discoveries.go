@@ -58,6 +58,18 @@ func (dd *DataDiscovery) DeserializeData() (DataDiscoveryData, error) {
 			return nil, err
 		}
 		return res, nil
+	case DiscoveryTypePropertyChanged:
+		res := PropertyChangedDiscovery{}
+		if err := json.Unmarshal(dd.Data, &res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	case DiscoveryTypePrimaryKeySuggested:
+		res := PrimaryKeySuggestedDiscovery{}
+		if err := json.Unmarshal(dd.Data, &res); err != nil {
+			return nil, err
+		}
+		return res, nil
 	case DiscoveryTypeDataSourceMissing:
 		res := DataSourceMissingDiscovery{}
 		if err := json.Unmarshal(dd.Data, &res); err != nil {
@@ -100,6 +112,14 @@ type NewPropertyDiscovery struct {
 	Name            string                 `json:"name"`
 	DataSourceId    *string                `json:"dataSourceId"`
 	Categories      []NewCategoryDiscovery `json:"categories"`
+
+	// Type and Format are the JSON schema type and format of the property.
+	Type   *string `json:"type,omitempty"`
+	Format *string `json:"format,omitempty"`
+
+	// UserPrimaryKeyID is the user primary key the property is linked to
+	// when the discovery is accepted, suggested from its categories.
+	UserPrimaryKeyID *string `json:"userPrimaryKeyId,omitempty"`
 }
 
 func (NewPropertyDiscovery) IsDataDiscoveryData() {}
@@ -125,6 +145,24 @@ type NewCategoryDiscovery struct {
 	DataDiscoveryID string  `json:"-"`
 	PropertyID      *string `json:"propertyId"`
 	CategoryID      string  `json:"categoryId"`
+
+	// Score is the strength of the evidence for the category, from 0 to 1.
+	// Discoveries made before scores were introduced don't have one.
+	Score *float64 `json:"score,omitempty"`
+
+	// Evidence is why the scanner reported the category.
+	Evidence []CategoryEvidence `json:"evidence,omitempty"`
+}
+
+// CategoryEvidence is a rule match that led the scanner to report a
+// category. Only masked samples of the matched values are kept, never the
+// values themselves.
+type CategoryEvidence struct {
+	RuleName    string   `json:"ruleName"`
+	MatchType   string   `json:"matchType"`
+	MatchCount  int      `json:"matchCount"`
+	RowsScanned int      `json:"rowsScanned"`
+	Samples     []string `json:"samples"`
 }
 
 func (NewCategoryDiscovery) IsDataDiscoveryData() {}
@@ -163,3 +201,59 @@ func (DataSourceMissingDiscovery) IsDataDiscoveryData() {}
 func (d DataSourceMissingDiscovery) Mappable() interface{} {
 	return d
 }
+
+// PropertyChangedDiscovery is a change to the JSON schema type or format
+// of a property.
+type PropertyChangedDiscovery struct {
+	ID        string  `json:"id"`
+	OldType   *string `json:"oldType"`
+	NewType   *string `json:"newType"`
+	OldFormat *string `json:"oldFormat"`
+	NewFormat *string `json:"newFormat"`
+}
+
+func (PropertyChangedDiscovery) IsDataDiscoveryData() {}
+
+type propertyChangedDiscoveryKey struct {
+	ID        string
+	NewType   string
+	NewFormat string
+}
+
+// Mappable keys the discovery on the property and its new type, so that a
+// property that changes again gets a new discovery.
+func (d PropertyChangedDiscovery) Mappable() interface{} {
+	key := propertyChangedDiscoveryKey{ID: d.ID}
+	if d.NewType != nil {
+		key.NewType = *d.NewType
+	}
+
+	if d.NewFormat != nil {
+		key.NewFormat = *d.NewFormat
+	}
+
+	return key
+}
+
+// PrimaryKeySuggestedDiscovery suggests linking a property to a user
+// primary key, because the property was classified with a category that
+// has the key's API identifier.
+type PrimaryKeySuggestedDiscovery struct {
+	PropertyID       string `json:"propertyId"`
+	UserPrimaryKeyID string `json:"userPrimaryKeyId"`
+	CategoryID       string `json:"categoryId"`
+}
+
+func (PrimaryKeySuggestedDiscovery) IsDataDiscoveryData() {}
+
+type primaryKeySuggestedDiscoveryKey struct {
+	PropertyID       string
+	UserPrimaryKeyID string
+}
+
+func (d PrimaryKeySuggestedDiscovery) Mappable() interface{} {
+	return primaryKeySuggestedDiscoveryKey{
+		PropertyID:       d.PropertyID,
+		UserPrimaryKeyID: d.UserPrimaryKeyID,
+	}
+}
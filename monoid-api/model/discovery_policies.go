@@ -0,0 +1,134 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DiscoveryPolicy automatically accepts or rejects the discoveries that
+// match all of its conditions. Conditions that aren't set match every
+// discovery.
+type DiscoveryPolicy struct {
+	ID          string                `json:"id"`
+	WorkspaceID string                `json:"workspaceId"`
+	Workspace   Workspace             `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Name        string                `json:"name"`
+	Action      DiscoveryPolicyAction `json:"action"`
+
+	// DiscoveryTypes are the types of discoveries the policy applies to.
+	DiscoveryTypes datatypes.JSON `json:"-"`
+
+	SiloDefinitionID *string        `json:"siloDefinitionId"`
+	SiloDefinition   SiloDefinition `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+
+	// CategoryIDs matches discoveries that found any of the categories.
+	CategoryIDs datatypes.JSON `json:"-"`
+
+	// DataSourcePattern is a regex that the name of the discovery's data
+	// source must match.
+	DataSourcePattern *string `json:"dataSourcePattern"`
+
+	// MinScore is the minimum score every category found by the discovery
+	// must have.
+	MinScore *float64 `json:"minScore"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DiscoveryTypeList returns the discovery types the policy applies to, an
+// empty list means all types.
+func (p *DiscoveryPolicy) DiscoveryTypeList() ([]DiscoveryType, error) {
+	res := []DiscoveryType{}
+	if len(p.DiscoveryTypes) == 0 {
+		return res, nil
+	}
+
+	if err := json.Unmarshal(p.DiscoveryTypes, &res); err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		return []DiscoveryType{}, nil
+	}
+
+	return res, nil
+}
+
+// CategoryIDList returns the categories the policy applies to, an empty
+// list means all categories.
+func (p *DiscoveryPolicy) CategoryIDList() ([]string, error) {
+	res := []string{}
+	if len(p.CategoryIDs) == 0 {
+		return res, nil
+	}
+
+	if err := json.Unmarshal(p.CategoryIDs, &res); err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		return []string{}, nil
+	}
+
+	return res, nil
+}
+
+// DiscoveryDecision records a discovery being accepted or rejected. Decisions
+// made by a policy have the policy's id and name, the name is kept so that
+// the decision can be explained after the policy is deleted. Reverting a
+// decision is recorded as an OPEN decision that refers to it.
+type DiscoveryDecision struct {
+	ID              string          `json:"id"`
+	DataDiscoveryID string          `json:"dataDiscoveryId"`
+	DataDiscovery   DataDiscovery   `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Status          DiscoveryStatus `json:"status"`
+
+	PolicyID   *string         `json:"policyId"`
+	Policy     DiscoveryPolicy `json:"-" gorm:"constraint:OnDelete:SET NULL;"`
+	PolicyName *string         `json:"policyName"`
+
+	// RevertedDecisionID is the decision that this decision reverted.
+	RevertedDecisionID *string            `json:"revertedDecisionId"`
+	RevertedDecision   *DiscoveryDecision `json:"-" gorm:"constraint:OnDelete:SET NULL;"`
+
+	// Effect is what accepting the discovery changed that isn't in the
+	// discovery's data, so that the decision can be reverted.
+	Effect datatypes.JSON `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DiscoveryEffect is the part of the change made by accepting a discovery
+// that's needed to revert it.
+type DiscoveryEffect struct {
+	// DataSourceID and PropertyIDs are the objects that were created or
+	// deleted.
+	DataSourceID *string  `json:"dataSourceId,omitempty"`
+	PropertyIDs  []string `json:"propertyIds,omitempty"`
+
+	// Categories are the category ids of the properties that were deleted,
+	// by property id.
+	Categories map[string][]string `json:"categories,omitempty"`
+
+	// CategoryAdded is true if accepting a category discovery added the
+	// category to the property, rather than it already being there.
+	CategoryAdded bool `json:"categoryAdded,omitempty"`
+}
+
+// DecodeEffect returns the effect of the decision, or nil if it doesn't
+// have one.
+func (d *DiscoveryDecision) DecodeEffect() (*DiscoveryEffect, error) {
+	if len(d.Effect) == 0 {
+		return nil, nil
+	}
+
+	res := DiscoveryEffect{}
+	if err := json.Unmarshal(d.Effect, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
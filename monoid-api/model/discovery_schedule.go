@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// DiscoverySchedule is a recurring scan of a silo for new data sources and
+// properties. Each schedule is backed by a Temporal schedule, that starts a
+// discover_sources job on every run.
+type DiscoverySchedule struct {
+	ID               string         `json:"id"`
+	SiloDefinitionID string         `json:"siloDefinitionId" gorm:"uniqueIndex"`
+	SiloDefinition   SiloDefinition `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	WorkspaceID      string         `json:"workspaceId"`
+	Workspace        Workspace      `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+
+	// Cron is a five field cron expression (minute, hour, day of month,
+	// month, day of week), interpreted in UTC.
+	Cron string `json:"cron"`
+
+	// JitterSeconds is the maximum random delay added to each run, so
+	// that silos on the same schedule aren't all scanned at once.
+	JitterSeconds int  `json:"jitterSeconds"`
+	Paused        bool `json:"paused"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
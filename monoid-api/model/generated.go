@@ -28,11 +28,39 @@ type CreateDataSourceInput struct {
 	Properties       []*PropertyInput `json:"properties"`
 }
 
+type CreateDiscoveryPolicyInput struct {
+	WorkspaceID       string                `json:"workspaceId"`
+	Name              string                `json:"name"`
+	Action            DiscoveryPolicyAction `json:"action"`
+	DiscoveryTypes    []DiscoveryType       `json:"discoveryTypes"`
+	SiloDefinitionID  *string               `json:"siloDefinitionId"`
+	CategoryIds       []string              `json:"categoryIds"`
+	DataSourcePattern *string               `json:"dataSourcePattern"`
+	MinScore          *float64              `json:"minScore"`
+}
+
+type CreateNotificationChannelInput struct {
+	WorkspaceID string                  `json:"workspaceId"`
+	Name        string                  `json:"name"`
+	Type        NotificationChannelType `json:"type"`
+	URL         string                  `json:"url"`
+}
+
 type CreatePropertyInput struct {
 	Property     *PropertyInput `json:"property"`
 	DataSourceID string         `json:"dataSourceID"`
 }
 
+type CreateScannerRuleInput struct {
+	WorkspaceID string          `json:"workspaceId"`
+	Name        string          `json:"name"`
+	Kind        ScannerRuleKind `json:"kind"`
+	Pattern     *string         `json:"pattern"`
+	ColumnNames []string        `json:"columnNames"`
+	Confidence  *string         `json:"confidence"`
+	CategoryID  string          `json:"categoryId"`
+}
+
 type CreateSiloDefinitionInput struct {
 	Description         *string  `json:"description"`
 	SiloSpecificationID string   `json:"siloSpecificationID"`
@@ -81,6 +109,14 @@ type DataMapResult struct {
 	NumRows     int           `json:"numRows"`
 }
 
+type DataSourcePreview struct {
+	Records []map[string]interface{} `json:"records"`
+	Masked  bool                     `json:"masked"`
+	// True if records were left out, or values were shortened, to keep the
+	// preview under the size limit.
+	Truncated bool `json:"truncated"`
+}
+
 type DownloadLink struct {
 	URL string `json:"url"`
 }
@@ -130,6 +166,18 @@ type RequestsResult struct {
 	NumRequests int        `json:"numRequests"`
 }
 
+// A set of optional scanner rules that are enabled together, e.g. the
+// identifiers used in a country. Rule packs are enabled with the
+// scannerRulePacks workspace setting.
+type ScannerRulePack struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// The ids of the categories the pack's rules report.
+	Categories []string `json:"categories"`
+	Enabled    bool     `json:"enabled"`
+}
+
 type UpdateCategoryInput struct {
 	Name *string `json:"name"`
 }
@@ -139,6 +187,12 @@ type UpdateDataSourceInput struct {
 	Description *string `json:"description"`
 }
 
+type UpdateDiscoveryScheduleInput struct {
+	SiloDefinitionID string `json:"siloDefinitionId"`
+	Cron             string `json:"cron"`
+	JitterSeconds    *int   `json:"jitterSeconds"`
+}
+
 type UpdatePropertyInput struct {
 	ID          string   `json:"id"`
 	CategoryIDs []string `json:"categoryIDs"`
@@ -191,6 +245,57 @@ type UserPrimaryKeyInput struct {
 	Value         string `json:"value"`
 }
 
+type DataMapChangeType string
+
+const (
+	DataMapChangeTypeDataSourceAdded   DataMapChangeType = "DATA_SOURCE_ADDED"
+	DataMapChangeTypeDataSourceRemoved DataMapChangeType = "DATA_SOURCE_REMOVED"
+	DataMapChangeTypePropertyAdded     DataMapChangeType = "PROPERTY_ADDED"
+	DataMapChangeTypePropertyRemoved   DataMapChangeType = "PROPERTY_REMOVED"
+	DataMapChangeTypeCategoriesChanged DataMapChangeType = "CATEGORIES_CHANGED"
+	DataMapChangeTypeTypeChanged       DataMapChangeType = "TYPE_CHANGED"
+	DataMapChangeTypePrimaryKeyChanged DataMapChangeType = "PRIMARY_KEY_CHANGED"
+)
+
+var AllDataMapChangeType = []DataMapChangeType{
+	DataMapChangeTypeDataSourceAdded,
+	DataMapChangeTypeDataSourceRemoved,
+	DataMapChangeTypePropertyAdded,
+	DataMapChangeTypePropertyRemoved,
+	DataMapChangeTypeCategoriesChanged,
+	DataMapChangeTypeTypeChanged,
+	DataMapChangeTypePrimaryKeyChanged,
+}
+
+func (e DataMapChangeType) IsValid() bool {
+	switch e {
+	case DataMapChangeTypeDataSourceAdded, DataMapChangeTypeDataSourceRemoved, DataMapChangeTypePropertyAdded, DataMapChangeTypePropertyRemoved, DataMapChangeTypeCategoriesChanged, DataMapChangeTypeTypeChanged, DataMapChangeTypePrimaryKeyChanged:
+		return true
+	}
+	return false
+}
+
+func (e DataMapChangeType) String() string {
+	return string(e)
+}
+
+func (e *DataMapChangeType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DataMapChangeType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DataMapChangeType", str)
+	}
+	return nil
+}
+
+func (e DataMapChangeType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type DiscoveryAction string
 
 const (
@@ -232,6 +337,52 @@ func (e DiscoveryAction) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
+// What a discovery policy does with the discoveries it matches. REVIEW
+// keeps them open for a manual decision, and takes precedence over REJECT,
+// which takes precedence over ACCEPT.
+type DiscoveryPolicyAction string
+
+const (
+	DiscoveryPolicyActionAccept DiscoveryPolicyAction = "ACCEPT"
+	DiscoveryPolicyActionReject DiscoveryPolicyAction = "REJECT"
+	DiscoveryPolicyActionReview DiscoveryPolicyAction = "REVIEW"
+)
+
+var AllDiscoveryPolicyAction = []DiscoveryPolicyAction{
+	DiscoveryPolicyActionAccept,
+	DiscoveryPolicyActionReject,
+	DiscoveryPolicyActionReview,
+}
+
+func (e DiscoveryPolicyAction) IsValid() bool {
+	switch e {
+	case DiscoveryPolicyActionAccept, DiscoveryPolicyActionReject, DiscoveryPolicyActionReview:
+		return true
+	}
+	return false
+}
+
+func (e DiscoveryPolicyAction) String() string {
+	return string(e)
+}
+
+func (e *DiscoveryPolicyAction) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DiscoveryPolicyAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DiscoveryPolicyAction", str)
+	}
+	return nil
+}
+
+func (e DiscoveryPolicyAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type DiscoveryStatus string
 
 const (
@@ -278,11 +429,13 @@ func (e DiscoveryStatus) MarshalGQL(w io.Writer) {
 type DiscoveryType string
 
 const (
-	DiscoveryTypeDataSourceMissing DiscoveryType = "DATA_SOURCE_MISSING"
-	DiscoveryTypeDataSourceFound   DiscoveryType = "DATA_SOURCE_FOUND"
-	DiscoveryTypePropertyFound     DiscoveryType = "PROPERTY_FOUND"
-	DiscoveryTypePropertyMissing   DiscoveryType = "PROPERTY_MISSING"
-	DiscoveryTypeCategoryFound     DiscoveryType = "CATEGORY_FOUND"
+	DiscoveryTypeDataSourceMissing   DiscoveryType = "DATA_SOURCE_MISSING"
+	DiscoveryTypeDataSourceFound     DiscoveryType = "DATA_SOURCE_FOUND"
+	DiscoveryTypePropertyFound       DiscoveryType = "PROPERTY_FOUND"
+	DiscoveryTypePropertyMissing     DiscoveryType = "PROPERTY_MISSING"
+	DiscoveryTypePropertyChanged     DiscoveryType = "PROPERTY_CHANGED"
+	DiscoveryTypeCategoryFound       DiscoveryType = "CATEGORY_FOUND"
+	DiscoveryTypePrimaryKeySuggested DiscoveryType = "PRIMARY_KEY_SUGGESTED"
 )
 
 var AllDiscoveryType = []DiscoveryType{
@@ -290,12 +443,14 @@ var AllDiscoveryType = []DiscoveryType{
 	DiscoveryTypeDataSourceFound,
 	DiscoveryTypePropertyFound,
 	DiscoveryTypePropertyMissing,
+	DiscoveryTypePropertyChanged,
 	DiscoveryTypeCategoryFound,
+	DiscoveryTypePrimaryKeySuggested,
 }
 
 func (e DiscoveryType) IsValid() bool {
 	switch e {
-	case DiscoveryTypeDataSourceMissing, DiscoveryTypeDataSourceFound, DiscoveryTypePropertyFound, DiscoveryTypePropertyMissing, DiscoveryTypeCategoryFound:
+	case DiscoveryTypeDataSourceMissing, DiscoveryTypeDataSourceFound, DiscoveryTypePropertyFound, DiscoveryTypePropertyMissing, DiscoveryTypePropertyChanged, DiscoveryTypeCategoryFound, DiscoveryTypePrimaryKeySuggested:
 		return true
 	}
 	return false
@@ -416,6 +571,50 @@ func (e JobStatus) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
+// The payload sent to a notification channel. WEBHOOK channels get the event
+// as JSON, and SLACK channels get a message for a Slack-compatible incoming
+// webhook.
+type NotificationChannelType string
+
+const (
+	NotificationChannelTypeWebhook NotificationChannelType = "WEBHOOK"
+	NotificationChannelTypeSLACk   NotificationChannelType = "SLACK"
+)
+
+var AllNotificationChannelType = []NotificationChannelType{
+	NotificationChannelTypeWebhook,
+	NotificationChannelTypeSLACk,
+}
+
+func (e NotificationChannelType) IsValid() bool {
+	switch e {
+	case NotificationChannelTypeWebhook, NotificationChannelTypeSLACk:
+		return true
+	}
+	return false
+}
+
+func (e NotificationChannelType) String() string {
+	return string(e)
+}
+
+func (e *NotificationChannelType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationChannelType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationChannelType", str)
+	}
+	return nil
+}
+
+func (e NotificationChannelType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type RequestStatusType string
 
 const (
@@ -463,6 +662,47 @@ func (e RequestStatusType) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
+type ScannerRuleKind string
+
+const (
+	ScannerRuleKindName  ScannerRuleKind = "NAME"
+	ScannerRuleKindRegex ScannerRuleKind = "REGEX"
+)
+
+var AllScannerRuleKind = []ScannerRuleKind{
+	ScannerRuleKindName,
+	ScannerRuleKindRegex,
+}
+
+func (e ScannerRuleKind) IsValid() bool {
+	switch e {
+	case ScannerRuleKindName, ScannerRuleKindRegex:
+		return true
+	}
+	return false
+}
+
+func (e ScannerRuleKind) String() string {
+	return string(e)
+}
+
+func (e *ScannerRuleKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScannerRuleKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScannerRuleKind", str)
+	}
+	return nil
+}
+
+func (e ScannerRuleKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type UpdateRequestStatusType string
 
 const (
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// NotificationChannel is a webhook that a workspace's notifications, such
+// as new discoveries, are sent to.
+type NotificationChannel struct {
+	ID          string                  `json:"id"`
+	WorkspaceID string                  `json:"workspaceId"`
+	Workspace   Workspace               `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Name        string                  `json:"name"`
+	Type        NotificationChannelType `json:"type"`
+
+	// URL is the webhook's URL. It's kept secret, since webhook URLs
+	// usually include a token.
+	URL SecretString `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
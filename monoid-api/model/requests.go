@@ -21,6 +21,11 @@ type RequestStatus struct {
 	Status        RequestStatusType
 	RequestHandle SecretString
 
+	// UnmappedPersonalData is true if the data source had personal data,
+	// but couldn't be searched because it isn't linked to a user primary
+	// key.
+	UnmappedPersonalData bool `json:"unmappedPersonalData"`
+
 	QueryResult *QueryResult
 }
 
@@ -36,6 +41,25 @@ type UserPrimaryKey struct {
 type DownloadableFile struct {
 	ID          string
 	StoragePath string
+
+	// PurgedAt is set once the file has been removed from the file store
+	// because it expired.
+	PurgedAt *time.Time
+}
+
+// RequestTombstone records that the results of a request were purged
+// after the workspace's retention period expired.
+type RequestTombstone struct {
+	ID          string
+	RequestID   string  `gorm:"uniqueIndex"`
+	Request     Request `gorm:"constraint:OnDelete:CASCADE;"`
+	WorkspaceID string
+
+	QueryResultsPurged     int
+	FilesPurged            int
+	PrimaryKeyValuesPurged int
+
+	PurgedAt time.Time
 }
 
 type Request struct {
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"encoding/json"
+
+	"gorm.io/datatypes"
+)
+
+// ScannerRule is a custom rule that the PII scanner runs in addition to
+// its built-in rules. Rules without a workspace apply to all workspaces.
+type ScannerRule struct {
+	ID          string          `json:"id" yaml:"id"`
+	WorkspaceID *string         `json:"workspaceId" yaml:"workspaceId"`
+	Workspace   Workspace       `json:"-" yaml:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Name        string          `json:"name" yaml:"name"`
+	Kind        ScannerRuleKind `json:"kind" yaml:"kind"`
+	Pattern     *string         `json:"pattern" yaml:"pattern"`
+	ColumnNames datatypes.JSON  `json:"-" yaml:"-"`
+	Confidence  string          `json:"confidence" yaml:"confidence"`
+	CategoryID  string          `json:"categoryId" yaml:"categoryId"`
+	Category    Category        `json:"-" yaml:"-" gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+// ColumnNameList returns the column names matched by a NAME rule.
+func (r *ScannerRule) ColumnNameList() ([]string, error) {
+	res := []string{}
+	if len(r.ColumnNames) == 0 {
+		return res, nil
+	}
+
+	if err := json.Unmarshal(r.ColumnNames, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
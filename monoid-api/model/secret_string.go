@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -8,6 +9,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/monoid-privacy/monoid/kms"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
@@ -16,11 +18,19 @@ import (
 type SecretString string
 
 var encryptionKey []byte
+var keyProvider kms.KeyProvider
 
 func SetEncryptionKey(key []byte) {
 	encryptionKey = key
 }
 
+// SetKeyProvider sets the key provider used to envelope encrypt secrets.
+// Secrets that were encrypted directly with the encryption key can still
+// be read after a key provider is set.
+func SetKeyProvider(kp kms.KeyProvider) {
+	keyProvider = kp
+}
+
 func gcmCipher() (cipher.AEAD, error) {
 	c, err := aes.NewCipher(encryptionKey)
 
@@ -37,27 +47,51 @@ func gcmCipher() (cipher.AEAD, error) {
 	return gcm, err
 }
 
-func (s *SecretString) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("could not scan value")
-	}
-
+// openLegacy decrypts a secret that was encrypted directly with the
+// encryption key.
+func openLegacy(bytes []byte) ([]byte, error) {
 	gcm, err := gcmCipher()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	nonceSize := gcm.NonceSize()
 	if len(bytes) < nonceSize {
-		log.Err(err).Msg("Invalid secret")
+		return nil, fmt.Errorf("invalid secret")
 	}
 
 	nonce, ciphertext := bytes[:nonceSize], bytes[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func (s *SecretString) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("could not scan value")
+	}
+
+	if keyProvider != nil && kms.IsEnvelope(bytes) {
+		plaintext, err := kms.Open(context.Background(), keyProvider, bytes)
+		if err != nil {
+			// A secret encrypted with the encryption key can start with the
+			// envelope magic by chance, so only use it if it decrypts.
+			legacy, legacyErr := openLegacy(bytes)
+			if legacyErr != nil {
+				return err
+			}
+
+			plaintext = legacy
+		}
+
+		*s = SecretString(plaintext)
+		return nil
+	}
+
+	plaintext, err := openLegacy(bytes)
 	if err != nil {
 		log.Err(err).Msg("Error decoding token")
+		return err
 	}
 
 	*s = SecretString(plaintext)
@@ -69,6 +103,10 @@ func (s SecretString) GormDBDataType(db *gorm.DB, field *schema.Field) string {
 }
 
 func (s SecretString) ValueBytes() ([]byte, error) {
+	if keyProvider != nil {
+		return kms.Seal(context.Background(), keyProvider, []byte(s))
+	}
+
 	gcm, err := gcmCipher()
 
 	if err != nil {
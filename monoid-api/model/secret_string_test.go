@@ -0,0 +1,66 @@
+package model
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/kms/localkms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKey1 = "Tc7ILcxCi68Xk7646IrNBYmbMzbWNU+s94fnZMJ1zzk="
+	testKey2 = "3uZ9ZrIJw8hDnGQ3l3x0m2cYbXk3k8m9rKcH6q2Jq6Y="
+)
+
+func mustDecode(t *testing.T, k string) []byte {
+	b, err := base64.StdEncoding.DecodeString(k)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestSecretStringScan(t *testing.T) {
+	SetEncryptionKey(mustDecode(t, testKey1))
+	defer SetKeyProvider(nil)
+
+	// Secrets encrypted directly with the encryption key.
+	SetKeyProvider(nil)
+
+	legacy, err := SecretString("legacy").ValueBytes()
+	require.NoError(t, err)
+
+	s := SecretString("")
+	require.NoError(t, s.Scan(legacy))
+	assert.Equal(t, SecretString("legacy"), s)
+
+	corrupt := append([]byte{}, legacy...)
+	corrupt[len(corrupt)-1] ^= 0xFF
+	assert.Error(t, s.Scan(corrupt))
+	assert.Error(t, s.Scan([]byte("short")))
+
+	// Envelope encrypted secrets.
+	kp, err := localkms.NewLocalKeyProvider(mustDecode(t, testKey1))
+	require.NoError(t, err)
+	SetKeyProvider(kp)
+
+	sealed, err := SecretString("sealed").ValueBytes()
+	require.NoError(t, err)
+
+	require.NoError(t, s.Scan(sealed))
+	assert.Equal(t, SecretString("sealed"), s)
+
+	// Legacy secrets can still be read once a key provider is set.
+	require.NoError(t, s.Scan(legacy))
+	assert.Equal(t, SecretString("legacy"), s)
+
+	// An envelope that can't be opened is an error, not an empty secret.
+	other, err := localkms.NewLocalKeyProvider(mustDecode(t, testKey2))
+	require.NoError(t, err)
+	SetKeyProvider(other)
+
+	s = SecretString("unchanged")
+	assert.Error(t, s.Scan(sealed))
+	assert.Equal(t, SecretString("unchanged"), s)
+}
@@ -0,0 +1,75 @@
+package model
+
+import "gorm.io/gorm"
+
+// UnmappedPersonalData is a data source with properties that have personal
+// data categories, but no property linked to a user primary key, so user
+// data requests can't search it.
+type UnmappedPersonalData struct {
+	DataSource *DataSource `json:"dataSource"`
+	Categories []*Category `json:"categories"`
+}
+
+// UnmappedCategories returns the categories of the data source's properties
+// if none of them are linked to a user primary key, or nil if the data source
+// is linked or has no personal data. The properties must be loaded with
+// their categories.
+func (ds *DataSource) UnmappedCategories() []*Category {
+	seen := map[string]bool{}
+	res := []*Category{}
+
+	for _, p := range ds.Properties {
+		if p.UserPrimaryKeyID != nil {
+			return nil
+		}
+
+		for _, c := range p.Categories {
+			if seen[c.ID] {
+				continue
+			}
+
+			seen[c.ID] = true
+			res = append(res, c)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// FindUnmappedPersonalData returns the data sources in a workspace with
+// personal data that isn't linked to a user primary key. Manual silos are
+// skipped, since their requests are handled by hand.
+func FindUnmappedPersonalData(workspaceID string, db *gorm.DB) ([]*UnmappedPersonalData, error) {
+	dataSources := []*DataSource{}
+	if err := db.Joins(
+		"JOIN silo_definitions ON data_sources.silo_definition_id = silo_definitions.id",
+	).Joins(
+		"JOIN silo_specifications ON silo_definitions.silo_specification_id = silo_specifications.id",
+	).Where(
+		"silo_definitions.workspace_id = ?", workspaceID,
+	).Where(
+		"silo_specifications.manual = ?", false,
+	).Preload("Properties.Categories").Order("data_sources.name").Find(&dataSources).Error; err != nil {
+		return nil, err
+	}
+
+	res := []*UnmappedPersonalData{}
+
+	for _, ds := range dataSources {
+		categories := ds.UnmappedCategories()
+		if categories == nil {
+			continue
+		}
+
+		res = append(res, &UnmappedPersonalData{
+			DataSource: ds,
+			Categories: categories,
+		})
+	}
+
+	return res, nil
+}
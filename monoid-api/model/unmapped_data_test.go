@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmappedCategories(t *testing.T) {
+	pk := "pk"
+	email := &Category{ID: "email"}
+	phone := &Category{ID: "phone"}
+
+	ds := &DataSource{Properties: []*Property{
+		{ID: "p1", Categories: []*Category{email}},
+		{ID: "p2", Categories: []*Category{email, phone}},
+		{ID: "p3"},
+	}}
+
+	assert.Equal(t, []*Category{email, phone}, ds.UnmappedCategories())
+
+	// Data sources linked to a primary key can be searched.
+	ds.Properties[2].UserPrimaryKeyID = &pk
+	assert.Nil(t, ds.UnmappedCategories())
+
+	// Data sources without personal data don't need to be.
+	assert.Nil(t, (&DataSource{Properties: []*Property{{ID: "p1"}}}).UnmappedCategories())
+}
@@ -26,6 +26,42 @@ type WorkspaceSettings struct {
 	Email         string `json:"email"`
 	SendNews      bool   `json:"sendNews"`
 	AnonymizeData bool   `json:"anonymizeData"`
+
+	// RequestRetentionDays is the number of days the results of a user
+	// data request are kept for. Results are kept forever if it's 0.
+	RequestRetentionDays int `json:"requestRetentionDays"`
+
+	// ScannerRulePacks are the ids of the optional rule packs (e.g. eu, uk)
+	// the PII scanner runs for the workspace.
+	ScannerRulePacks []string `json:"scannerRulePacks"`
+
+	// DiscoveryScoreThreshold is the minimum score (from 0 to 1) a
+	// category needs for a discovery to be created. The default threshold
+	// is used if it's nil.
+	DiscoveryScoreThreshold *float64 `json:"discoveryScoreThreshold,omitempty"`
+
+	// FailUnmappedDataSources fails the request statuses of data sources
+	// with personal data that isn't linked to a user primary key, instead
+	// of marking them as executed.
+	FailUnmappedDataSources bool `json:"failUnmappedDataSources"`
+
+	// AllowUnmaskedPreview allows previews of data sources to show the
+	// values of sensitive properties. It isn't tied to a role, anyone who
+	// can update the workspace's settings can turn it on.
+	AllowUnmaskedPreview bool `json:"allowUnmaskedPreview"`
+}
+
+// DefaultDiscoveryScoreThreshold is the score threshold of workspaces that
+// haven't set one.
+const DefaultDiscoveryScoreThreshold = 0.2
+
+// ScoreThreshold returns the workspace's discovery score threshold.
+func (s WorkspaceSettings) ScoreThreshold() float64 {
+	if s.DiscoveryScoreThreshold == nil {
+		return DefaultDiscoveryScoreThreshold
+	}
+
+	return *s.DiscoveryScoreThreshold
 }
 
 func ValidateEmail(email string) bool {
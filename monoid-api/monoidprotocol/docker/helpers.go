@@ -99,6 +99,7 @@ func readRecords(stream chan monoidprotocol.MonoidMessage) chan monoidprotocol.M
 		for s := range stream {
 			if s.Type != monoidprotocol.MonoidMessageTypeRECORD || s.Record == nil {
 				log.Debug().Msgf("Message type is not record: %s", string(s.Type))
+				continue
 			}
 
 			recordChan <- *s.Record
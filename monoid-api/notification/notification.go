@@ -0,0 +1,219 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/monoid-privacy/monoid/model"
+)
+
+// EventDiscoveriesFound is the event sent when a scan finds new
+// discoveries.
+const EventDiscoveriesFound = "discoveries.found"
+
+// EventTest is the event sent to test a channel.
+const EventTest = "test"
+
+// DiscoveryTypeCount is the number of new discoveries of a type.
+type DiscoveryTypeCount struct {
+	Type  model.DiscoveryType `json:"type"`
+	Count int                 `json:"count"`
+}
+
+// CategoryCount is the number of new discoveries that found a category.
+type CategoryCount struct {
+	CategoryID string `json:"categoryId"`
+	Count      int    `json:"count"`
+}
+
+// DiscoveryEvent is sent to a workspace's notification channels when a
+// scan of a silo finds new discoveries.
+type DiscoveryEvent struct {
+	Event            string               `json:"event"`
+	WorkspaceID      string               `json:"workspaceId"`
+	SiloDefinitionID string               `json:"siloDefinitionId"`
+	SiloName         string               `json:"siloName"`
+	Total            int                  `json:"total"`
+	Types            []DiscoveryTypeCount `json:"types"`
+	Categories       []CategoryCount      `json:"categories"`
+	CreatedAt        time.Time            `json:"createdAt"`
+}
+
+// NewDiscoveryEvent counts the discoveries by type and by category. New
+// data sources and properties count once for each category their
+// properties have. Returns nil if there are no discoveries.
+func NewDiscoveryEvent(
+	silo *model.SiloDefinition,
+	discoveries []*model.DataDiscovery,
+	now time.Time,
+) *DiscoveryEvent {
+	if len(discoveries) == 0 {
+		return nil
+	}
+
+	types := map[model.DiscoveryType]int{}
+	categories := map[string]int{}
+
+	for _, d := range discoveries {
+		types[d.Type]++
+
+		data, err := d.DeserializeData()
+		if err != nil {
+			continue
+		}
+
+		found := map[string]bool{}
+
+		switch v := data.(type) {
+		case model.NewCategoryDiscovery:
+			found[v.CategoryID] = true
+		case model.NewPropertyDiscovery:
+			for _, c := range v.Categories {
+				found[c.CategoryID] = true
+			}
+		case model.NewDataSourceDiscovery:
+			for _, p := range v.Properties {
+				for _, c := range p.Categories {
+					found[c.CategoryID] = true
+				}
+			}
+		}
+
+		for c := range found {
+			categories[c]++
+		}
+	}
+
+	res := &DiscoveryEvent{
+		Event:            EventDiscoveriesFound,
+		WorkspaceID:      silo.WorkspaceID,
+		SiloDefinitionID: silo.ID,
+		SiloName:         silo.Name,
+		Total:            len(discoveries),
+		Types:            make([]DiscoveryTypeCount, 0, len(types)),
+		Categories:       make([]CategoryCount, 0, len(categories)),
+		CreatedAt:        now,
+	}
+
+	for t, n := range types {
+		res.Types = append(res.Types, DiscoveryTypeCount{Type: t, Count: n})
+	}
+
+	sort.Slice(res.Types, func(i, j int) bool {
+		return res.Types[i].Type < res.Types[j].Type
+	})
+
+	for c, n := range categories {
+		res.Categories = append(res.Categories, CategoryCount{CategoryID: c, Count: n})
+	}
+
+	sort.Slice(res.Categories, func(i, j int) bool {
+		return res.Categories[i].CategoryID < res.Categories[j].CategoryID
+	})
+
+	return res
+}
+
+// slackMessage is the payload of a Slack-compatible incoming webhook.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// SlackText formats the event as a Slack message.
+func SlackText(e *DiscoveryEvent) string {
+	if e.Event == EventTest {
+		return "Test notification from Monoid."
+	}
+
+	plural := "discoveries"
+	if e.Total == 1 {
+		plural = "discovery"
+	}
+
+	lines := []string{
+		fmt.Sprintf("*%d new %s* in silo *%s*", e.Total, plural, e.SiloName),
+	}
+
+	for _, t := range e.Types {
+		lines = append(lines, fmt.Sprintf("• %s: %d", t.Type, t.Count))
+	}
+
+	if len(e.Categories) != 0 {
+		cats := make([]string, 0, len(e.Categories))
+		for _, c := range e.Categories {
+			cats = append(cats, fmt.Sprintf("%s (%d)", c.CategoryID, c.Count))
+		}
+
+		lines = append(lines, "Categories: "+strings.Join(cats, ", "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Payload returns the body sent to a channel of the given type.
+func Payload(channelType model.NotificationChannelType, e *DiscoveryEvent) ([]byte, error) {
+	switch channelType {
+	case model.NotificationChannelTypeWebhook:
+		return json.Marshal(e)
+	case model.NotificationChannelTypeSLACk:
+		return json.Marshal(slackMessage{Text: SlackText(e)})
+	}
+
+	return nil, fmt.Errorf("unknown channel type %s", channelType)
+}
+
+// ValidateURL checks that a channel's URL is an absolute http(s) URL.
+func ValidateURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("webhook url must be an http or https url")
+	}
+
+	return nil
+}
+
+// Send posts the event to a channel. Responses that aren't 2xx are
+// returned as errors, so the delivery can be retried.
+func Send(ctx context.Context, client *http.Client, channel *model.NotificationChannel, e *DiscoveryEvent) error {
+	body, err := Payload(channel.Type, e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, string(channel.URL), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Monoid-Webhook")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	// Drain the body (up to a limit) so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("channel %s responded with status %d", channel.ID, res.StatusCode)
+	}
+
+	return nil
+}
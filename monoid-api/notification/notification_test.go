@@ -0,0 +1,126 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func discovery(t *testing.T, discoveryType model.DiscoveryType, data interface{}) *model.DataDiscovery {
+	d, err := json.Marshal(data)
+	assert.NoError(t, err)
+
+	return &model.DataDiscovery{Type: discoveryType, Data: d}
+}
+
+func testEvent(t *testing.T) *DiscoveryEvent {
+	silo := &model.SiloDefinition{ID: "silo", WorkspaceID: "ws", Name: "Postgres"}
+	propertyID := "p1"
+
+	return NewDiscoveryEvent(silo, []*model.DataDiscovery{
+		discovery(t, model.DiscoveryTypeCategoryFound, model.NewCategoryDiscovery{
+			PropertyID: &propertyID,
+			CategoryID: "email",
+		}),
+		discovery(t, model.DiscoveryTypePropertyFound, model.NewPropertyDiscovery{
+			Name: "phone",
+			Categories: []model.NewCategoryDiscovery{
+				{CategoryID: "phone"},
+			},
+		}),
+		discovery(t, model.DiscoveryTypeDataSourceFound, model.NewDataSourceDiscovery{
+			Name: "users",
+			Properties: []model.NewPropertyDiscovery{
+				{Name: "email", Categories: []model.NewCategoryDiscovery{{CategoryID: "email"}}},
+				{Name: "email2", Categories: []model.NewCategoryDiscovery{{CategoryID: "email"}}},
+			},
+		}),
+	}, time.Unix(0, 0))
+}
+
+func TestNewDiscoveryEvent(t *testing.T) {
+	e := testEvent(t)
+
+	assert.Equal(t, EventDiscoveriesFound, e.Event)
+	assert.Equal(t, "ws", e.WorkspaceID)
+	assert.Equal(t, 3, e.Total)
+	assert.ElementsMatch(t, []DiscoveryTypeCount{
+		{Type: model.DiscoveryTypeCategoryFound, Count: 1},
+		{Type: model.DiscoveryTypePropertyFound, Count: 1},
+		{Type: model.DiscoveryTypeDataSourceFound, Count: 1},
+	}, e.Types)
+
+	// The new data source counts once for email, even though two of its
+	// properties have it.
+	assert.Equal(t, []CategoryCount{
+		{CategoryID: "email", Count: 2},
+		{CategoryID: "phone", Count: 1},
+	}, e.Categories)
+
+	assert.Nil(t, NewDiscoveryEvent(&model.SiloDefinition{}, nil, time.Now()))
+}
+
+func TestSend(t *testing.T) {
+	var body []byte
+	var contentType string
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer sink.Close()
+
+	e := testEvent(t)
+
+	err := Send(context.Background(), sink.Client(), &model.NotificationChannel{
+		Type: model.NotificationChannelTypeWebhook,
+		URL:  model.SecretString(sink.URL),
+	}, e)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+
+	received := DiscoveryEvent{}
+	assert.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, e.Categories, received.Categories)
+	assert.Equal(t, e.Total, received.Total)
+
+	err = Send(context.Background(), sink.Client(), &model.NotificationChannel{
+		Type: model.NotificationChannelTypeSLACk,
+		URL:  model.SecretString(sink.URL),
+	}, e)
+	assert.NoError(t, err)
+
+	msg := map[string]string{}
+	assert.NoError(t, json.Unmarshal(body, &msg))
+	assert.Equal(t, "*3 new discoveries* in silo *Postgres*\n"+
+		"• CATEGORY_FOUND: 1\n"+
+		"• DATA_SOURCE_FOUND: 1\n"+
+		"• PROPERTY_FOUND: 1\n"+
+		"Categories: email (2), phone (1)", msg["text"])
+}
+
+func TestSendError(t *testing.T) {
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer sink.Close()
+
+	err := Send(context.Background(), sink.Client(), &model.NotificationChannel{
+		Type: model.NotificationChannelTypeWebhook,
+		URL:  model.SecretString(sink.URL),
+	}, testEvent(t))
+	assert.Error(t, err)
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://hooks.slack.com/services/T/B/X"))
+	assert.Error(t, ValidateURL("ftp://example.com"))
+	assert.Error(t, ValidateURL("/relative"))
+}
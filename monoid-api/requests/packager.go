@@ -73,7 +73,7 @@ func generateSiloFile(
 				continue
 			}
 
-			f, err := os.Open(data.FilePath)
+			f, err := conf.FileStore.NewReader(context.Background(), data.FilePath, false)
 			if err != nil {
 				log.Err(err).Msg("Error opening file")
 				continue
@@ -7,10 +7,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/monoid-privacy/monoid/generated"
 	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/scanner/basicscanner"
 )
 
 // CreateWorkspace is the resolver for the createWorkspace field.
@@ -111,6 +114,60 @@ func (r *mutationResolver) UpdateWorkspaceSettings(ctx context.Context, input mo
 				settings.SendNews = false
 			}
 		}
+
+		if s.Key == "requestRetentionDays" {
+			days, err := strconv.Atoi(s.Value)
+			if err != nil || days < 0 {
+				return nil, handleError(
+					fmt.Errorf("invalid retention period %s", s.Value),
+					"Retention period must be a non-negative number of days.",
+				)
+			}
+
+			settings.RequestRetentionDays = days
+		}
+
+		if s.Key == "scannerRulePacks" {
+			packs := []string{}
+
+			for _, id := range strings.Split(s.Value, ",") {
+				id = strings.TrimSpace(id)
+				if id == "" {
+					continue
+				}
+
+				if _, ok := basicscanner.LookupRulePack(id); !ok {
+					return nil, handleError(
+						fmt.Errorf("unknown rule pack %s", id),
+						fmt.Sprintf("Unknown scanner rule pack %s.", id),
+					)
+				}
+
+				packs = append(packs, id)
+			}
+
+			settings.ScannerRulePacks = packs
+		}
+
+		if s.Key == "discoveryScoreThreshold" {
+			threshold, err := strconv.ParseFloat(s.Value, 64)
+			if err != nil || threshold < 0 || threshold > 1 {
+				return nil, handleError(
+					fmt.Errorf("invalid score threshold %s", s.Value),
+					"Discovery score threshold must be between 0 and 1.",
+				)
+			}
+
+			settings.DiscoveryScoreThreshold = &threshold
+		}
+
+		if s.Key == "failUnmappedDataSources" {
+			settings.FailUnmappedDataSources = s.Value == "t"
+		}
+
+		if s.Key == "allowUnmaskedPreview" {
+			settings.AllowUnmaskedPreview = s.Value == "t"
+		}
 	}
 
 	if valid := model.ValidateEmail(settings.Email); !valid {
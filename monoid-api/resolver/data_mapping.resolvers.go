@@ -5,21 +5,39 @@ package resolver
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/monoid-privacy/monoid/dataloader"
 	"github.com/monoid-privacy/monoid/generated"
 	"github.com/monoid-privacy/monoid/model"
 	"github.com/monoid-privacy/monoid/workflow"
+	"github.com/monoid-privacy/monoid/workflow/activity"
 	"github.com/rs/zerolog/log"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 	"go.temporal.io/sdk/client"
 	"gorm.io/gorm"
 )
 
+// DataSources is the resolver for the dataSources field.
+func (r *dataMapSnapshotResolver) DataSources(ctx context.Context, obj *model.DataMapSnapshot) ([]*model.DataSourceSnapshot, error) {
+	dataSources, err := obj.DataSources()
+	if err != nil {
+		return nil, handleError(err, "Error reading data map version.")
+	}
+
+	res := make([]*model.DataSourceSnapshot, len(dataSources))
+	for i := range dataSources {
+		res[i] = &dataSources[i]
+	}
+
+	return res, nil
+}
+
 // SiloDefinition is the resolver for the siloDefinition field.
 func (r *dataSourceResolver) SiloDefinition(ctx context.Context, obj *model.DataSource) (*model.SiloDefinition, error) {
 	return dataloader.SiloDefinition(ctx, obj.SiloDefinitionID)
@@ -71,7 +89,7 @@ func (r *mutationResolver) CreateDataSource(ctx context.Context, input model.Cre
 			return err
 		}
 
-		return nil
+		return snapshotDataMap(tx, dataSource.SiloDefinitionID)
 	}); err != nil {
 		return nil, handleError(err, "Error creating data source.")
 	}
@@ -105,18 +123,24 @@ func (r *mutationResolver) CreateProperty(ctx context.Context, input *model.Crea
 		Name:         input.Property.Name,
 	}
 
-	if err := r.Conf.DB.Create(&property).Error; err != nil {
-		return nil, handleError(err, "Error creating property.")
-	}
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&property).Error; err != nil {
+			return err
+		}
 
-	categories := []model.Category{}
+		categories := []model.Category{}
 
-	if err := r.Conf.DB.Where("id IN ?", input.Property.CategoryIDs).Find(&categories).Error; err != nil {
-		return nil, handleError(err, "Error finding categories.")
-	}
+		if err := tx.Where("id IN ?", input.Property.CategoryIDs).Find(&categories).Error; err != nil {
+			return err
+		}
 
-	if err := r.Conf.DB.Model(&property).Association("Categories").Append(categories); err != nil {
-		return nil, handleError(err, "Error creating categories.")
+		if err := tx.Model(&property).Association("Categories").Append(categories); err != nil {
+			return err
+		}
+
+		return snapshotDataSourceDataMap(tx, property.DataSourceID)
+	}); err != nil {
+		return nil, handleError(err, "Error creating property.")
 	}
 
 	return &property, nil
@@ -137,7 +161,13 @@ func (r *mutationResolver) UpdateDataSource(ctx context.Context, input *model.Up
 
 	dataSource.Description = input.Description
 
-	if err := r.Conf.DB.Save(&dataSource).Error; err != nil {
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&dataSource).Error; err != nil {
+			return err
+		}
+
+		return snapshotDataMap(tx, dataSource.SiloDefinitionID)
+	}); err != nil {
 		return nil, handleError(err, "Error updating data source.")
 	}
 
@@ -179,20 +209,26 @@ func (r *mutationResolver) UpdateProperty(ctx context.Context, input *model.Upda
 		return nil, handleError(err, "Error finding property.")
 	}
 
-	// Updating categories
-	if input.CategoryIDs != nil {
-		categories := []model.Category{}
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		// Updating categories
+		if input.CategoryIDs != nil {
+			categories := []model.Category{}
+
+			if err := tx.Where("id IN ?", input.CategoryIDs).Find(&categories).Error; err != nil {
+				return err
+			}
 
-		if err := r.Conf.DB.Where("id IN ?", input.CategoryIDs).Find(&categories).Error; err != nil {
-			return nil, handleError(err, "Error updating property.")
+			if err := tx.Model(&property).Association("Categories").Replace(&categories); err != nil {
+				return err
+			}
 		}
 
-		if err := r.Conf.DB.Model(&property).Association("Categories").Replace(&categories); err != nil {
-			return nil, handleError(err, "Error updating property.")
+		if err := tx.Omit("Categories", "Purposes").Save(&property).Error; err != nil {
+			return err
 		}
-	}
 
-	if err := r.Conf.DB.Omit("Categories", "Purposes").Save(&property).Error; err != nil {
+		return snapshotDataSourceDataMap(tx, property.DataSourceID)
+	}); err != nil {
 		return nil, handleError(err, "Error updating property.")
 	}
 
@@ -206,7 +242,13 @@ func (r *mutationResolver) UpdateSubject(ctx context.Context, input *model.Updat
 
 // DeleteDataSource is the resolver for the deleteDataSource field.
 func (r *mutationResolver) DeleteDataSource(ctx context.Context, id string) (*string, error) {
-	if err := model.DeleteDataSource(id, r.Conf.DB); err != nil {
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := model.DeleteDataSource(id, tx); err != nil {
+			return err
+		}
+
+		return snapshotDataSourceDataMap(tx, id)
+	}); err != nil {
 		return nil, handleError(err, "Error deleting data source.")
 	}
 
@@ -232,7 +274,18 @@ func (r *mutationResolver) DeleteSiloSpecification(ctx context.Context, id strin
 
 // DeleteProperty is the resolver for the deleteProperty field.
 func (r *mutationResolver) DeleteProperty(ctx context.Context, id string) (*string, error) {
-	if err := model.DeleteProperty(id, r.Conf.DB); err != nil {
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		property := model.Property{}
+		if err := tx.Where("id = ?", id).First(&property).Error; err != nil {
+			return err
+		}
+
+		if err := model.DeleteProperty(id, tx); err != nil {
+			return err
+		}
+
+		return snapshotDataSourceDataMap(tx, property.DataSourceID)
+	}); err != nil {
 		return nil, handleError(err, "Error deleting property.")
 	}
 
@@ -310,6 +363,74 @@ func (r *mutationResolver) DetectSiloSources(ctx context.Context, workspaceID st
 	return &job, nil
 }
 
+// DetectAllSiloSources is the resolver for the detectAllSiloSources field.
+func (r *mutationResolver) DetectAllSiloSources(ctx context.Context, workspaceID string) ([]*model.Job, error) {
+	silos := []model.SiloDefinition{}
+	if err := r.Conf.DB.Where("workspace_id = ?", workspaceID).Find(&silos).Error; err != nil {
+		return nil, handleError(err, "Error finding silos.")
+	}
+
+	jobs := make([]*model.Job, 0, len(silos))
+	args := workflow.DetectAllDSArgs{
+		WorkspaceID: workspaceID,
+		Silos:       make([]workflow.DetectDSArgs, 0, len(silos)),
+	}
+
+	for _, silo := range silos {
+		job := model.Job{
+			ID:          uuid.NewString(),
+			WorkspaceID: workspaceID,
+			JobType:     model.JobTypeDiscoverSources,
+			Status:      model.JobStatusQueued,
+			ResourceID:  silo.ID,
+		}
+
+		jobs = append(jobs, &job)
+		args.Silos = append(args.Silos, workflow.DetectDSArgs{
+			SiloDefID:   silo.ID,
+			WorkspaceID: workspaceID,
+			JobID:       job.ID,
+		})
+	}
+
+	if len(jobs) == 0 {
+		return jobs, nil
+	}
+
+	r.Conf.AnalyticsIngestor.Track("siloAction", nil, map[string]interface{}{
+		"action":   "detect_all_silos",
+		"numSilos": len(silos),
+	})
+
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&jobs).Error; err != nil {
+			return err
+		}
+
+		options := client.StartWorkflowOptions{
+			ID:        uuid.NewString(),
+			TaskQueue: workflow.DockerRunnerQueue,
+		}
+
+		sf := workflow.Workflow{
+			Conf: r.Conf,
+		}
+
+		_, err := r.Conf.TemporalClient.ExecuteWorkflow(
+			context.Background(),
+			options,
+			sf.DetectAllDSWorkflow,
+			args,
+		)
+
+		return err
+	}); err != nil {
+		return nil, handleError(err, "Error running jobs.")
+	}
+
+	return jobs, nil
+}
+
 // Categories is the resolver for the categories field.
 func (r *propertyResolver) Categories(ctx context.Context, obj *model.Property) ([]*model.Category, error) {
 	return dataloader.PropertyCategories(ctx, obj.ID)
@@ -354,6 +475,146 @@ func (r *queryResolver) Property(ctx context.Context, id string) (*model.Propert
 	return findObjectByID[model.Property](id, r.Conf.DB, "Error finding property.")
 }
 
+// DataSourcePreview is the resolver for the dataSourcePreview field.
+func (r *queryResolver) DataSourcePreview(ctx context.Context, id *string, discoveryID *string, limit *int, unmask *bool) (*model.DataSourcePreview, error) {
+	args := activity.PreviewDataSourceArgs{}
+	silo := model.SiloDefinition{}
+
+	switch {
+	case id != nil && discoveryID == nil:
+		dataSource := model.DataSource{}
+		if err := r.Conf.DB.Preload("SiloDefinition.Workspace").Where("id = ?", *id).First(&dataSource).Error; err != nil {
+			return nil, handleError(err, "Could not find data source.")
+		}
+
+		args.DataSourceID = dataSource.ID
+		silo = dataSource.SiloDefinition
+	case discoveryID != nil && id == nil:
+		discovery := model.DataDiscovery{}
+		if err := r.Conf.DB.Preload("SiloDefinition.Workspace").Where(
+			"id = ?", *discoveryID,
+		).First(&discovery).Error; err != nil {
+			return nil, handleError(err, "Could not find discovery.")
+		}
+
+		if discovery.Type != model.DiscoveryTypeDataSourceFound {
+			return nil, handleError(
+				fmt.Errorf("discovery %s is not a new data source", discovery.ID),
+				"Only discoveries of new data sources can be previewed.",
+			)
+		}
+
+		args.DiscoveryID = discovery.ID
+		silo = discovery.SiloDefinition
+	default:
+		return nil, handleError(
+			fmt.Errorf("exactly one of id and discoveryId must be set"),
+			"Exactly one of id and discoveryId must be set.",
+		)
+	}
+
+	if limit != nil {
+		if *limit <= 0 || *limit > activity.MaxPreviewRecords {
+			return nil, handleError(
+				fmt.Errorf("invalid preview limit %d", *limit),
+				fmt.Sprintf("Limit must be between 1 and %d.", activity.MaxPreviewRecords),
+			)
+		}
+
+		args.Limit = *limit
+	}
+
+	if unmask != nil && *unmask {
+		settings := model.WorkspaceSettings{}
+		if len(silo.Workspace.Settings) != 0 {
+			if err := json.Unmarshal(silo.Workspace.Settings, &settings); err != nil {
+				return nil, handleError(err, "Error getting workspace settings.")
+			}
+		}
+
+		if !settings.AllowUnmaskedPreview {
+			return nil, handleError(
+				fmt.Errorf("unmasked previews are not allowed"),
+				"Unmasked previews are not allowed in this workspace.",
+			)
+		}
+
+		args.Unmask = true
+	}
+
+	r.Conf.AnalyticsIngestor.Track("dataSourcePreview", nil, map[string]interface{}{
+		"siloId":   silo.ID,
+		"unmasked": args.Unmask,
+	})
+
+	res, err := r.previewDataSource(ctx, args)
+	if err != nil {
+		return nil, handleError(err, "Error previewing data source.")
+	}
+
+	return res, nil
+}
+
+// DataMapVersions is the resolver for the dataMapVersions field.
+func (r *siloDefinitionResolver) DataMapVersions(ctx context.Context, obj *model.SiloDefinition, limit int, offset int) ([]*model.DataMapSnapshot, error) {
+	snapshots := []*model.DataMapSnapshot{}
+	if err := r.Conf.DB.Where("silo_definition_id = ?", obj.ID).Order(
+		"version desc",
+	).Limit(limit).Offset(offset).Find(&snapshots).Error; err != nil {
+		return nil, handleError(err, "Error finding data map versions.")
+	}
+
+	return snapshots, nil
+}
+
+// DataMapAt is the resolver for the dataMapAt field.
+func (r *siloDefinitionResolver) DataMapAt(ctx context.Context, obj *model.SiloDefinition, time time.Time) (*model.DataMapSnapshot, error) {
+	snapshot, err := model.DataMapSnapshotAt(obj.ID, time, r.Conf.DB)
+	if err != nil {
+		return nil, handleError(err, "Error finding data map version.")
+	}
+
+	return snapshot, nil
+}
+
+// DataMapDiff is the resolver for the dataMapDiff field.
+func (r *siloDefinitionResolver) DataMapDiff(ctx context.Context, obj *model.SiloDefinition, fromVersion int, toVersion int) ([]*model.DataMapChange, error) {
+	snapshots := []model.DataMapSnapshot{}
+	if err := r.Conf.DB.Where("silo_definition_id = ?", obj.ID).Where(
+		"version IN ?", []int{fromVersion, toVersion},
+	).Find(&snapshots).Error; err != nil {
+		return nil, handleError(err, "Error finding data map versions.")
+	}
+
+	versions := map[int][]model.DataSourceSnapshot{}
+	for i := range snapshots {
+		dataSources, err := snapshots[i].DataSources()
+		if err != nil {
+			return nil, handleError(err, "Error reading data map version.")
+		}
+
+		versions[snapshots[i].Version] = dataSources
+	}
+
+	from, ok := versions[fromVersion]
+	if !ok {
+		return nil, handleError(fmt.Errorf("version %d not found", fromVersion), "Could not find data map version.")
+	}
+
+	to, ok := versions[toVersion]
+	if !ok {
+		return nil, handleError(fmt.Errorf("version %d not found", toVersion), "Could not find data map version.")
+	}
+
+	changes := model.DiffDataMaps(from, to)
+	res := make([]*model.DataMapChange, len(changes))
+	for i := range changes {
+		res[i] = &changes[i]
+	}
+
+	return res, nil
+}
+
 // Logo is the resolver for the logo field.
 func (r *siloSpecificationResolver) Logo(ctx context.Context, obj *model.SiloSpecification) (*string, error) {
 	if obj.LogoURL == nil {
@@ -440,6 +701,11 @@ func (r *workspaceResolver) DataMap(ctx context.Context, obj *model.Workspace, q
 	}, nil
 }
 
+// DataMapSnapshot returns generated.DataMapSnapshotResolver implementation.
+func (r *Resolver) DataMapSnapshot() generated.DataMapSnapshotResolver {
+	return &dataMapSnapshotResolver{r}
+}
+
 // DataSource returns generated.DataSourceResolver implementation.
 func (r *Resolver) DataSource() generated.DataSourceResolver { return &dataSourceResolver{r} }
 
@@ -451,6 +717,7 @@ func (r *Resolver) SiloSpecification() generated.SiloSpecificationResolver {
 	return &siloSpecificationResolver{r}
 }
 
+type dataMapSnapshotResolver struct{ *Resolver }
 type dataSourceResolver struct{ *Resolver }
 type propertyResolver struct{ *Resolver }
 type siloSpecificationResolver struct{ *Resolver }
@@ -0,0 +1,21 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDataSourcePreviewArgs checks that exactly one of the data source and
+// discovery ids is given.
+func TestDataSourcePreviewArgs(t *testing.T) {
+	r := &queryResolver{&Resolver{Conf: &config.BaseConfig{}}}
+
+	_, err := r.DataSourcePreview(context.Background(), nil, nil, nil, nil)
+	assert.Error(t, err)
+
+	_, err = r.DataSourcePreview(context.Background(), str("ds"), str("discovery"), nil, nil)
+	assert.Error(t, err)
+}
@@ -5,11 +5,15 @@ package resolver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/monoid-privacy/monoid/dataloader"
+	"github.com/monoid-privacy/monoid/discovery"
 	"github.com/monoid-privacy/monoid/generated"
 	"github.com/monoid-privacy/monoid/model"
 	"gorm.io/gorm"
@@ -30,6 +34,34 @@ func (r *dataDiscoveryResolver) Data(ctx context.Context, obj *model.DataDiscove
 	return data, nil
 }
 
+// Decision is the resolver for the decision field.
+func (r *dataDiscoveryResolver) Decision(ctx context.Context, obj *model.DataDiscovery) (*model.DiscoveryDecision, error) {
+	decision := model.DiscoveryDecision{}
+	if err := r.Conf.DB.Where("data_discovery_id = ?", obj.ID).Order(
+		"created_at desc",
+	).First(&decision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, handleError(err, "Error finding decision.")
+	}
+
+	return &decision, nil
+}
+
+// Decisions is the resolver for the decisions field.
+func (r *dataDiscoveryResolver) Decisions(ctx context.Context, obj *model.DataDiscovery) ([]*model.DiscoveryDecision, error) {
+	decisions := []*model.DiscoveryDecision{}
+	if err := r.Conf.DB.Where("data_discovery_id = ?", obj.ID).Order(
+		"created_at desc",
+	).Find(&decisions).Error; err != nil {
+		return nil, handleError(err, "Error finding decisions.")
+	}
+
+	return decisions, nil
+}
+
 // DataSource is the resolver for the dataSource field.
 func (r *dataSourceMissingDiscoveryResolver) DataSource(ctx context.Context, obj *model.DataSourceMissingDiscovery) (*model.DataSource, error) {
 	dataSource := model.DataSource{}
@@ -44,14 +76,43 @@ func (r *dataSourceMissingDiscoveryResolver) DataSource(ctx context.Context, obj
 	return &dataSource, nil
 }
 
+// DiscoveryTypes is the resolver for the discoveryTypes field.
+func (r *discoveryPolicyResolver) DiscoveryTypes(ctx context.Context, obj *model.DiscoveryPolicy) ([]model.DiscoveryType, error) {
+	res, err := obj.DiscoveryTypeList()
+	if err != nil {
+		return nil, handleError(err, "Error getting discovery types.")
+	}
+
+	return res, nil
+}
+
+// SiloDefinition is the resolver for the siloDefinition field.
+func (r *discoveryPolicyResolver) SiloDefinition(ctx context.Context, obj *model.DiscoveryPolicy) (*model.SiloDefinition, error) {
+	if obj.SiloDefinitionID == nil {
+		return nil, nil
+	}
+
+	return dataloader.SiloDefinition(ctx, *obj.SiloDefinitionID)
+}
+
+// CategoryIds is the resolver for the categoryIds field.
+func (r *discoveryPolicyResolver) CategoryIds(ctx context.Context, obj *model.DiscoveryPolicy) ([]string, error) {
+	res, err := obj.CategoryIDList()
+	if err != nil {
+		return nil, handleError(err, "Error getting categories.")
+	}
+
+	return res, nil
+}
+
 // HandleDiscovery is the resolver for the handleDiscovery field.
 func (r *mutationResolver) HandleDiscovery(ctx context.Context, input *model.HandleDiscoveryInput) (*model.DataDiscovery, error) {
-	discovery := model.DataDiscovery{}
-	if err := r.Conf.DB.Where("id = ?", input.DiscoveryID).First(&discovery).Error; err != nil {
+	dataDiscovery := model.DataDiscovery{}
+	if err := r.Conf.DB.Where("id = ?", input.DiscoveryID).First(&dataDiscovery).Error; err != nil {
 		return nil, handleError(err, "Could not find discovery.")
 	}
 
-	res, errs := applyDiscoveries(r.Conf, []*model.DataDiscovery{&discovery}, input.Action)
+	res, errs := discovery.Apply(r.Conf, []*model.DataDiscovery{&dataDiscovery}, input.Action, nil)
 	if len(errs) != 0 {
 		return nil, handleError(errs[0], "Error applying discovery.")
 	}
@@ -72,7 +133,7 @@ func (r *mutationResolver) HandleAllOpenDiscoveries(ctx context.Context, input *
 		return nil, handleError(err, "Error finding discoveries.")
 	}
 
-	res, errs := applyDiscoveries(r.Conf, discoveries, input.Action)
+	res, errs := discovery.Apply(r.Conf, discoveries, input.Action, nil)
 	if len(errs) != 0 {
 		return nil, handleError(errs[0], fmt.Sprintf("Errors applying %d discoveries.", len(errs)))
 	}
@@ -80,6 +141,92 @@ func (r *mutationResolver) HandleAllOpenDiscoveries(ctx context.Context, input *
 	return res, nil
 }
 
+// RevertDiscovery is the resolver for the revertDiscovery field.
+func (r *mutationResolver) RevertDiscovery(ctx context.Context, id string) (*model.DataDiscovery, error) {
+	dataDiscovery := model.DataDiscovery{}
+	if err := r.Conf.DB.Where("id = ?", id).First(&dataDiscovery).Error; err != nil {
+		return nil, handleError(err, "Could not find discovery.")
+	}
+
+	res, err := discovery.Revert(r.Conf, &dataDiscovery)
+	if err != nil {
+		switch {
+		case errors.Is(err, discovery.ErrNotDecided):
+			return nil, handleError(err, "Discovery has not been accepted or rejected.")
+		case errors.Is(err, discovery.ErrNoEffect):
+			return nil, handleError(err, "Discovery was accepted before reverts were supported, and cannot be reverted.")
+		}
+
+		return nil, handleError(err, "Error reverting discovery.")
+	}
+
+	return res, nil
+}
+
+// CreateDiscoveryPolicy is the resolver for the createDiscoveryPolicy field.
+func (r *mutationResolver) CreateDiscoveryPolicy(ctx context.Context, input model.CreateDiscoveryPolicyInput) (*model.DiscoveryPolicy, error) {
+	if input.DataSourcePattern != nil {
+		if _, err := regexp.Compile(*input.DataSourcePattern); err != nil {
+			return nil, handleError(err, "Invalid data source pattern.")
+		}
+	}
+
+	if input.MinScore != nil && (*input.MinScore < 0 || *input.MinScore > 1) {
+		return nil, handleError(fmt.Errorf("invalid min score %f", *input.MinScore), "Minimum score must be between 0 and 1.")
+	}
+
+	if input.SiloDefinitionID != nil {
+		silo := model.SiloDefinition{}
+		if err := r.Conf.DB.Where("id = ?", *input.SiloDefinitionID).Where(
+			"workspace_id = ?", input.WorkspaceID,
+		).First(&silo).Error; err != nil {
+			return nil, handleError(err, "Could not find silo.")
+		}
+	}
+
+	discoveryTypes, err := json.Marshal(input.DiscoveryTypes)
+	if err != nil {
+		return nil, handleError(err, "Error creating policy.")
+	}
+
+	categoryIDs, err := json.Marshal(input.CategoryIds)
+	if err != nil {
+		return nil, handleError(err, "Error creating policy.")
+	}
+
+	policy := model.DiscoveryPolicy{
+		ID:                uuid.NewString(),
+		WorkspaceID:       input.WorkspaceID,
+		Name:              input.Name,
+		Action:            input.Action,
+		DiscoveryTypes:    discoveryTypes,
+		SiloDefinitionID:  input.SiloDefinitionID,
+		CategoryIDs:       categoryIDs,
+		DataSourcePattern: input.DataSourcePattern,
+		MinScore:          input.MinScore,
+	}
+
+	if err := r.Conf.DB.Create(&policy).Error; err != nil {
+		return nil, handleError(err, "Error creating policy.")
+	}
+
+	return &policy, nil
+}
+
+// DeleteDiscoveryPolicy is the resolver for the deleteDiscoveryPolicy field.
+func (r *mutationResolver) DeleteDiscoveryPolicy(ctx context.Context, id string) (string, error) {
+	policy := model.DiscoveryPolicy{}
+	if err := r.Conf.DB.Where("id = ?", id).First(&policy).Error; err != nil {
+		return "", handleError(err, "Could not find policy.")
+	}
+
+	if err := r.Conf.DB.Delete(&policy).Error; err != nil {
+		return "", handleError(err, "Error deleting policy.")
+	}
+
+	return id, nil
+}
+
 // Category is the resolver for the category field.
 func (r *newCategoryDiscoveryResolver) Category(ctx context.Context, obj *model.NewCategoryDiscovery) (*model.Category, error) {
 	category := model.Category{}
@@ -126,6 +273,48 @@ func (r *newPropertyDiscoveryResolver) DataSource(ctx context.Context, obj *mode
 	return &dataSource, nil
 }
 
+// Property is the resolver for the property field.
+func (r *primaryKeySuggestedDiscoveryResolver) Property(ctx context.Context, obj *model.PrimaryKeySuggestedDiscovery) (*model.Property, error) {
+	property := model.Property{}
+	if err := r.Conf.DB.Where("id = ?", obj.PropertyID).First(&property).Error; err != nil {
+		if errors.Is(gorm.ErrRecordNotFound, err) {
+			return nil, nil
+		}
+
+		return nil, handleError(err, "Error finding property")
+	}
+
+	return &property, nil
+}
+
+// UserPrimaryKey is the resolver for the userPrimaryKey field.
+func (r *primaryKeySuggestedDiscoveryResolver) UserPrimaryKey(ctx context.Context, obj *model.PrimaryKeySuggestedDiscovery) (*model.UserPrimaryKey, error) {
+	primaryKey := model.UserPrimaryKey{}
+	if err := r.Conf.DB.Where("id = ?", obj.UserPrimaryKeyID).First(&primaryKey).Error; err != nil {
+		if errors.Is(gorm.ErrRecordNotFound, err) {
+			return nil, nil
+		}
+
+		return nil, handleError(err, "Error finding primary key")
+	}
+
+	return &primaryKey, nil
+}
+
+// Property is the resolver for the property field.
+func (r *propertyChangedDiscoveryResolver) Property(ctx context.Context, obj *model.PropertyChangedDiscovery) (*model.Property, error) {
+	property := model.Property{}
+	if err := r.Conf.DB.Where("id = ?", obj.ID).First(&property).Error; err != nil {
+		if errors.Is(gorm.ErrRecordNotFound, err) {
+			return nil, nil
+		}
+
+		return nil, handleError(err, "Error finding property")
+	}
+
+	return &property, nil
+}
+
 // Property is the resolver for the property field.
 func (r *propertyMissingDiscoveryResolver) Property(ctx context.Context, obj *model.PropertyMissingDiscovery) (*model.Property, error) {
 	property := model.Property{}
@@ -213,6 +402,18 @@ func (r *workspaceResolver) Discoveries(ctx context.Context, obj *model.Workspac
 	}, nil
 }
 
+// DiscoveryPolicies is the resolver for the discoveryPolicies field.
+func (r *workspaceResolver) DiscoveryPolicies(ctx context.Context, obj *model.Workspace) ([]*model.DiscoveryPolicy, error) {
+	policies := []*model.DiscoveryPolicy{}
+	if err := r.Conf.DB.Where("workspace_id = ?", obj.ID).Order(
+		"created_at",
+	).Find(&policies).Error; err != nil {
+		return nil, handleError(err, "Error getting discovery policies.")
+	}
+
+	return policies, nil
+}
+
 // DataDiscovery returns generated.DataDiscoveryResolver implementation.
 func (r *Resolver) DataDiscovery() generated.DataDiscoveryResolver { return &dataDiscoveryResolver{r} }
 
@@ -221,6 +422,11 @@ func (r *Resolver) DataSourceMissingDiscovery() generated.DataSourceMissingDisco
 	return &dataSourceMissingDiscoveryResolver{r}
 }
 
+// DiscoveryPolicy returns generated.DiscoveryPolicyResolver implementation.
+func (r *Resolver) DiscoveryPolicy() generated.DiscoveryPolicyResolver {
+	return &discoveryPolicyResolver{r}
+}
+
 // NewCategoryDiscovery returns generated.NewCategoryDiscoveryResolver implementation.
 func (r *Resolver) NewCategoryDiscovery() generated.NewCategoryDiscoveryResolver {
 	return &newCategoryDiscoveryResolver{r}
@@ -231,6 +437,16 @@ func (r *Resolver) NewPropertyDiscovery() generated.NewPropertyDiscoveryResolver
 	return &newPropertyDiscoveryResolver{r}
 }
 
+// PrimaryKeySuggestedDiscovery returns generated.PrimaryKeySuggestedDiscoveryResolver implementation.
+func (r *Resolver) PrimaryKeySuggestedDiscovery() generated.PrimaryKeySuggestedDiscoveryResolver {
+	return &primaryKeySuggestedDiscoveryResolver{r}
+}
+
+// PropertyChangedDiscovery returns generated.PropertyChangedDiscoveryResolver implementation.
+func (r *Resolver) PropertyChangedDiscovery() generated.PropertyChangedDiscoveryResolver {
+	return &propertyChangedDiscoveryResolver{r}
+}
+
 // PropertyMissingDiscovery returns generated.PropertyMissingDiscoveryResolver implementation.
 func (r *Resolver) PropertyMissingDiscovery() generated.PropertyMissingDiscoveryResolver {
 	return &propertyMissingDiscoveryResolver{r}
@@ -238,6 +454,9 @@ func (r *Resolver) PropertyMissingDiscovery() generated.PropertyMissingDiscovery
 
 type dataDiscoveryResolver struct{ *Resolver }
 type dataSourceMissingDiscoveryResolver struct{ *Resolver }
+type discoveryPolicyResolver struct{ *Resolver }
 type newCategoryDiscoveryResolver struct{ *Resolver }
 type newPropertyDiscoveryResolver struct{ *Resolver }
+type primaryKeySuggestedDiscoveryResolver struct{ *Resolver }
+type propertyChangedDiscoveryResolver struct{ *Resolver }
 type propertyMissingDiscoveryResolver struct{ *Resolver }
@@ -0,0 +1,89 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/notification"
+	"github.com/monoid-privacy/monoid/workflow/activity"
+)
+
+// CreateNotificationChannel is the resolver for the createNotificationChannel field.
+func (r *mutationResolver) CreateNotificationChannel(ctx context.Context, input model.CreateNotificationChannelInput) (*model.NotificationChannel, error) {
+	if err := notification.ValidateURL(input.URL); err != nil {
+		return nil, handleError(err, "Invalid webhook URL.")
+	}
+
+	channel := model.NotificationChannel{
+		ID:          uuid.NewString(),
+		WorkspaceID: input.WorkspaceID,
+		Name:        input.Name,
+		Type:        input.Type,
+		URL:         model.SecretString(input.URL),
+	}
+
+	if err := r.Conf.DB.Create(&channel).Error; err != nil {
+		return nil, handleError(err, "Error creating notification channel.")
+	}
+
+	r.Conf.AnalyticsIngestor.Track("notificationChannelCreate", nil, map[string]interface{}{
+		"workspaceId": input.WorkspaceID,
+		"type":        input.Type,
+	})
+
+	return &channel, nil
+}
+
+// DeleteNotificationChannel is the resolver for the deleteNotificationChannel field.
+func (r *mutationResolver) DeleteNotificationChannel(ctx context.Context, id string) (string, error) {
+	channel := model.NotificationChannel{}
+	if err := r.Conf.DB.Where("id = ?", id).First(&channel).Error; err != nil {
+		return "", handleError(err, "Could not find notification channel.")
+	}
+
+	if err := r.Conf.DB.Delete(&channel).Error; err != nil {
+		return "", handleError(err, "Error deleting notification channel.")
+	}
+
+	return id, nil
+}
+
+// TestNotificationChannel is the resolver for the testNotificationChannel field.
+func (r *mutationResolver) TestNotificationChannel(ctx context.Context, id string) (bool, error) {
+	channel := model.NotificationChannel{}
+	if err := r.Conf.DB.Where("id = ?", id).First(&channel).Error; err != nil {
+		return false, handleError(err, "Could not find notification channel.")
+	}
+
+	client := &http.Client{Timeout: activity.NotificationTimeout}
+
+	if err := notification.Send(ctx, client, &channel, &notification.DiscoveryEvent{
+		Event:       notification.EventTest,
+		WorkspaceID: channel.WorkspaceID,
+		Types:       []notification.DiscoveryTypeCount{},
+		Categories:  []notification.CategoryCount{},
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		return false, handleError(err, "Error sending test notification.")
+	}
+
+	return true, nil
+}
+
+// NotificationChannels is the resolver for the notificationChannels field.
+func (r *workspaceResolver) NotificationChannels(ctx context.Context, obj *model.Workspace) ([]*model.NotificationChannel, error) {
+	channels := []*model.NotificationChannel{}
+	if err := r.Conf.DB.Where("workspace_id = ?", obj.ID).Order(
+		"created_at",
+	).Find(&channels).Error; err != nil {
+		return nil, handleError(err, "Error getting notification channels.")
+	}
+
+	return channels, nil
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/monoid-privacy/monoid/dataloader"
+	"github.com/monoid-privacy/monoid/filestore"
 	"github.com/monoid-privacy/monoid/generated"
 	"github.com/monoid-privacy/monoid/model"
 	"github.com/monoid-privacy/monoid/requests"
@@ -92,7 +93,11 @@ func (r *mutationResolver) UpdateRequestStatus(ctx context.Context, input model.
 		}
 
 		// Create a handle to write to the file store
-		wr, obj, err := r.Conf.FileStore.NewWriter(ctx, uuid.NewString(), false)
+		wr, obj, err := r.Conf.FileStore.NewWriter(
+			filestore.WithWorkspaceID(ctx, status.Request.WorkspaceID),
+			uuid.NewString(),
+			false,
+		)
 		if err != nil {
 			return nil, handleError(err, "Could not upload file.")
 		}
@@ -103,6 +108,11 @@ func (r *mutationResolver) UpdateRequestStatus(ctx context.Context, input model.
 		}
 
 		if _, err := io.Copy(wr, input.ResultData.File); err != nil {
+			wr.Close()
+			return nil, handleError(err, "Error uploading file.")
+		}
+
+		if err := wr.Close(); err != nil {
 			return nil, handleError(err, "Error uploading file.")
 		}
 
@@ -333,13 +343,31 @@ func (r *mutationResolver) GenerateRequestDownloadLink(ctx context.Context, requ
 		)
 	}
 
+	var tombstones int64
+	if err := r.Conf.DB.Model(&model.RequestTombstone{}).Where(
+		"request_id = ?", request.ID,
+	).Count(&tombstones).Error; err != nil {
+		return nil, handleError(err, "Error generating file.")
+	}
+
+	if tombstones > 0 && request.DownloadableFileID == nil {
+		return nil, handleError(
+			fmt.Errorf("request results have been purged"),
+			"The results of this request have expired.",
+		)
+	}
+
 	if request.DownloadableFileID != nil {
 		return &model.DownloadLink{
 			URL: "/downloads/" + *request.DownloadableFileID,
 		}, nil
 	}
 
-	res, err := requests.GenerateRequestTar(ctx, r.Conf, request.ID)
+	res, err := requests.GenerateRequestTar(
+		filestore.WithWorkspaceID(ctx, request.WorkspaceID),
+		r.Conf,
+		request.ID,
+	)
 	if err != nil {
 		return nil, handleError(err, "Error generating file.")
 	}
@@ -609,6 +637,16 @@ func (r *workspaceResolver) UserPrimaryKeys(ctx context.Context, obj *model.Work
 	return findChildObjects[model.UserPrimaryKey](r.Conf.DB, obj.ID, "workspace_id")
 }
 
+// UnmappedPersonalData is the resolver for the unmappedPersonalData field.
+func (r *workspaceResolver) UnmappedPersonalData(ctx context.Context, obj *model.Workspace) ([]*model.UnmappedPersonalData, error) {
+	res, err := model.FindUnmappedPersonalData(obj.ID, r.Conf.DB)
+	if err != nil {
+		return nil, handleError(err, "Error finding unmapped personal data.")
+	}
+
+	return res, nil
+}
+
 // PrimaryKeyValue returns generated.PrimaryKeyValueResolver implementation.
 func (r *Resolver) PrimaryKeyValue() generated.PrimaryKeyValueResolver {
 	return &primaryKeyValueResolver{r}
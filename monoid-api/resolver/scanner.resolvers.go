@@ -0,0 +1,163 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/generated"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/scanner/basicscanner"
+)
+
+// CreateScannerRule is the resolver for the createScannerRule field.
+func (r *mutationResolver) CreateScannerRule(ctx context.Context, input model.CreateScannerRuleInput) (*model.ScannerRule, error) {
+	switch input.Kind {
+	case model.ScannerRuleKindRegex:
+		if input.Pattern == nil || *input.Pattern == "" {
+			return nil, handleError(fmt.Errorf("missing pattern"), "Regex rules must have a pattern.")
+		}
+
+		if len(input.ColumnNames) != 0 {
+			return nil, handleError(fmt.Errorf("unexpected column names"), "Regex rules can't have column names.")
+		}
+
+		if _, err := regexp.Compile(*input.Pattern); err != nil {
+			return nil, handleError(err, "Invalid regex pattern.")
+		}
+	case model.ScannerRuleKindName:
+		if len(input.ColumnNames) == 0 {
+			return nil, handleError(fmt.Errorf("missing column names"), "Name rules must have at least one column name.")
+		}
+
+		if input.Pattern != nil {
+			return nil, handleError(fmt.Errorf("unexpected pattern"), "Name rules can't have a pattern.")
+		}
+	}
+
+	confidence := "medium"
+	if input.Confidence != nil {
+		confidence = *input.Confidence
+	}
+
+	if confidence != "low" && confidence != "medium" && confidence != "high" {
+		return nil, handleError(fmt.Errorf("invalid confidence %s", confidence), "Confidence must be low, medium or high.")
+	}
+
+	category := model.Category{}
+	if err := r.Conf.DB.Where("id = ?", input.CategoryID).Where(
+		r.Conf.DB.Where("workspace_id = ?", input.WorkspaceID).Or("workspace_id IS NULL"),
+	).First(&category).Error; err != nil {
+		return nil, handleError(err, "Could not find category.")
+	}
+
+	columnNames, err := json.Marshal(input.ColumnNames)
+	if err != nil {
+		return nil, handleError(err, "Error creating rule.")
+	}
+
+	rule := model.ScannerRule{
+		ID:          uuid.NewString(),
+		WorkspaceID: &input.WorkspaceID,
+		Name:        input.Name,
+		Kind:        input.Kind,
+		Pattern:     input.Pattern,
+		ColumnNames: columnNames,
+		Confidence:  confidence,
+		CategoryID:  category.ID,
+	}
+
+	if err := r.Conf.DB.Create(&rule).Error; err != nil {
+		return nil, handleError(err, "Error creating rule.")
+	}
+
+	return &rule, nil
+}
+
+// DeleteScannerRule is the resolver for the deleteScannerRule field.
+func (r *mutationResolver) DeleteScannerRule(ctx context.Context, id string) (string, error) {
+	rule := model.ScannerRule{}
+	if err := r.Conf.DB.Where("id = ?", id).First(&rule).Error; err != nil {
+		return "", handleError(err, "Could not find rule.")
+	}
+
+	if rule.WorkspaceID == nil {
+		return "", handleError(fmt.Errorf("rule %s is global", id), "Global rules can only be changed through the loader.")
+	}
+
+	if err := r.Conf.DB.Delete(&rule).Error; err != nil {
+		return "", handleError(err, "Error deleting rule.")
+	}
+
+	return id, nil
+}
+
+// ColumnNames is the resolver for the columnNames field.
+func (r *scannerRuleResolver) ColumnNames(ctx context.Context, obj *model.ScannerRule) ([]string, error) {
+	res, err := obj.ColumnNameList()
+	if err != nil {
+		return nil, handleError(err, "Error getting column names.")
+	}
+
+	return res, nil
+}
+
+// Category is the resolver for the category field.
+func (r *scannerRuleResolver) Category(ctx context.Context, obj *model.ScannerRule) (*model.Category, error) {
+	return findObjectByID[model.Category](obj.CategoryID, r.Conf.DB, "Error finding category.")
+}
+
+// Global is the resolver for the global field.
+func (r *scannerRuleResolver) Global(ctx context.Context, obj *model.ScannerRule) (bool, error) {
+	return obj.WorkspaceID == nil, nil
+}
+
+// ScannerRules is the resolver for the scannerRules field.
+func (r *workspaceResolver) ScannerRules(ctx context.Context, obj *model.Workspace) ([]*model.ScannerRule, error) {
+	rules := []*model.ScannerRule{}
+	if err := r.Conf.DB.Where("workspace_id = ?", obj.ID).Or(
+		"workspace_id IS NULL",
+	).Order("name").Find(&rules).Error; err != nil {
+		return nil, handleError(err, "Error getting scanner rules.")
+	}
+
+	return rules, nil
+}
+
+// ScannerRulePacks is the resolver for the scannerRulePacks field.
+func (r *workspaceResolver) ScannerRulePacks(ctx context.Context, obj *model.Workspace) ([]*model.ScannerRulePack, error) {
+	settings := model.WorkspaceSettings{}
+	if len(obj.Settings) > 0 {
+		if err := json.Unmarshal(obj.Settings, &settings); err != nil {
+			return nil, handleError(err, "Error getting workspace settings.")
+		}
+	}
+
+	enabled := map[string]bool{}
+	for _, id := range settings.ScannerRulePacks {
+		enabled[id] = true
+	}
+
+	packs := []*model.ScannerRulePack{}
+	for _, p := range basicscanner.RulePacks() {
+		packs = append(packs, &model.ScannerRulePack{
+			ID:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Categories:  p.Categories,
+			Enabled:     enabled[p.ID],
+		})
+	}
+
+	return packs, nil
+}
+
+// ScannerRule returns generated.ScannerRuleResolver implementation.
+func (r *Resolver) ScannerRule() generated.ScannerRuleResolver { return &scannerRuleResolver{r} }
+
+type scannerRuleResolver struct{ *Resolver }
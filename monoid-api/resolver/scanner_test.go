@@ -0,0 +1,137 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"gorm.io/gorm"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+// TestCreateScannerRuleValidation checks the rules that are rejected
+// before anything is read from the database.
+func TestCreateScannerRuleValidation(t *testing.T) {
+	r := &mutationResolver{&Resolver{Conf: &config.BaseConfig{}}}
+
+	for name, input := range map[string]model.CreateScannerRuleInput{
+		"missing pattern": {Kind: model.ScannerRuleKindRegex},
+		"empty pattern":   {Kind: model.ScannerRuleKindRegex, Pattern: str("")},
+		"invalid pattern": {Kind: model.ScannerRuleKindRegex, Pattern: str("EMP-(\\d+")},
+		"missing columns": {Kind: model.ScannerRuleKindName},
+		"regex with columns": {
+			Kind:        model.ScannerRuleKindRegex,
+			Pattern:     str(`\bEMP-\d{6}\b`),
+			ColumnNames: []string{"badge"},
+		},
+		"name with pattern": {
+			Kind:        model.ScannerRuleKindName,
+			ColumnNames: []string{"badge"},
+			Pattern:     str(`\bEMP-\d{6}\b`),
+		},
+		"bad confidence": {
+			Kind:        model.ScannerRuleKindName,
+			ColumnNames: []string{"badge"},
+			Confidence:  str("certain"),
+		},
+	} {
+		_, err := r.CreateScannerRule(context.Background(), input)
+		assert.Error(t, err, name)
+	}
+}
+
+type scannerRuleTestSuite struct {
+	suite.Suite
+
+	container testcontainers.Container
+	db        *gorm.DB
+	r         *mutationResolver
+}
+
+func (s *scannerRuleTestSuite) SetupSuite() {
+	container, db, err := testutil.SetupDB()
+	if err != nil {
+		s.T().Skipf("could not start postgres: %v", err)
+	}
+
+	s.container = container
+	s.db = db
+	s.r = &mutationResolver{&Resolver{Conf: &config.BaseConfig{DB: db}}}
+}
+
+func (s *scannerRuleTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+func (s *scannerRuleTestSuite) TestCreateAndDelete() {
+	ctx := context.Background()
+
+	w := model.Workspace{ID: uuid.NewString()}
+	s.Require().NoError(s.db.Create(&w).Error)
+
+	other := model.Workspace{ID: uuid.NewString()}
+	s.Require().NoError(s.db.Create(&other).Error)
+
+	global := model.Category{ID: uuid.NewString(), Name: "Employee"}
+	s.Require().NoError(s.db.Create(&global).Error)
+
+	otherCategory := model.Category{ID: uuid.NewString(), Name: "Other", WorkspaceID: &other.ID}
+	s.Require().NoError(s.db.Create(&otherCategory).Error)
+
+	rule, err := s.r.CreateScannerRule(ctx, model.CreateScannerRuleInput{
+		WorkspaceID: w.ID,
+		Name:        "Badge",
+		Kind:        model.ScannerRuleKindName,
+		ColumnNames: []string{"badge", "badge_number"},
+		CategoryID:  global.ID,
+	})
+	s.Require().NoError(err)
+	s.Equal("medium", rule.Confidence)
+
+	columnNames, err := rule.ColumnNameList()
+	s.Require().NoError(err)
+	s.Equal([]string{"badge", "badge_number"}, columnNames)
+
+	// Categories from other workspaces can't be used.
+	_, err = s.r.CreateScannerRule(ctx, model.CreateScannerRuleInput{
+		WorkspaceID: w.ID,
+		Name:        "Other",
+		Kind:        model.ScannerRuleKindRegex,
+		Pattern:     str(`\bEMP-\d{6}\b`),
+		CategoryID:  otherCategory.ID,
+	})
+	s.Error(err)
+
+	id, err := s.r.DeleteScannerRule(ctx, rule.ID)
+	s.Require().NoError(err)
+	s.Equal(rule.ID, id)
+
+	// Global rules are managed by the loader.
+	globalRule := model.ScannerRule{
+		ID:         uuid.NewString(),
+		Name:       "Global",
+		Kind:       model.ScannerRuleKindRegex,
+		Pattern:    str(`\bCUST-\d{8}\b`),
+		Confidence: "high",
+		CategoryID: global.ID,
+	}
+	s.Require().NoError(s.db.Create(&globalRule).Error)
+
+	_, err = s.r.DeleteScannerRule(ctx, globalRule.ID)
+	s.Error(err)
+}
+
+func TestScannerRuleSuite(t *testing.T) {
+	suite.Run(t, new(scannerRuleTestSuite))
+}
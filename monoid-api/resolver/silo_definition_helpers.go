@@ -2,11 +2,15 @@ package resolver
 
 import (
 	"context"
+	"encoding/json"
 
+	"github.com/google/uuid"
 	"github.com/monoid-privacy/monoid/model"
 	"github.com/monoid-privacy/monoid/monoidprotocol"
 	"github.com/monoid-privacy/monoid/workflow"
+	"github.com/monoid-privacy/monoid/workflow/activity"
 	"go.temporal.io/sdk/client"
+	"gorm.io/gorm"
 )
 
 type validateResult struct {
@@ -63,3 +67,87 @@ func (r *Resolver) validateSiloDef(ctx context.Context, workflowID string, siloD
 		message: "",
 	}, nil
 }
+
+// previewDataSource runs the workflow that scans a sample of records from a
+// data source, and decrypts its result.
+func (r *Resolver) previewDataSource(
+	ctx context.Context,
+	args activity.PreviewDataSourceArgs,
+) (*model.DataSourcePreview, error) {
+	options := client.StartWorkflowOptions{
+		ID:        "preview-" + uuid.NewString(),
+		TaskQueue: workflow.DockerRunnerQueue,
+	}
+
+	sf := workflow.Workflow{
+		Conf: r.Conf,
+	}
+
+	we, err := r.Conf.TemporalClient.ExecuteWorkflow(ctx, options, sf.PreviewDataSourceWorkflow, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if err := we.Get(ctx, &data); err != nil {
+		return nil, err
+	}
+
+	previewJSON := model.SecretString("")
+	if err := previewJSON.Scan(data); err != nil {
+		return nil, err
+	}
+
+	res := model.DataSourcePreview{}
+	if err := json.Unmarshal([]byte(previewJSON), &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// setDiscoverySchedulePaused pauses or resumes the discovery schedule of
+// a silo.
+func (r *Resolver) setDiscoverySchedulePaused(
+	ctx context.Context,
+	siloDefID string,
+	paused bool,
+) (*model.DiscoverySchedule, error) {
+	schedule := model.DiscoverySchedule{}
+	if err := r.Conf.DB.Where("silo_definition_id = ?", siloDefID).First(&schedule).Error; err != nil {
+		return nil, handleError(err, "Error finding discovery schedule.")
+	}
+
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&schedule).Update("paused", paused).Error; err != nil {
+			return err
+		}
+
+		return workflow.PauseDiscoverySchedule(ctx, r.Conf.TemporalClient, siloDefID, paused)
+	}); err != nil {
+		return nil, handleError(err, "Error updating discovery schedule.")
+	}
+
+	schedule.Paused = paused
+
+	return &schedule, nil
+}
+
+// snapshotDataMap records a new version of a silo's data map after it's
+// been edited by hand. It's run in the transaction that made the edit, so
+// the edit fails if the version can't be recorded.
+func snapshotDataMap(tx *gorm.DB, siloID string) error {
+	_, err := model.CreateDataMapSnapshot(siloID, model.SnapshotReasonManualEdit, tx)
+	return err
+}
+
+// snapshotDataSourceDataMap records a new version of the data map of the
+// silo a data source is in, the data source may have been deleted.
+func snapshotDataSourceDataMap(tx *gorm.DB, dataSourceID string) error {
+	dataSource := model.DataSource{}
+	if err := tx.Unscoped().Where("id = ?", dataSourceID).First(&dataSource).Error; err != nil {
+		return err
+	}
+
+	return snapshotDataMap(tx, dataSource.SiloDefinitionID)
+}
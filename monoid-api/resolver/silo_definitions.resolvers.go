@@ -6,6 +6,7 @@ package resolver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -13,8 +14,10 @@ import (
 	"github.com/monoid-privacy/monoid/generated"
 	"github.com/monoid-privacy/monoid/jsonschema"
 	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/workflow"
 	"github.com/rs/zerolog/log"
 	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -189,6 +192,10 @@ func (r *mutationResolver) DeleteSiloDefinition(ctx context.Context, id string)
 		return "", handleError(err, "Error deleting silo definition.")
 	}
 
+	if err := workflow.DeleteDiscoverySchedule(ctx, r.Conf.TemporalClient, id); err != nil {
+		log.Err(err).Msg("Error deleting discovery schedule")
+	}
+
 	r.Conf.AnalyticsIngestor.Track("siloAction", nil, map[string]interface{}{
 		"action": "delete",
 		"siloId": id,
@@ -199,6 +206,87 @@ func (r *mutationResolver) DeleteSiloDefinition(ctx context.Context, id string)
 	return id, nil
 }
 
+// UpdateDiscoverySchedule is the resolver for the updateDiscoverySchedule field.
+func (r *mutationResolver) UpdateDiscoverySchedule(ctx context.Context, input model.UpdateDiscoveryScheduleInput) (*model.DiscoverySchedule, error) {
+	if _, err := model.ParseCron(input.Cron); err != nil {
+		return nil, handleError(err, "Invalid cron expression.")
+	}
+
+	silo := model.SiloDefinition{}
+	if err := r.Conf.DB.Where("id = ?", input.SiloDefinitionID).First(&silo).Error; err != nil {
+		return nil, handleError(err, "Error finding silo definition.")
+	}
+
+	schedule := model.DiscoverySchedule{}
+	if err := r.Conf.DB.Where("silo_definition_id = ?", silo.ID).First(&schedule).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, handleError(err, "Error finding discovery schedule.")
+		}
+
+		schedule = model.DiscoverySchedule{
+			ID:               uuid.NewString(),
+			SiloDefinitionID: silo.ID,
+			WorkspaceID:      silo.WorkspaceID,
+		}
+	}
+
+	schedule.Cron = input.Cron
+	if input.JitterSeconds != nil {
+		if *input.JitterSeconds < 0 {
+			return nil, handleError(fmt.Errorf("negative jitter"), "Jitter can't be negative.")
+		}
+
+		schedule.JitterSeconds = *input.JitterSeconds
+	}
+
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&schedule).Error; err != nil {
+			return err
+		}
+
+		return workflow.UpsertDiscoverySchedule(ctx, r.Conf.TemporalClient, schedule)
+	}); err != nil {
+		return nil, handleError(err, "Error updating discovery schedule.")
+	}
+
+	r.Conf.AnalyticsIngestor.Track("siloAction", nil, map[string]interface{}{
+		"action": "update_discovery_schedule",
+		"siloId": silo.ID,
+	})
+
+	return &schedule, nil
+}
+
+// PauseDiscoverySchedule is the resolver for the pauseDiscoverySchedule field.
+func (r *mutationResolver) PauseDiscoverySchedule(ctx context.Context, siloDefinitionID string) (*model.DiscoverySchedule, error) {
+	return r.setDiscoverySchedulePaused(ctx, siloDefinitionID, true)
+}
+
+// ResumeDiscoverySchedule is the resolver for the resumeDiscoverySchedule field.
+func (r *mutationResolver) ResumeDiscoverySchedule(ctx context.Context, siloDefinitionID string) (*model.DiscoverySchedule, error) {
+	return r.setDiscoverySchedulePaused(ctx, siloDefinitionID, false)
+}
+
+// DeleteDiscoverySchedule is the resolver for the deleteDiscoverySchedule field.
+func (r *mutationResolver) DeleteDiscoverySchedule(ctx context.Context, siloDefinitionID string) (*string, error) {
+	schedule := model.DiscoverySchedule{}
+	if err := r.Conf.DB.Where("silo_definition_id = ?", siloDefinitionID).First(&schedule).Error; err != nil {
+		return nil, handleError(err, "Error finding discovery schedule.")
+	}
+
+	if err := r.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&schedule).Error; err != nil {
+			return err
+		}
+
+		return workflow.DeleteDiscoverySchedule(ctx, r.Conf.TemporalClient, siloDefinitionID)
+	}); err != nil {
+		return nil, handleError(err, "Error deleting discovery schedule.")
+	}
+
+	return &schedule.ID, nil
+}
+
 // SiloDefinition is the resolver for the siloDefinition field.
 func (r *queryResolver) SiloDefinition(ctx context.Context, id string) (*model.SiloDefinition, error) {
 	silo := &model.SiloDefinition{}
@@ -258,6 +346,20 @@ func (r *siloDefinitionResolver) SiloConfig(ctx context.Context, obj *model.Silo
 	return res, nil
 }
 
+// DiscoverySchedule is the resolver for the discoverySchedule field.
+func (r *siloDefinitionResolver) DiscoverySchedule(ctx context.Context, obj *model.SiloDefinition) (*model.DiscoverySchedule, error) {
+	schedule := model.DiscoverySchedule{}
+	if err := r.Conf.DB.Where("silo_definition_id = ?", obj.ID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, handleError(err, "Error finding discovery schedule.")
+	}
+
+	return &schedule, nil
+}
+
 // SiloDefinitions is the resolver for the siloDefinitions field.
 func (r *workspaceResolver) SiloDefinitions(ctx context.Context, obj *model.Workspace) ([]*model.SiloDefinition, error) {
 	defs := []*model.SiloDefinition{}
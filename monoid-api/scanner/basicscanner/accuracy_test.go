@@ -0,0 +1,397 @@
+package basicscanner
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:generate go run testdata/gen_corpus.go
+
+var updateBaseline = flag.Bool("update-accuracy-baseline", false, "write the accuracy on the corpus to the baseline file")
+
+const (
+	corpusDir    = "testdata/corpus"
+	baselineFile = "testdata/accuracy_baseline.json"
+
+	// discoveryThreshold is the score a category needs to be discovered,
+	// the same as the server's default.
+	discoveryThreshold = 0.2
+
+	// accuracyTolerance is how far a metric can drop below the baseline
+	// before the test fails.
+	accuracyTolerance = 0.01
+)
+
+// corpusTable is a table in the labelled corpus. Labels has the categories
+// of every property in the table.
+type corpusTable struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	RulePacks   []string                 `json:"rulePacks"`
+	Labels      map[string][]string      `json:"labels"`
+	Schema      map[string]interface{}   `json:"schema"`
+	Records     []map[string]interface{} `json:"records"`
+}
+
+// metrics counts the predictions for a rule or category, at the property
+// level.
+type metrics struct {
+	TruePositives  int `json:"truePositives"`
+	FalsePositives int `json:"falsePositives"`
+	FalseNegatives int `json:"falseNegatives"`
+}
+
+func (m metrics) precision() float64 {
+	if m.TruePositives+m.FalsePositives == 0 {
+		return 1
+	}
+
+	return float64(m.TruePositives) / float64(m.TruePositives+m.FalsePositives)
+}
+
+func (m metrics) recall() float64 {
+	if m.TruePositives+m.FalseNegatives == 0 {
+		return 1
+	}
+
+	return float64(m.TruePositives) / float64(m.TruePositives+m.FalseNegatives)
+}
+
+func (m metrics) f1() float64 {
+	p, r := m.precision(), m.recall()
+	if p+r == 0 {
+		return 0
+	}
+
+	return 2 * p * r / (p + r)
+}
+
+// accuracy is the accuracy of the scanner on the corpus.
+type accuracy struct {
+	Overall    metrics             `json:"overall"`
+	Categories map[string]*metrics `json:"categories"`
+	Rules      map[string]*metrics `json:"rules"`
+
+	// Errors describe each wrong prediction, e.g. "customers.notes:
+	// unexpected surname".
+	Errors []string `json:"-"`
+}
+
+// baseline is the accuracy that's expected on the corpus.
+type baseline struct {
+	Precision  float64            `json:"precision"`
+	Recall     float64            `json:"recall"`
+	Categories map[string]float64 `json:"categoryF1"`
+}
+
+func loadCorpus(t testing.TB) []corpusTable {
+	files, err := filepath.Glob(filepath.Join(corpusDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []corpusTable{}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		table := corpusTable{}
+		if err := json.Unmarshal(data, &table); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+// scanTable scans a corpus table, and returns the rules that matched each
+// property, and the combined score of each category.
+func scanTable(t testing.TB, table corpusTable) (map[string]map[string]float64, map[string]map[string]bool) {
+	sc, err := NewBasicScanner(monoidprotocol.MonoidSchema{
+		Name:       table.Name,
+		JsonSchema: table.Schema,
+	}, scanner.RuleConfig{RulePacks: table.RulePacks})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range table.Records {
+		if err := sc.Scan(&monoidprotocol.MonoidRecord{SchemaName: table.Name, Data: r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scores := map[string]map[string][]float64{}
+	rules := map[string]map[string]bool{}
+
+	for _, m := range sc.Summary() {
+		if scores[m.Identifier] == nil {
+			scores[m.Identifier] = map[string][]float64{}
+			rules[m.Identifier] = map[string]bool{}
+		}
+
+		scores[m.Identifier][m.Category] = append(scores[m.Identifier][m.Category], m.Score)
+		rules[m.Identifier][m.RuleName+"/"+m.Category] = true
+	}
+
+	combined := map[string]map[string]float64{}
+	for prop, cats := range scores {
+		combined[prop] = map[string]float64{}
+		for cat, s := range cats {
+			combined[prop][cat] = scanner.CombineScores(s...)
+		}
+	}
+
+	return combined, rules
+}
+
+// measureAccuracy scans the corpus, and compares the categories that would
+// be discovered for each property with its labels.
+func measureAccuracy(t testing.TB, tables []corpusTable) accuracy {
+	acc := accuracy{
+		Categories: map[string]*metrics{},
+		Rules:      map[string]*metrics{},
+	}
+
+	count := func(m map[string]*metrics, key string) *metrics {
+		if m[key] == nil {
+			m[key] = &metrics{}
+		}
+
+		return m[key]
+	}
+
+	tableRules := make([]map[string]map[string]bool, len(tables))
+
+	for i, table := range tables {
+		scores, rules := scanTable(t, table)
+		tableRules[i] = rules
+
+		for prop, labels := range table.Labels {
+			expected := map[string]bool{}
+			for _, l := range labels {
+				expected[l] = true
+			}
+
+			predicted := map[string]bool{}
+			for cat, score := range scores[prop] {
+				if score >= discoveryThreshold {
+					predicted[cat] = true
+				}
+			}
+
+			for cat := range predicted {
+				if expected[cat] {
+					acc.Overall.TruePositives++
+					count(acc.Categories, cat).TruePositives++
+				} else {
+					acc.Overall.FalsePositives++
+					count(acc.Categories, cat).FalsePositives++
+					acc.Errors = append(acc.Errors, fmt.Sprintf("%s.%s: unexpected %s (score %.2f)", table.Name, prop, cat, scores[prop][cat]))
+				}
+			}
+
+			for cat := range expected {
+				if !predicted[cat] {
+					acc.Overall.FalseNegatives++
+					count(acc.Categories, cat).FalseNegatives++
+					acc.Errors = append(acc.Errors, fmt.Sprintf("%s.%s: missed %s (score %.2f)", table.Name, prop, cat, scores[prop][cat]))
+				}
+			}
+
+			// Rules are scored on whether they matched, regardless of the
+			// score, since a rule only needs to contribute to a category.
+			for rc := range rules[prop] {
+				parts := strings.SplitN(rc, "/", 2)
+				if expected[parts[1]] {
+					count(acc.Rules, parts[0]).TruePositives++
+				} else {
+					count(acc.Rules, parts[0]).FalsePositives++
+				}
+			}
+		}
+
+		// Every property should be labelled, so a prediction is never
+		// ignored.
+		for prop := range scores {
+			if _, ok := table.Labels[prop]; !ok {
+				t.Errorf("%s.%s isn't labelled", table.Name, prop)
+			}
+		}
+	}
+
+	// A rule missed a property if the property has the rule's category,
+	// but the rule didn't match it. Rules that share a category (e.g.
+	// uk_nino and in_aadhaar) each miss the other's properties, so a
+	// rule's recall is only comparable with its own baseline.
+	categories := ruleCategories()
+
+	for i, table := range tables {
+		for name, m := range acc.Rules {
+			for prop, labels := range table.Labels {
+				for _, l := range labels {
+					if categories[name] == l && !tableRules[i][prop][name+"/"+l] {
+						m.FalseNegatives++
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(acc.Errors)
+
+	return acc
+}
+
+// ruleCategories returns the category each built-in and rule pack rule
+// reports.
+func ruleCategories() map[string]string {
+	packs := []string{}
+	for _, p := range RulePacks() {
+		packs = append(packs, p.ID)
+	}
+
+	conf, _ := NewMatchConfig(scanner.RuleConfig{RulePacks: packs})
+	res := map[string]string{}
+
+	for _, r := range conf.RegexRules {
+		res[r.Name] = ruleCategory(r.Name, r.Category)
+	}
+
+	for _, r := range conf.NameRules {
+		res[r.Name] = ruleCategory(r.Name, r.Category)
+	}
+
+	for _, r := range conf.MultiNameRules {
+		res[r.Name] = r.Name
+	}
+
+	for _, r := range conf.TokenRules {
+		res[r.Name] = r.Name
+	}
+
+	return res
+}
+
+func (a accuracy) report() string {
+	b := strings.Builder{}
+
+	write := func(title string, m map[string]*metrics) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		fmt.Fprintf(&b, "\n%-16s %9s %9s %9s %4s %4s %4s\n", title, "precision", "recall", "f1", "tp", "fp", "fn")
+
+		for _, k := range keys {
+			v := m[k]
+			fmt.Fprintf(&b, "%-16s %9.2f %9.2f %9.2f %4d %4d %4d\n", k, v.precision(), v.recall(), v.f1(), v.TruePositives, v.FalsePositives, v.FalseNegatives)
+		}
+	}
+
+	write("category", a.Categories)
+	write("rule", a.Rules)
+
+	fmt.Fprintf(
+		&b,
+		"\noverall precision %.2f, recall %.2f, f1 %.2f\n",
+		a.Overall.precision(),
+		a.Overall.recall(),
+		a.Overall.f1(),
+	)
+
+	if len(a.Errors) > 0 {
+		b.WriteString("\nerrors:\n  " + strings.Join(a.Errors, "\n  ") + "\n")
+	}
+
+	return b.String()
+}
+
+func (a accuracy) baseline() baseline {
+	res := baseline{
+		Precision:  a.Overall.precision(),
+		Recall:     a.Overall.recall(),
+		Categories: map[string]float64{},
+	}
+
+	for k, m := range a.Categories {
+		res.Categories[k] = m.f1()
+	}
+
+	return res
+}
+
+// TestAccuracy checks the scanner's precision and recall on the labelled
+// corpus haven't dropped below the baseline. After a change that improves
+// them, update the baseline with:
+//
+//	go test ./scanner/basicscanner -run TestAccuracy -update-accuracy-baseline
+func TestAccuracy(t *testing.T) {
+	acc := measureAccuracy(t, loadCorpus(t))
+	t.Log(acc.report())
+
+	if *updateBaseline {
+		data, err := json.MarshalIndent(acc.baseline(), "", "  ")
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(baselineFile, append(data, '\n'), 0644))
+
+		return
+	}
+
+	data, err := os.ReadFile(baselineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := baseline{}
+	if err := json.Unmarshal(data, &base); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.GreaterOrEqual(t, acc.Overall.precision(), base.Precision-accuracyTolerance, "precision regressed")
+	assert.GreaterOrEqual(t, acc.Overall.recall(), base.Recall-accuracyTolerance, "recall regressed")
+
+	for cat, f1 := range base.Categories {
+		m, ok := acc.Categories[cat]
+		if !ok {
+			t.Errorf("category %s is no longer predicted", cat)
+			continue
+		}
+
+		assert.GreaterOrEqual(t, m.f1(), f1-accuracyTolerance, "f1 of %s regressed", cat)
+	}
+}
+
+// BenchmarkAccuracy reports the accuracy on the corpus with the time it
+// takes to scan it.
+func BenchmarkAccuracy(b *testing.B) {
+	tables := loadCorpus(b)
+
+	var acc accuracy
+	for n := 0; n < b.N; n++ {
+		acc = measureAccuracy(b, tables)
+	}
+
+	b.ReportMetric(acc.Overall.precision(), "precision")
+	b.ReportMetric(acc.Overall.recall(), "recall")
+	b.ReportMetric(acc.Overall.f1(), "f1")
+}
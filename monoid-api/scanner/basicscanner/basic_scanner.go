@@ -2,10 +2,7 @@ package basicscanner
 
 import (
 	"errors"
-	"fmt"
-	"strings"
 
-	"github.com/mitchellh/mapstructure"
 	"github.com/monoid-privacy/monoid/jsonschema"
 	"github.com/monoid-privacy/monoid/monoidprotocol"
 	"github.com/monoid-privacy/monoid/scanner"
@@ -18,17 +15,32 @@ type BasicScanner struct {
 	ValuePaths  []scanner.ValuePath
 	MatchConfig *MatchConfig
 	MatchFinder MatchFinder
+
+	// RowCount is the number of records that have been scanned.
+	RowCount int
+
+	// DocumentCount is the number of documents that have been scanned with
+	// ScanText.
+	DocumentCount int
 }
 
-func NewBasicScanner(schema monoidprotocol.MonoidSchema) (*BasicScanner, error) {
-	matchConfig := NewMatchConfig()
+// NewBasicScanner creates a scanner for records of schema. The rule packs
+// and custom rules in ruleConfig are run in addition to the built-in rules.
+func NewBasicScanner(
+	schema monoidprotocol.MonoidSchema,
+	ruleConfig scanner.RuleConfig,
+) (*BasicScanner, error) {
+	matchConfig, err := NewMatchConfig(ruleConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	parsedSchema := jsonschema.Schema{}
-	if err := mapstructure.Decode(schema.JsonSchema, &parsedSchema); err != nil {
+	if err := jsonschema.Decode(schema.JsonSchema, &parsedSchema); err != nil {
 		return nil, err
 	}
 
-	valuePaths := getValuePaths(parsedSchema)
+	valuePaths := GetValuePaths(parsedSchema)
 
 	schemaGroup := ""
 	if schema.Group != nil {
@@ -53,75 +65,64 @@ func NewBasicScanner(schema monoidprotocol.MonoidSchema) (*BasicScanner, error)
 
 func (r *BasicScanner) ScanNames() {
 	for _, vp := range r.ValuePaths {
-		colName := vp.Path[len(vp.Path)-1]
-		name := strings.Replace(strings.ToLower(colName), "_", "", -1)
+		name := normalizeColumnName(propertyName(vp.Path))
 
-		_, index := matchNameRule(name, r.MatchConfig.NameRules)
-		if index >= 0 {
-			r.MatchFinder.NameValues[index] = append(r.MatchFinder.NameValues[index], MatchLine{Path: strings.Join(vp.Path, "."), Line: ""})
+		for i, rule := range r.MatchConfig.NameRules {
+			if stringInSlice(name, rule.ColumnNames) {
+				r.MatchFinder.NameValues[i] = append(r.MatchFinder.NameValues[i], MatchLine{Path: scanner.PathString(vp.Path), Line: ""})
+			}
 		}
 	}
-}
 
-func getValuePathsHelper(schema *jsonschema.Schema, path []string, valuePaths *[]scanner.ValuePath) {
-	if schema == nil {
-		return
-	}
-
-	for propertyName, propertyValue := range schema.Properties {
-		path = append(path, propertyName)
-		if stringInSlice(propertyValue.Type, []string{"string", "number", "integer"}) {
-			copiedPath := make([]string, len(path))
-			copy(copiedPath, path)
-			*valuePaths = append(*valuePaths, scanner.ValuePath{
-				Path: copiedPath,
-				Type: propertyValue.Type,
-			})
-		} else {
-			if propertyValue != nil {
-				getValuePathsHelper(propertyValue, path, valuePaths)
-			}
-		}
-		path = path[:len(path)-1]
+	for i, rule := range r.MatchConfig.MultiNameRules {
+		r.MatchFinder.MultiNameValues[i] = r.scanMultiName(rule)
 	}
 }
 
-func getValuePaths(schema jsonschema.Schema) []scanner.ValuePath {
-	valuePaths := []scanner.ValuePath{}
-	getValuePathsHelper(&schema, []string{}, &valuePaths)
-	return valuePaths
-}
+// scanMultiName finds the objects that have a column for every group of
+// names in rule (e.g. both a latitude and a longitude column), and returns
+// those columns, with the path of their object as the line.
+func (r *BasicScanner) scanMultiName(rule multiNameRule) []MatchLine {
+	// The columns in each object that match each group of names.
+	objects := map[string][][]string{}
+	objectOrder := []string{}
 
-func getValueByPath(valuePath scanner.ValuePath, data monoidprotocol.MonoidRecordData) (string, error) {
-	value, ok := data[valuePath.Path[0]]
-	if !ok {
-		return "", errors.New("property doesn't match path ")
-	}
+	for _, vp := range r.ValuePaths {
+		name := normalizeColumnName(propertyName(vp.Path))
+		parent := scanner.PathString(vp.Path[:len(vp.Path)-1])
 
-	if value == nil {
-		return "", nil
-	}
+		for g, group := range rule.ColumnNames {
+			if !stringInSlice(name, group) {
+				continue
+			}
 
-	if len(valuePath.Path) == 1 {
+			if _, ok := objects[parent]; !ok {
+				objects[parent] = make([][]string, len(rule.ColumnNames))
+				objectOrder = append(objectOrder, parent)
+			}
 
-		valueString, ok := value.(string)
-		if !ok {
-			return "", fmt.Errorf("value has incorrect type")
+			objects[parent][g] = append(objects[parent][g], scanner.PathString(vp.Path))
 		}
-		return valueString, nil
 	}
-	valueData, ok := value.(monoidprotocol.MonoidRecordData)
-	if !ok {
-		return "", errors.New("cannot parse value data into monoid data")
-	}
-	valueString, err := getValueByPath(scanner.ValuePath{
-		Path: valuePath.Path[1:],
-		Type: valuePath.Type,
-	}, valueData)
-	if err != nil {
-		return "", err
+
+	res := []MatchLine{}
+
+L:
+	for _, parent := range objectOrder {
+		for _, columns := range objects[parent] {
+			if len(columns) == 0 {
+				continue L
+			}
+		}
+
+		for _, columns := range objects[parent] {
+			for _, c := range columns {
+				res = append(res, MatchLine{Path: c, Line: parent})
+			}
+		}
 	}
-	return valueString, nil
+
+	return res
 }
 
 func (r *BasicScanner) Scan(record *monoidprotocol.MonoidRecord) error {
@@ -134,18 +135,23 @@ func (r *BasicScanner) Scan(record *monoidprotocol.MonoidRecord) error {
 		return errors.New("record not compatible with scanner's schema")
 	}
 
+	r.RowCount++
+
 	for _, valuePath := range r.ValuePaths {
-		if valuePath.Type != "string" {
-			continue
+		for _, value := range getValuesByPath(valuePath.Path, record.Data) {
+			r.MatchFinder.ScanString(value, valuePath.Path)
 		}
+	}
 
-		value, err := getValueByPath(valuePath, record.Data)
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		r.MatchFinder.ScanString(value, valuePath.Path)
-	}
+// ScanText runs the regex and token rules on the text of a document. Each
+// document counts as one value of the scanner.FileProperty path, so match
+// ratios are the share of documents that matched.
+func (r *BasicScanner) ScanText(text string) error {
+	r.DocumentCount++
+	r.MatchFinder.ScanString(text, []string{scanner.FileProperty})
 
 	return nil
 }
@@ -154,61 +160,70 @@ func (r *BasicScanner) Summary() []scanner.RuleMatch {
 	// Go through MatchFinder -- for each rule, group by paths, run checkmatches, and output rulematches
 	ruleMatches := []scanner.RuleMatch{}
 
+	addMatch := func(match scanner.RuleMatch, path string) {
+		match.RowCount = r.RowCount
+		if path == scanner.FileProperty {
+			match.RowCount = r.DocumentCount
+		}
+
+		match.SchemaName = r.SchemaName
+		match.SchemaGroup = &r.SchemaGroup
+		match.Identifier = path
+		ruleMatches = append(ruleMatches, match)
+	}
+
 	for i, rule := range r.MatchConfig.RegexRules {
-		pathMap := map[string][]MatchLine{}
-		for _, v := range r.MatchFinder.MatchedValues[i] {
-			if _, ok := pathMap[v.Path]; !ok {
-				pathMap[v.Path] = []MatchLine{v}
-			} else {
-				pathMap[v.Path] = append(pathMap[v.Path], v)
+		for path, stats := range r.MatchFinder.ValueMatches[i] {
+			if match, ok := r.MatchFinder.valueMatch(rule, stats, r.MatchFinder.PathCounts[path]); ok {
+				addMatch(match, path)
 			}
 		}
+	}
 
-		for path, matchedData := range pathMap {
-			if len(matchedData) >= r.MatchConfig.MinCount {
-				confidence := rule.Confidence
-
-				if confidence == "" {
-					confidence = "low"
-				}
-
-				lineCount := len(matchedData)
-
-				stringMatchedData := []string{}
-
-				for _, line := range matchedData {
-					stringMatchedData = append(stringMatchedData, line.Line)
-				}
-				ruleMatches = append(ruleMatches, scanner.RuleMatch{
-					SchemaName:  r.SchemaName,
-					SchemaGroup: &r.SchemaGroup,
-					RuleName:    rule.Name,
-					DisplayName: rule.DisplayName,
-					Confidence:  confidence,
-					Identifier:  path,
-					MatchedData: stringMatchedData,
-					LineCount:   lineCount,
-					MatchType:   "value",
-				})
-
+	for i, rule := range r.MatchConfig.TokenRules {
+		for path, stats := range r.MatchFinder.TokenMatches[i] {
+			if match, ok := r.MatchFinder.tokenMatch(rule, stats, r.MatchFinder.PathCounts[path]); ok {
+				addMatch(match, path)
 			}
 		}
 	}
 
 	for i, rule := range r.MatchConfig.NameRules {
-		paths := []string{}
+		confidence := rule.Confidence
+		if confidence == "" {
+			confidence = "medium"
+		}
+
 		for _, lineMatch := range r.MatchFinder.NameValues[i] {
-			paths = append(paths, lineMatch.Path)
+			addMatch(scanner.RuleMatch{
+				RuleName:    rule.Name,
+				DisplayName: rule.DisplayName,
+				Category:    ruleCategory(rule.Name, rule.Category),
+				Confidence:  confidence,
+				MatchedData: []string{lineMatch.Path},
+				Score:       nameScores[confidence],
+				MatchType:   "name",
+			}, lineMatch.Path)
 		}
-		if len(paths) > 0 {
-			ruleMatches = append(ruleMatches, scanner.RuleMatch{
+	}
+
+	for i, rule := range r.MatchConfig.MultiNameRules {
+		// The columns that were found together in each object.
+		objectColumns := map[string][]string{}
+		for _, lineMatch := range r.MatchFinder.MultiNameValues[i] {
+			objectColumns[lineMatch.Line] = append(objectColumns[lineMatch.Line], lineMatch.Path)
+		}
+
+		for _, lineMatch := range r.MatchFinder.MultiNameValues[i] {
+			addMatch(scanner.RuleMatch{
 				RuleName:    rule.Name,
 				DisplayName: rule.DisplayName,
+				Category:    rule.Name,
 				Confidence:  "medium",
-				Identifier:  pathToString(paths),
-				MatchedData: paths,
+				MatchedData: objectColumns[lineMatch.Line],
+				Score:       nameScores["medium"],
 				MatchType:   "name",
-			})
+			}, lineMatch.Path)
 		}
 	}
 
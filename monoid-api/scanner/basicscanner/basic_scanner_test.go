@@ -0,0 +1,161 @@
+package basicscanner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryTokenAndMultiNameRules(t *testing.T) {
+	s, err := NewBasicScanner(monoidprotocol.MonoidSchema{
+		Name: "users",
+		JsonSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"family":  map[string]interface{}{"type": "string"},
+				"comment": map[string]interface{}{"type": "string"},
+				"card":    map[string]interface{}{"type": "number"},
+				"place": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"lat": map[string]interface{}{"type": "number"},
+						"lng": map[string]interface{}{"type": "number"},
+					},
+				},
+				"lat": map[string]interface{}{"type": "number"},
+			},
+		},
+	}, scanner.RuleConfig{})
+	assert.NoError(t, err)
+
+	for i, name := range lastNames[:20] {
+		comment := "no surnames here"
+		if i == 0 {
+			comment = "talked to smith"
+		}
+
+		assert.NoError(t, s.Scan(&monoidprotocol.MonoidRecord{
+			SchemaName: "users",
+			Data: monoidprotocol.MonoidRecordData{
+				"family":  name,
+				"comment": comment,
+				"card":    float64(4111111111111111),
+			},
+		}))
+	}
+
+	matches := map[string][]string{}
+	for _, m := range s.Summary() {
+		matches[m.Identifier] = append(matches[m.Identifier], m.RuleName)
+	}
+
+	assert.Equal(t, []string{"surname"}, matches["family"])
+	assert.NotContains(t, matches, "comment")
+	assert.Equal(t, []string{"credit_card"}, matches["card"])
+	assert.Equal(t, []string{"location"}, matches["place.lat"])
+	assert.Equal(t, []string{"location"}, matches["place.lng"])
+
+	// lat on its own doesn't match the location rule.
+	assert.NotContains(t, matches, "lat")
+}
+
+func TestRulePacks(t *testing.T) {
+	_, err := NewMatchConfig(scanner.RuleConfig{RulePacks: []string{"unknown"}})
+	assert.Error(t, err)
+
+	schema := monoidprotocol.MonoidSchema{
+		Name: "payments",
+		JsonSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"account":  map[string]interface{}{"type": "string"},
+				"postcode": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+
+	for _, packs := range [][]string{nil, {"eu", "uk"}} {
+		s, err := NewBasicScanner(schema, scanner.RuleConfig{RulePacks: packs})
+		assert.NoError(t, err)
+
+		assert.NoError(t, s.Scan(&monoidprotocol.MonoidRecord{
+			SchemaName: "payments",
+			Data: monoidprotocol.MonoidRecordData{
+				"account": "DE89 3704 0044 0532 0130 00",
+			},
+		}))
+
+		matches := map[string][]string{}
+		for _, m := range s.Summary() {
+			matches[m.Identifier] = append(matches[m.Identifier], m.Category)
+		}
+
+		if packs == nil {
+			assert.Empty(t, matches)
+			continue
+		}
+
+		assert.Equal(t, []string{"bank_account"}, matches["account"])
+
+		// Both packs match postcode columns, but it's only reported once.
+		assert.Equal(t, []string{"postal_code"}, matches["postcode"])
+	}
+}
+
+func TestMatchStatsBounded(t *testing.T) {
+	conf, err := NewMatchConfig(scanner.RuleConfig{})
+	assert.NoError(t, err)
+	conf.SampleSize = 10
+
+	finder := NewMatchFinder(&conf)
+	for i := 0; i < 1000; i++ {
+		finder.ScanString(fmt.Sprintf("user%d@example.com", i), []string{"email"})
+	}
+
+	for i, r := range conf.RegexRules {
+		if r.Name != "email" {
+			continue
+		}
+
+		stats := finder.ValueMatches[i]["email"]
+		assert.Equal(t, 1000, stats.Matched)
+		assert.Equal(t, 1000, stats.Validated)
+		assert.Len(t, stats.Sample, 10)
+	}
+}
+
+func TestScanTextDocumentCount(t *testing.T) {
+	s, err := NewBasicScanner(monoidprotocol.MonoidSchema{
+		Name: "users",
+		JsonSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"email": map[string]interface{}{"type": "string"},
+			},
+		},
+	}, scanner.RuleConfig{})
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Scan(&monoidprotocol.MonoidRecord{
+			SchemaName: "users",
+			Data:       monoidprotocol.MonoidRecordData{"email": fmt.Sprintf("user%d@example.com", i)},
+		}))
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.ScanText(fmt.Sprintf("contact user%d@example.com", i)))
+	}
+
+	counts := map[string]int{}
+	for _, m := range s.Summary() {
+		if m.RuleName == "email" {
+			counts[m.Identifier] = m.RowCount
+		}
+	}
+
+	assert.Equal(t, map[string]int{"email": 10, scanner.FileProperty: 3}, counts)
+}
@@ -0,0 +1,123 @@
+package basicscanner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+)
+
+const benchRows = 1000000
+
+var benchSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"id":      map[string]interface{}{"type": "number"},
+		"email":   map[string]interface{}{"type": "string"},
+		"name":    map[string]interface{}{"type": "string"},
+		"card":    map[string]interface{}{"type": "string"},
+		"comment": map[string]interface{}{"type": "string"},
+	},
+}
+
+func benchRecord(schema string, i int) monoidprotocol.MonoidRecord {
+	return monoidprotocol.MonoidRecord{
+		SchemaName: schema,
+		Data: monoidprotocol.MonoidRecordData{
+			"id":      float64(i),
+			"email":   fmt.Sprintf("user%d@example.com", i),
+			"name":    lastNames[i%len(lastNames)],
+			"card":    "4111 1111 1111 1111",
+			"comment": "called about their order, nothing to report",
+		},
+	}
+}
+
+// reportHeap reports the heap in use after a scan, which should stay
+// constant as the number of rows grows.
+func reportHeap(b *testing.B) {
+	runtime.GC()
+
+	mem := runtime.MemStats{}
+	runtime.ReadMemStats(&mem)
+
+	b.ReportMetric(float64(mem.HeapInuse)/(1024*1024), "heap-MB")
+}
+
+// BenchmarkScan scans a million rows of a single schema.
+func BenchmarkScan(b *testing.B) {
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		s, err := NewBasicScanner(monoidprotocol.MonoidSchema{Name: "users", JsonSchema: benchSchema}, scanner.RuleConfig{})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for i := 0; i < benchRows; i++ {
+			record := benchRecord("users", i)
+			if err := s.Scan(&record); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		s.Summary()
+		reportHeap(b)
+	}
+
+	b.ReportMetric(float64(benchRows*b.N)/b.Elapsed().Seconds(), "rows/s")
+}
+
+// BenchmarkPipeline scans a million rows spread across several schemas
+// with a worker per CPU.
+func BenchmarkPipeline(b *testing.B) {
+	b.ReportAllocs()
+
+	const schemas = 8
+
+	for n := 0; n < b.N; n++ {
+		scanners := map[string]scanner.Scanner{}
+		for i := 0; i < schemas; i++ {
+			name := fmt.Sprintf("table%d", i)
+
+			s, err := NewBasicScanner(monoidprotocol.MonoidSchema{Name: name, JsonSchema: benchSchema}, scanner.RuleConfig{})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			scanners[name] = s
+		}
+
+		records := make(chan monoidprotocol.MonoidRecord)
+		go func() {
+			for i := 0; i < benchRows; i++ {
+				records <- benchRecord(fmt.Sprintf("table%d", i%schemas), i)
+			}
+
+			close(records)
+		}()
+
+		err := scanner.Pipeline{Workers: runtime.NumCPU()}.Run(
+			context.Background(),
+			records,
+			func(r *monoidprotocol.MonoidRecord) scanner.Scanner {
+				return scanners[r.SchemaName]
+			},
+		)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, s := range scanners {
+			s.Summary()
+		}
+
+		reportHeap(b)
+	}
+
+	b.ReportMetric(float64(benchRows*b.N)/b.Elapsed().Seconds(), "rows/s")
+}
@@ -0,0 +1,88 @@
+package basicscanner
+
+import (
+	"testing"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomRules(t *testing.T) {
+	_, err := NewMatchConfig(scanner.RuleConfig{CustomRules: []scanner.CustomRule{
+		{Name: "bad", Kind: scanner.RuleKindRegex, Pattern: "EMP-(\\d+"},
+	}})
+	assert.Error(t, err)
+
+	_, err = NewMatchConfig(scanner.RuleConfig{CustomRules: []scanner.CustomRule{
+		{Name: "bad", Kind: scanner.RuleKind("UNKNOWN")},
+	}})
+	assert.Error(t, err)
+
+	rules := []scanner.CustomRule{
+		{
+			Name:       "employee_id",
+			Kind:       scanner.RuleKindRegex,
+			Pattern:    `\bEMP-\d{6}\b`,
+			Confidence: "high",
+			Category:   "employee",
+		},
+		{
+			Name:        "badge",
+			Kind:        scanner.RuleKindName,
+			ColumnNames: []string{"Badge_Number", "zip"},
+			Category:    "employee",
+		},
+	}
+
+	conf, err := NewMatchConfig(scanner.RuleConfig{CustomRules: rules})
+	assert.NoError(t, err)
+
+	// Custom rules are added to the built-in rules, with their column
+	// names normalized.
+	assert.Len(t, conf.RegexRules, len(regexRules)+1)
+	assert.Len(t, conf.NameRules, len(nameRules)+1)
+	assert.Equal(t, []string{"badgenumber", "zip"}, conf.NameRules[len(nameRules)].ColumnNames)
+
+	// The built-in rules aren't changed.
+	for _, r := range nameRules {
+		assert.NotEqual(t, "badge", r.Name)
+	}
+
+	s, err := NewBasicScanner(monoidprotocol.MonoidSchema{
+		Name: "employees",
+		JsonSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code":         map[string]interface{}{"type": "string"},
+				"badge_number": map[string]interface{}{"type": "string"},
+				"zip":          map[string]interface{}{"type": "string"},
+			},
+		},
+	}, scanner.RuleConfig{CustomRules: rules})
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Scan(&monoidprotocol.MonoidRecord{
+		SchemaName: "employees",
+		Data: monoidprotocol.MonoidRecordData{
+			"code": "EMP-123456",
+		},
+	}))
+
+	matches := map[string][]string{}
+	for _, m := range s.Summary() {
+		matches[m.Identifier] = append(matches[m.Identifier], m.RuleName)
+
+		if m.RuleName == "employee_id" {
+			assert.Equal(t, "employee", m.Category)
+			assert.Equal(t, "high", m.Confidence)
+		}
+	}
+
+	assert.Equal(t, []string{"employee_id"}, matches["code"])
+	assert.Equal(t, []string{"badge"}, matches["badge_number"])
+
+	// Every name rule that matches a column is reported, not only the
+	// first one.
+	assert.ElementsMatch(t, []string{"postal_code", "badge"}, matches["zip"])
+}
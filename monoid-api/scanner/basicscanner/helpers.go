@@ -2,6 +2,7 @@ package basicscanner
 
 import (
 	"regexp"
+	"strings"
 )
 
 func stringInSlice(a string, list []string) bool {
@@ -13,13 +14,10 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
-func matchNameRule(name string, rules []nameRule) (nameRule, int) {
-	for i, rule := range rules {
-		if stringInSlice(name, rule.ColumnNames) {
-			return rule, i
-		}
-	}
-	return nameRule{}, -1
+// normalizeColumnName lowercases name and removes underscores, so a single
+// rule can match both under_score and camelCase column names.
+func normalizeColumnName(name string) string {
+	return strings.Replace(strings.ToLower(name), "_", "", -1)
 }
 
 func anyMatches(rule tokenRule, values []string) bool {
@@ -0,0 +1,237 @@
+package basicscanner
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// alphanumeric returns the letters and digits in s, uppercased.
+func alphanumeric(s string) string {
+	var b strings.Builder
+
+	for _, c := range strings.ToUpper(s) {
+		if (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') {
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+// validIBAN checks the ISO 13616 mod-97 checksum.
+func validIBAN(match string) bool {
+	iban := alphanumeric(match)
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rem := 0
+
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			rem = (rem*100 + int(c-'A'+10)) % 97
+		} else {
+			rem = (rem*10 + int(c-'0')) % 97
+		}
+	}
+
+	return rem == 1
+}
+
+// invalidNINOPrefixes are never issued as National Insurance numbers.
+var invalidNINOPrefixes = map[string]bool{
+	"BG": true, "GB": true, "KN": true, "NK": true, "NT": true, "TN": true, "ZZ": true,
+}
+
+// validNINO rejects the prefixes that aren't allocated.
+func validNINO(match string) bool {
+	nino := alphanumeric(match)
+
+	return len(nino) == 9 && !invalidNINOPrefixes[nino[:2]]
+}
+
+// validSteuerID checks the ISO 7064 MOD 11,10 check digit of a German tax
+// id, and that exactly one digit is repeated in the first ten.
+func validSteuerID(match string) bool {
+	id := digits(match)
+	if len(id) != 11 || id[0] == '0' {
+		return false
+	}
+
+	counts := map[rune]int{}
+	for _, c := range id[:10] {
+		counts[c]++
+	}
+
+	repeated := 0
+	for _, n := range counts {
+		if n > 3 {
+			return false
+		}
+
+		if n > 1 {
+			repeated++
+		}
+	}
+
+	if repeated != 1 {
+		return false
+	}
+
+	product := 10
+	for _, c := range id[:10] {
+		sum := (int(c-'0') + product) % 10
+		if sum == 0 {
+			sum = 10
+		}
+
+		product = (sum * 2) % 11
+	}
+
+	check := 11 - product
+	if check == 10 {
+		check = 0
+	}
+
+	return check == int(id[10]-'0')
+}
+
+// validNIR checks the key of a French social security number, which is
+// 97 minus the first 13 digits mod 97. Corsican departments (2A and 2B)
+// are replaced with 19 and 18 to compute it.
+func validNIR(match string) bool {
+	nir := alphanumeric(match)
+	if len(nir) != 15 {
+		return false
+	}
+
+	number := nir[:13]
+
+	offset := uint64(0)
+	switch number[5:7] {
+	case "2A":
+		number = number[:5] + "19" + number[7:]
+		offset = 1000000
+	case "2B":
+		number = number[:5] + "18" + number[7:]
+		offset = 2000000
+	}
+
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	key, err := strconv.ParseUint(nir[13:], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return 97-((n-offset)%97) == key
+}
+
+// Verhoeff checksum tables.
+var (
+	verhoeffD = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffP = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// validAadhaar checks the Verhoeff checksum of an Aadhaar number.
+func validAadhaar(match string) bool {
+	number := digits(match)
+	if len(number) != 12 || allSame(number) {
+		return false
+	}
+
+	c := 0
+	for i := 0; i < len(number); i++ {
+		d := int(number[len(number)-1-i] - '0')
+		c = verhoeffD[c][verhoeffP[i%8][d]]
+	}
+
+	return c == 0
+}
+
+// mrzCheckDigit computes the ICAO 9303 check digit of a machine readable
+// zone field.
+func mrzCheckDigit(field string) int {
+	weights := []int{7, 3, 1}
+	sum := 0
+
+	for i, c := range field {
+		v := 0
+
+		switch {
+		case c >= '0' && c <= '9':
+			v = int(c - '0')
+		case c >= 'A' && c <= 'Z':
+			v = int(c-'A') + 10
+		}
+
+		sum += v * weights[i%3]
+	}
+
+	return sum % 10
+}
+
+// validPassportMRZ checks the check digits of the second line of a
+// passport's machine readable zone: the document number, date of birth,
+// expiry date and the composite check digit.
+func validPassportMRZ(match string) bool {
+	if len(match) != 44 {
+		return false
+	}
+
+	checks := []struct {
+		field string
+		digit byte
+	}{
+		{match[0:9], match[9]},
+		{match[13:19], match[19]},
+		{match[21:27], match[27]},
+		{match[0:10] + match[13:20] + match[21:43], match[43]},
+	}
+
+	for _, c := range checks {
+		if strconv.Itoa(mrzCheckDigit(c.field)) != string(c.digit) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validE164 checks the number of digits in an international phone number.
+func validE164(match string) bool {
+	n := len(digits(strings.Replace(match, "%2B", "+", 1)))
+
+	return n >= 8 && n <= 15
+}
+
+// validIPv6 checks that the match parses as an IPv6 address.
+func validIPv6(match string) bool {
+	ip := net.ParseIP(match)
+
+	return ip != nil && ip.To4() == nil && strings.Count(match, ":") >= 2 && match != "::"
+}
@@ -0,0 +1,82 @@
+package basicscanner
+
+import (
+	"strings"
+)
+
+// maxSamples is the number of masked values kept as evidence for a match.
+const maxSamples = 3
+
+// maskEmail keeps the first character of the local part and of the
+// domain, and the top level domain, e.g. j***@e***.com.
+func maskEmail(v string, at int, sepLen int) string {
+	local := v[:at]
+	domain := v[at+sepLen:]
+
+	masked := maskPrefix(local) + "@"
+
+	dot := strings.LastIndex(domain, ".")
+	if dot < 0 {
+		return masked + maskPrefix(domain)
+	}
+
+	return masked + maskPrefix(domain[:dot]) + domain[dot:]
+}
+
+// maskPrefix keeps the first character of v.
+func maskPrefix(v string) string {
+	if v == "" {
+		return "***"
+	}
+
+	r := []rune(v)
+
+	return string(r[0]) + "***"
+}
+
+// MaskValue masks a matched value, so it can be shown as evidence
+// without revealing it. Emails keep their first characters and top level
+// domain, long identifiers (e.g. card numbers) keep their last four
+// characters, and anything else keeps its first character.
+func MaskValue(v string) string {
+	if at := strings.LastIndex(v, "@"); at > 0 {
+		return maskEmail(v, at, 1)
+	}
+
+	if at := strings.LastIndex(v, "%40"); at > 0 {
+		return maskEmail(v, at, 3)
+	}
+
+	alnum := alphanumeric(v)
+	if len(alnum) >= 8 {
+		return "****" + alnum[len(alnum)-4:]
+	}
+
+	return maskPrefix(v)
+}
+
+// maskedSamples returns up to maxSamples unique masked values.
+func maskedSamples(values []string) []string {
+	res := []string{}
+	seen := map[string]bool{}
+
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+
+		masked := MaskValue(v)
+		if seen[masked] {
+			continue
+		}
+
+		seen[masked] = true
+		res = append(res, masked)
+
+		if len(res) == maxSamples {
+			break
+		}
+	}
+
+	return res
+}
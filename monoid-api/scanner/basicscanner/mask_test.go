@@ -0,0 +1,43 @@
+package basicscanner
+
+import (
+	"testing"
+
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskValue(t *testing.T) {
+	assert.Equal(t, "j***@e***.com", MaskValue("jane@example.com"))
+	assert.Equal(t, "j***@e***.com", MaskValue("jane%40example.com"))
+	assert.Equal(t, "****1111", MaskValue("4111 1111 1111 1111"))
+	assert.Equal(t, "****6789", MaskValue("123-45-6789"))
+	assert.Equal(t, "s***", MaskValue("smith"))
+}
+
+func TestMaskedSamples(t *testing.T) {
+	conf, err := NewMatchConfig(scanner.RuleConfig{})
+	assert.NoError(t, err)
+
+	finder := NewMatchFinder(&conf)
+	for _, v := range []string{
+		"contact jane@example.com for details",
+		"jane@example.com",
+		"joe@example.org",
+		"jim@example.net",
+		"jo@example.io",
+	} {
+		finder.ScanString(v, []string{"email"})
+	}
+
+	var email *matchStats
+	for i, r := range conf.RegexRules {
+		if r.Name == "email" {
+			email = finder.ValueMatches[i]["email"]
+		}
+	}
+
+	// The rest of the line isn't kept, duplicates are dropped, and only
+	// maxSamples are kept.
+	assert.Equal(t, []string{"j***@e***.com", "j***@e***.org", "j***@e***.net"}, maskedSamples(email.Sample))
+}
@@ -1,6 +1,9 @@
 package basicscanner
 
 import (
+	"fmt"
+	"math/rand"
+	"regexp"
 	"strings"
 
 	"github.com/monoid-privacy/monoid/scanner"
@@ -8,122 +11,337 @@ import (
 
 // make matchfinder an interface with its own config
 
-func NewMatchConfig() MatchConfig {
-	return MatchConfig{
-		RegexRules:     regexRules,
-		NameRules:      nameRules,
+// defaultSampleSize is the number of matched values kept for each rule and
+// path.
+const defaultSampleSize = 100
+
+// NewMatchConfig returns the config with the built-in rules, merged with
+// the rule packs and custom rules in ruleConfig.
+func NewMatchConfig(ruleConfig scanner.RuleConfig) (MatchConfig, error) {
+	conf := MatchConfig{
+		RegexRules:     append([]regexRule{}, regexRules...),
+		NameRules:      append([]nameRule{}, nameRules...),
 		MultiNameRules: multiNameRules,
 		TokenRules:     tokenRules,
 		MinCount:       1,
+		SampleSize:     defaultSampleSize,
+	}
+
+	for _, id := range ruleConfig.RulePacks {
+		pack, ok := findRulePack(id)
+		if !ok {
+			return MatchConfig{}, fmt.Errorf("unknown rule pack %s", id)
+		}
+
+		conf.RegexRules = append(conf.RegexRules, pack.RegexRules...)
+
+		for _, r := range pack.NameRules {
+			conf.NameRules = mergeNameRule(conf.NameRules, r)
+		}
+	}
+
+	for _, r := range ruleConfig.CustomRules {
+		switch r.Kind {
+		case scanner.RuleKindName:
+			columnNames := make([]string, len(r.ColumnNames))
+			for i, c := range r.ColumnNames {
+				columnNames[i] = normalizeColumnName(c)
+			}
+
+			conf.NameRules = append(conf.NameRules, nameRule{
+				Name:        r.Name,
+				DisplayName: r.DisplayName,
+				Category:    r.Category,
+				Confidence:  r.Confidence,
+				ColumnNames: columnNames,
+			})
+		case scanner.RuleKindRegex:
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return MatchConfig{}, fmt.Errorf("invalid pattern for rule %s: %v", r.Name, err)
+			}
+
+			conf.RegexRules = append(conf.RegexRules, regexRule{
+				Name:        r.Name,
+				DisplayName: r.DisplayName,
+				Category:    r.Category,
+				Confidence:  r.Confidence,
+				Regex:       re,
+			})
+		default:
+			return MatchConfig{}, fmt.Errorf("unknown kind %s for rule %s", r.Kind, r.Name)
+		}
+	}
+
+	return conf, nil
+}
+
+// mergeNameRule adds rule to rules. If there's already a rule with the
+// same name (e.g. postal codes), its column names are added to that rule
+// instead, so a column isn't reported twice.
+func mergeNameRule(rules []nameRule, rule nameRule) []nameRule {
+	for i, r := range rules {
+		if r.Name != rule.Name {
+			continue
+		}
+
+		columnNames := append([]string{}, r.ColumnNames...)
+		for _, c := range rule.ColumnNames {
+			if !stringInSlice(c, columnNames) {
+				columnNames = append(columnNames, c)
+			}
+		}
+
+		rules[i].ColumnNames = columnNames
+
+		return rules
 	}
+
+	return append(rules, rule)
+}
+
+// ruleCategory returns the category a rule's matches belong to. Built-in
+// rules are named after their category.
+func ruleCategory(name string, category string) string {
+	if category != "" {
+		return category
+	}
+
+	return name
 }
 
 func (a *MatchFinder) Clear() {
-	a.MatchedValues = make([][]MatchLine, len(a.matchConfig.RegexRules))
-	a.TokenValues = make([][]MatchLine, len(a.matchConfig.TokenRules))
+	a.ValueMatches = newMatchStats(len(a.matchConfig.RegexRules))
+	a.TokenMatches = newMatchStats(len(a.matchConfig.TokenRules))
 	a.Count = 0
+	a.PathCounts = map[string]int{}
+}
+
+func newMatchStats(n int) []map[string]*matchStats {
+	res := make([]map[string]*matchStats, n)
+	for i := range res {
+		res[i] = map[string]*matchStats{}
+	}
+
+	return res
 }
 
 func NewMatchFinder(matchConfig *MatchConfig) MatchFinder {
 	return MatchFinder{
-		make([][]MatchLine, len(matchConfig.RegexRules)),
-		make([][]MatchLine, len(matchConfig.TokenRules)),
-		make([][]MatchLine, len(matchConfig.NameRules)),
-		0,
-		matchConfig,
+		ValueMatches:    newMatchStats(len(matchConfig.RegexRules)),
+		TokenMatches:    newMatchStats(len(matchConfig.TokenRules)),
+		NameValues:      make([][]MatchLine, len(matchConfig.NameRules)),
+		MultiNameValues: make([][]MatchLine, len(matchConfig.MultiNameRules)),
+		PathCounts:      map[string]int{},
+		// The sample is only used as evidence, so a fixed seed is fine, and
+		// keeps scans of the same data reproducible.
+		rand:        rand.New(rand.NewSource(1)),
+		matchConfig: matchConfig,
+	}
+}
+
+// add records a value that matched a rule. valid is the part of the value
+// that passed the rule's validator, or empty if none did.
+func (s *matchStats) add(valid string, sampleSize int, rnd *rand.Rand) {
+	s.Matched++
+
+	if valid == "" {
+		return
+	}
+
+	s.Validated++
+
+	if len(s.Sample) < sampleSize {
+		s.Sample = append(s.Sample, valid)
+		return
+	}
+
+	if i := rnd.Intn(s.Validated); i < sampleSize {
+		s.Sample[i] = valid
 	}
 }
 
+// mergeStats combines the matches of a rule at every path.
+func mergeStats(byPath map[string]*matchStats) *matchStats {
+	res := &matchStats{}
+
+	for _, s := range byPath {
+		res.Matched += s.Matched
+		res.Validated += s.Validated
+		res.Sample = append(res.Sample, s.Sample...)
+	}
+
+	return res
+}
+
 func (m *MatchFinder) CheckMatches(colIdentifier string, schemaName string, schemaGroup *string) []scanner.RuleMatch {
 	// TODO: handle onlyvalues?
 	matchList := []scanner.RuleMatch{}
 
-	matchedValues := m.MatchedValues
 	count := m.Count
 
 	for i, rule := range m.matchConfig.RegexRules {
-		matchedData := []string{}
-		for _, v := range matchedValues[i] {
-			matchedData = append(matchedData, v.Line)
+		if match, ok := m.valueMatch(rule, mergeStats(m.ValueMatches[i]), count); ok {
+			match.SchemaName = schemaName
+			match.SchemaGroup = schemaGroup
+			match.Identifier = colIdentifier
+			matchList = append(matchList, match)
 		}
+	}
 
-		if rule.Name == "email" {
-			// filter out false positives with URL credentials
-			newMatchedData := matchedData
-			matchedData = []string{}
-			for _, v := range newMatchedData {
-				v2 := urlPassword.ReplaceAllString(v, "[FILTERED]")
-				if rule.Regex.MatchString(v2) {
-					matchedData = append(matchedData, v)
-				}
-			}
+	for i, rule := range m.matchConfig.TokenRules {
+		if match, ok := m.tokenMatch(rule, mergeStats(m.TokenMatches[i]), count); ok {
+			match.SchemaName = schemaName
+			match.SchemaGroup = schemaGroup
+			match.Identifier = colIdentifier
+			matchList = append(matchList, match)
 		}
+	}
 
-		if len(matchedData) >= m.matchConfig.MinCount {
-			confidence := rule.Confidence
-
-			if confidence == "" {
-				if float64(len(matchedData))/float64(count) > 0.5 {
-					confidence = "high"
-				} else {
-					confidence = "low"
-				}
-			}
-			lineCount := len(matchedData)
+	return matchList
+}
 
-			matchList = append(matchList, scanner.RuleMatch{SchemaName: schemaName, SchemaGroup: schemaGroup, RuleName: rule.Name, DisplayName: rule.DisplayName, Confidence: confidence, Identifier: colIdentifier, MatchedData: matchedData, LineCount: lineCount, MatchType: "value"})
+// valueMatch summarizes the values that matched a regex rule, out of count
+// values scanned. Only values that passed validation are reported, and
+// the share that did sets the confidence of rules with a validator. It
+// returns false if not enough values validated.
+func (m *MatchFinder) valueMatch(rule regexRule, stats *matchStats, count int) (scanner.RuleMatch, bool) {
+	if stats.Matched == 0 || stats.Validated < m.matchConfig.MinCount {
+		return scanner.RuleMatch{}, false
+	}
 
+	confidence := rule.Confidence
+	if confidence == "" {
+		switch {
+		case rule.Validator != nil:
+			confidence = validationConfidence(stats.Validated, stats.Matched)
+		case count > 0 && float64(stats.Matched)/float64(count) > 0.5:
+			confidence = "high"
+		default:
+			confidence = "low"
 		}
+	}
+
+	return scanner.RuleMatch{
+		RuleName:       rule.Name,
+		DisplayName:    rule.DisplayName,
+		Category:       ruleCategory(rule.Name, rule.Category),
+		Confidence:     confidence,
+		Samples:        maskedSamples(stats.Sample),
+		LineCount:      stats.Matched,
+		ValidatedCount: stats.Validated,
+		ValueCount:     count,
+		Score:          ratioScore(stats.Validated, count, 1),
+		MatchType:      "value",
+	}, true
+}
 
+// tokenMatch summarizes the values that contain one of a token rule's
+// tokens, out of count values scanned. Dictionaries like surnames match a
+// few values of most text columns, so it returns false unless at least the
+// rule's MinRatio of the values matched.
+func (m *MatchFinder) tokenMatch(rule tokenRule, stats *matchStats, count int) (scanner.RuleMatch, bool) {
+	if stats.Matched < m.matchConfig.MinCount || count == 0 ||
+		float64(stats.Matched)/float64(count) < rule.MinRatio {
+		return scanner.RuleMatch{}, false
 	}
 
-	for i, rule := range m.matchConfig.TokenRules {
-		matchedData := []string{}
-		for _, v := range m.TokenValues[i] {
-			matchedData = append(matchedData, v.Line)
+	// The number of distinct tokens is estimated from the sample.
+	confidence := "low"
+	if len(unique(stats.Sample)) >= 10 {
+		confidence = "high"
+	}
+
+	return scanner.RuleMatch{
+		RuleName:       rule.Name,
+		DisplayName:    rule.DisplayName,
+		Category:       rule.Name,
+		Confidence:     confidence,
+		Samples:        maskedSamples(stats.Sample),
+		LineCount:      stats.Matched,
+		ValidatedCount: stats.Validated,
+		ValueCount:     count,
+		Score:          ratioScore(stats.Matched, count, tokenSaturation),
+		MatchType:      "token",
+	}, true
+}
+
+// contextSize is the length of the text before a match that is passed to
+// a rule's RejectContext.
+const contextSize = 16
+
+// firstValidMatch returns the first of the rule's matches in v that
+// passes its validator and context check. URL credentials are filtered out
+// first, so e.g. the password in a connection string isn't reported as an
+// email.
+func firstValidMatch(rule regexRule, v string) string {
+	filtered := urlPassword.ReplaceAllString(v, "[FILTERED]")
+
+	for _, loc := range rule.Regex.FindAllStringIndex(filtered, -1) {
+		match := filtered[loc[0]:loc[1]]
+		if rule.Validator != nil && !rule.Validator(match) {
+			continue
 		}
 
-		if len(matchedData) >= m.matchConfig.MinCount {
-			confidence := "low"
-			if float64(len(matchedData))/float64(count) > 0.1 && len(unique(matchedData)) >= 10 {
-				confidence = "high"
+		if rule.RejectContext != nil {
+			start := loc[0] - contextSize
+			if start < 0 {
+				start = 0
 			}
 
-			lineCount := len(matchedData)
-			matchList = append(matchList, scanner.RuleMatch{
-				RuleName:    rule.Name,
-				DisplayName: rule.DisplayName,
-				Confidence:  confidence,
-				Identifier:  colIdentifier,
-				MatchedData: matchedData,
-				LineCount:   lineCount,
-				MatchType:   "value",
-			})
+			if rule.RejectContext(filtered[start:loc[0]]) {
+				continue
+			}
 		}
+
+		return match
 	}
 
-	return matchList
+	return ""
+}
+
+// firstToken returns the first of v's tokens that's in the rule's
+// dictionary.
+func firstToken(rule tokenRule, v string) string {
+	for _, t := range tokenizer.Split(strings.ToLower(v), -1) {
+		if rule.Tokens.Contains(t) {
+			return t
+		}
+	}
+
+	return ""
 }
 
 func pathToString(path []string) string {
-	return strings.Join(path[:], ".")
+	return scanner.PathString(path)
+}
+
+// stats returns the matches of a rule at path, creating them if needed.
+func stats(byPath map[string]*matchStats, path string) *matchStats {
+	s, ok := byPath[path]
+	if !ok {
+		s = &matchStats{}
+		byPath[path] = s
+	}
+
+	return s
 }
 
 func (m *MatchFinder) ScanString(v string, path []string) {
+	p := pathToString(path)
+
 	for i, rule := range m.matchConfig.RegexRules {
 		if rule.Regex.MatchString(v) {
-			m.MatchedValues[i] = append(m.MatchedValues[i], MatchLine{Path: pathToString(path), Line: v})
+			stats(m.ValueMatches[i], p).add(firstValidMatch(rule, v), m.matchConfig.SampleSize, m.rand)
 		}
 	}
 
-	if len(m.matchConfig.TokenRules) > 0 {
-		tokens := tokenizer.Split(strings.ToLower(v), -1)
-		for i, rule := range m.matchConfig.TokenRules {
-			if anyMatches(rule, tokens) {
-				m.TokenValues[i] = append(m.TokenValues[i], MatchLine{Path: pathToString(path), Line: v})
-			}
+	for i, rule := range m.matchConfig.TokenRules {
+		if token := firstToken(rule, v); token != "" {
+			stats(m.TokenMatches[i], p).add(token, m.matchConfig.SampleSize, m.rand)
 		}
 	}
 
+	m.PathCounts[p]++
 	m.Count++
 }
@@ -0,0 +1,160 @@
+package basicscanner
+
+import (
+	"regexp"
+
+	"github.com/monoid-privacy/monoid/scanner"
+)
+
+// rulePack is a set of optional rules, which are enabled per workspace.
+type rulePack struct {
+	scanner.RulePack
+	RegexRules []regexRule
+	NameRules  []nameRule
+}
+
+var rulePacks = []rulePack{
+	{
+		RulePack: scanner.RulePack{
+			ID:          "eu",
+			Name:        "European Union",
+			Description: "IBANs, EU VAT numbers and European postal codes.",
+		},
+		RegexRules: []regexRule{
+			{Name: "iban", DisplayName: "IBANs", Category: "bank_account", Validator: validIBAN, Regex: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`)},
+			{Name: "eu_vat", DisplayName: "EU VAT numbers", Category: "tax_id", Regex: regexp.MustCompile(`\b(?:ATU\d{8}|BE[01]\d{9}|BG\d{9,10}|CY\d{8}[A-Z]|CZ\d{8,10}|DE\d{9}|DK\d{8}|EE\d{9}|EL\d{9}|ES[A-Z0-9]\d{7}[A-Z0-9]|FI\d{8}|FR[A-HJ-NP-Z0-9]{2}\d{9}|HR\d{11}|HU\d{8}|IE\d{7}[A-W][A-I]?|IT\d{11}|LT(?:\d{9}|\d{12})|LU\d{8}|LV\d{11}|MT\d{8}|NL\d{9}B\d{2}|PL\d{10}|PT\d{9}|RO\d{2,10}|SE\d{12}|SI\d{8}|SK\d{10})\b`)},
+		},
+		NameRules: []nameRule{
+			{Name: "postal_code", DisplayName: "postal codes", Category: "postal_code", ColumnNames: []string{"postcode", "plz", "postleitzahl", "codepostal", "codigopostal"}},
+		},
+	},
+	{
+		RulePack: scanner.RulePack{
+			ID:          "uk",
+			Name:        "United Kingdom",
+			Description: "National Insurance numbers, UK VAT numbers and postcodes.",
+		},
+		RegexRules: []regexRule{
+			{Name: "uk_nino", DisplayName: "National Insurance numbers", Category: "national_id", Validator: validNINO, Regex: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`)},
+			{Name: "uk_vat", DisplayName: "UK VAT numbers", Category: "tax_id", Regex: regexp.MustCompile(`\b(?:GB|XI)\d{9}(?:\d{3})?\b`)},
+			{Name: "uk_postcode", DisplayName: "UK postcodes", Category: "postal_code", Regex: regexp.MustCompile(`\b(?:[A-PR-UWYZ][A-HK-Y]?\d[A-Z\d]? ?\d[ABD-HJLNP-UW-Z]{2}|GIR ?0AA)\b`)},
+		},
+		NameRules: []nameRule{
+			{Name: "uk_nino", DisplayName: "National Insurance numbers", Category: "national_id", ColumnNames: []string{"nino", "nationalinsurancenumber", "ninumber"}},
+			{Name: "postal_code", DisplayName: "postal codes", Category: "postal_code", ColumnNames: []string{"postcode"}},
+		},
+	},
+	{
+		RulePack: scanner.RulePack{
+			ID:          "de",
+			Name:        "Germany",
+			Description: "Tax identification numbers (Steuer-ID).",
+		},
+		RegexRules: []regexRule{
+			{Name: "de_steuer_id", DisplayName: "German tax ids", Category: "tax_id", Validator: validSteuerID, Regex: regexp.MustCompile(`\b[1-9]\d(?: ?\d{3}){3}\b`)},
+		},
+		NameRules: []nameRule{
+			{Name: "de_steuer_id", DisplayName: "German tax ids", Category: "tax_id", ColumnNames: []string{"steuerid", "steueridentifikationsnummer", "idnr"}},
+		},
+	},
+	{
+		RulePack: scanner.RulePack{
+			ID:          "fr",
+			Name:        "France",
+			Description: "Social security numbers (NIR).",
+		},
+		RegexRules: []regexRule{
+			{Name: "fr_nir", DisplayName: "French social security numbers", Category: "national_id", Validator: validNIR, Regex: regexp.MustCompile(`\b[12] ?\d{2} ?(?:0[1-9]|1[0-2]|[2-9]\d) ?(?:\d{2}|2[AB]) ?\d{3} ?\d{3} ?\d{2}\b`)},
+		},
+		NameRules: []nameRule{
+			{Name: "fr_nir", DisplayName: "French social security numbers", Category: "national_id", ColumnNames: []string{"nir", "numerosecu", "numerosecuritesociale"}},
+		},
+	},
+	{
+		RulePack: scanner.RulePack{
+			ID:          "in",
+			Name:        "India",
+			Description: "Aadhaar numbers, PANs and PIN codes.",
+		},
+		RegexRules: []regexRule{
+			{Name: "in_aadhaar", DisplayName: "Aadhaar numbers", Category: "national_id", Validator: validAadhaar, Regex: regexp.MustCompile(`\b[2-9]\d{3} ?\d{4} ?\d{4}\b`)},
+			{Name: "in_pan", DisplayName: "PANs", Category: "tax_id", Regex: regexp.MustCompile(`\b[A-Z]{3}[ABCFGHJLPT][A-Z]\d{4}[A-Z]\b`)},
+		},
+		NameRules: []nameRule{
+			{Name: "in_aadhaar", DisplayName: "Aadhaar numbers", Category: "national_id", ColumnNames: []string{"aadhaar", "aadhaarnumber"}},
+			{Name: "in_pan", DisplayName: "PANs", Category: "tax_id", ColumnNames: []string{"pan", "pannumber"}},
+			{Name: "postal_code", DisplayName: "postal codes", Category: "postal_code", ColumnNames: []string{"pincode"}},
+		},
+	},
+	{
+		RulePack: scanner.RulePack{
+			ID:          "global",
+			Name:        "International",
+			Description: "Passport machine readable zones, international (E.164) phone numbers, IPv6 addresses and Canadian postal codes.",
+		},
+		RegexRules: []regexRule{
+			{Name: "passport_mrz", DisplayName: "passport numbers", Category: "passport_number", Validator: validPassportMRZ, Regex: regexp.MustCompile(`[A-Z0-9<]{9}\d[A-Z<]{3}\d{6}\d[MFX<]\d{6}\d[A-Z0-9<]{14}[0-9<]\d`)},
+			{Name: "phone_e164", DisplayName: "phone numbers", Category: "phone", Validator: validE164, Regex: regexp.MustCompile(`(?:\+|%2B)[1-9]\d{0,2}(?:[ .-]?\d{1,4}){2,5}\b`)},
+			{Name: "ipv6", DisplayName: "IPv6 addresses", Category: "ip", Validator: validIPv6, Regex: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`)},
+			{Name: "ca_postal_code", DisplayName: "Canadian postal codes", Category: "postal_code", Regex: regexp.MustCompile(`\b[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d\b`)},
+		},
+		NameRules: []nameRule{
+			{Name: "passport_number", DisplayName: "passport numbers", Category: "passport_number", ColumnNames: []string{"passport", "passportnumber", "passportno"}},
+		},
+	},
+}
+
+// RulePacks returns the optional rule packs that can be enabled.
+func RulePacks() []scanner.RulePack {
+	res := make([]scanner.RulePack, 0, len(rulePacks))
+
+	for _, p := range rulePacks {
+		pack := p.RulePack
+		pack.Categories = p.categories()
+		res = append(res, pack)
+	}
+
+	return res
+}
+
+// LookupRulePack returns the rule pack with the given id.
+func LookupRulePack(id string) (scanner.RulePack, bool) {
+	p, ok := findRulePack(id)
+	if !ok {
+		return scanner.RulePack{}, false
+	}
+
+	pack := p.RulePack
+	pack.Categories = p.categories()
+
+	return pack, true
+}
+
+func findRulePack(id string) (rulePack, bool) {
+	for _, p := range rulePacks {
+		if p.ID == id {
+			return p, true
+		}
+	}
+
+	return rulePack{}, false
+}
+
+// categories returns the categories the pack's rules report.
+func (p rulePack) categories() []string {
+	res := []string{}
+
+	for _, r := range p.RegexRules {
+		if !stringInSlice(r.Category, res) {
+			res = append(res, r.Category)
+		}
+	}
+
+	for _, r := range p.NameRules {
+		if !stringInSlice(r.Category, res) {
+			res = append(res, r.Category)
+		}
+	}
+
+	return res
+}
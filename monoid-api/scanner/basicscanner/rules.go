@@ -9,6 +9,8 @@ import (
 type nameRule struct {
 	Name        string
 	DisplayName string
+	Category    string
+	Confidence  string
 	ColumnNames []string
 }
 
@@ -21,14 +23,24 @@ type multiNameRule struct {
 type regexRule struct {
 	Name        string
 	DisplayName string
+	Category    string
 	Confidence  string
 	Regex       *regexp.Regexp
+	Validator   validator
+
+	// RejectContext returns true if the text before a match shows that
+	// it isn't what the rule looks for.
+	RejectContext func(before string) bool
 }
 
 type tokenRule struct {
 	Name        string
 	DisplayName string
 	Tokens      mapset.Set
+
+	// MinRatio is the share of a column's values that must contain one of
+	// the tokens for the rule to match.
+	MinRatio float64
 }
 
 // Credit to github.com/ankane/pdscan for many of the rules and setup
@@ -52,19 +64,17 @@ var multiNameRules = []multiNameRule{
 	{Name: "location", DisplayName: "location data", ColumnNames: [][]string{{"latitude", "lat"}, {"longitude", "lon", "lng"}}},
 }
 
-// TODO IPv6
+// IPv6 and international identifiers are in the optional rule packs.
 // TODO more popular access tokens
 var regexRules = []regexRule{
-	{Name: "email", DisplayName: "emails", Confidence: "high", Regex: regexp.MustCompile(`\b[\w][\w+.-]+(@|%40)[a-z\d-]+(\.[a-z\d-]+)*\.[a-z]+\b`)},
-	// TODO make high confidence
-	{Name: "ip", DisplayName: "IP addresses", Regex: regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)},
-	{Name: "credit_card", DisplayName: "credit card numbers", Regex: regexp.MustCompile(`(\b[3456]\d{3}[\s+-]\d{4}[\s+-]\d{4}[\s+-]\d{4}\b)|(\b[3456]\d{15}\b)`)},
+	{Name: "email", DisplayName: "emails", Validator: validEmail, Regex: regexp.MustCompile(`\b[\w][\w+.-]+(@|%40)[a-z\d-]+(\.[a-z\d-]+)*\.[a-z]+\b`)},
+	{Name: "ip", DisplayName: "IP addresses", Validator: validIPv4, RejectContext: versionContext, Regex: regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)},
+	{Name: "credit_card", DisplayName: "credit card numbers", Validator: validCreditCard, Regex: regexp.MustCompile(`(\b[3456]\d{3}[\s+-]\d{4}[\s+-]\d{4}[\s+-]\d{4}\b)|(\b[3456]\d{15}\b)`)},
 	{Name: "phone", DisplayName: "phone numbers", Regex: regexp.MustCompile(`(\b(\+\d{1,2}\s)?\(?\d{3}\)?[\s+.-]\d{3}[\s+.-]\d{4}\b)|((?:\+|%2B)[1-9]\d{6,14}\b)`)},
-	{Name: "ssn", DisplayName: "SSNs", Regex: regexp.MustCompile(`\b\d{3}[\s+-]\d{2}[\s+-]\d{4}\b`)},
+	{Name: "ssn", DisplayName: "SSNs", Validator: validSSN, Regex: regexp.MustCompile(`\b\d{3}[\s+-]\d{2}[\s+-]\d{4}\b`)},
 	{Name: "street", DisplayName: "street addresses", Regex: regexp.MustCompile(`(?i)\b\d+\b.{4,60}\b(st|street|ave|avenue|road|rd|drive|dr)\b`)},
 	{Name: "oauth_token", DisplayName: "OAuth tokens", Regex: regexp.MustCompile(`ya29\..{60,200}`)}, // google
-	// TODO make high confidence
-	{Name: "mac", DisplayName: "MAC addresses", Regex: regexp.MustCompile(`\b[0-9a-fA-F]{2}(?:(?::|%3A)[0-9a-fA-F]{2}){5}\b`)},
+	{Name: "mac", DisplayName: "MAC addresses", Validator: validMAC, Regex: regexp.MustCompile(`\b[0-9a-fA-F]{2}(?:(?::|%3A)[0-9a-fA-F]{2}){5}\b`)},
 }
 
 // first 300 from 2010 US Census https://www.census.gov/topics/population/genealogy/data/2010_surnames.html
@@ -72,5 +82,5 @@ var regexRules = []regexRule{
 // TODO: more surnames?
 var lastNames = []interface{}{"smith", "johnson", "williams", "brown", "jones", "garcia", "miller", "davis", "rodriguez", "martinez", "hernandez", "lopez", "gonzalez", "wilson", "anderson", "thomas", "taylor", "moore", "jackson", "martin", "lee", "perez", "thompson", "white", "harris", "sanchez", "clark", "ramirez", "lewis", "robinson", "walker", "young", "allen", "king", "wright", "scott", "torres", "nguyen", "hill", "flores", "green", "adams", "nelson", "baker", "hall", "rivera", "campbell", "mitchell", "carter", "roberts", "gomez", "phillips", "evans", "turner", "diaz", "parker", "cruz", "edwards", "collins", "reyes", "stewart", "morris", "morales", "murphy", "cook", "rogers", "gutierrez", "ortiz", "morgan", "cooper", "peterson", "bailey", "reed", "kelly", "howard", "ramos", "kim", "cox", "ward", "richardson", "watson", "brooks", "chavez", "wood", "james", "bennett", "gray", "mendoza", "ruiz", "hughes", "price", "alvarez", "castillo", "sanders", "patel", "myers", "long", "ross", "foster", "jimenez", "powell", "jenkins", "perry", "russell", "sullivan", "bell", "coleman", "butler", "henderson", "barnes", "gonzales", "fisher", "vasquez", "simmons", "romero", "jordan", "patterson", "alexander", "hamilton", "graham", "reynolds", "griffin", "wallace", "moreno", "west", "cole", "hayes", "bryant", "herrera", "gibson", "ellis", "tran", "medina", "aguilar", "stevens", "murray", "ford", "castro", "marshall", "owens", "harrison", "fernandez", "mcdonald", "woods", "washington", "kennedy", "wells", "vargas", "henry", "chen", "freeman", "webb", "tucker", "guzman", "burns", "crawford", "olson", "simpson", "porter", "hunter", "gordon", "mendez", "silva", "shaw", "snyder", "mason", "dixon", "munoz", "hunt", "hicks", "holmes", "palmer", "wagner", "black", "robertson", "boyd", "rose", "stone", "salazar", "fox", "warren", "mills", "meyer", "rice", "schmidt", "garza", "daniels", "ferguson", "nichols", "stephens", "soto", "weaver", "ryan", "gardner", "payne", "grant", "dunn", "kelley", "spencer", "hawkins", "arnold", "pierce", "vazquez", "hansen", "peters", "santos", "hart", "bradley", "knight", "elliott", "cunningham", "duncan", "armstrong", "hudson", "carroll", "lane", "riley", "andrews", "alvarado", "ray", "delgado", "berry", "perkins", "hoffman", "johnston", "matthews", "pena", "richards", "contreras", "willis", "carpenter", "lawrence", "sandoval", "guerrero", "george", "chapman", "rios", "estrada", "ortega", "watkins", "greene", "nunez", "wheeler", "valdez", "harper", "burke", "larson", "santiago", "maldonado", "morrison", "franklin", "carlson", "austin", "dominguez", "carr", "lawson", "jacobs", "obrien", "lynch", "singh", "vega", "bishop", "montgomery", "oliver", "jensen", "harvey", "williamson", "gilbert", "dean", "sims", "espinoza", "howell", "li", "wong", "reid", "hanson", "le", "mccoy", "garrett", "burton", "fuller", "wang", "weber", "welch", "rojas", "lucas", "marquez", "fields", "park", "yang", "little", "banks", "padilla", "day", "walsh", "bowman", "schultz", "luna", "fowler", "mejia"}
 var tokenRules = []tokenRule{
-	{Name: "surname", DisplayName: "last names", Tokens: mapset.NewSetFromSlice(lastNames)},
+	{Name: "surname", DisplayName: "last names", Tokens: mapset.NewSetFromSlice(lastNames), MinRatio: 0.1},
 }
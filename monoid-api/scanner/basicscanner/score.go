@@ -0,0 +1,32 @@
+package basicscanner
+
+// nameScores are the scores of column name matches, by the confidence of
+// the rule. A name is weaker evidence than the values themselves, so a
+// name match alone never scores as highly as a column of valid values.
+var nameScores = map[string]float64{
+	"low":    0.3,
+	"medium": 0.5,
+	"high":   0.7,
+}
+
+// tokenSaturation is the share of a column's values that must contain a
+// token for the token rule to be conclusive. Dictionaries only cover some
+// of the possible values (e.g. the surname list covers about 30% of the
+// US population), so they can't be expected to match every value.
+const tokenSaturation = 0.3
+
+// ratioScore scores value evidence by the share of count values that
+// matched (and passed validation), relative to the share at which the
+// evidence is conclusive.
+func ratioScore(matched int, count int, saturation float64) float64 {
+	if count == 0 {
+		return 0
+	}
+
+	score := float64(matched) / float64(count) / saturation
+	if score > 1 {
+		return 1
+	}
+
+	return score
+}
@@ -0,0 +1,468 @@
+//go:build ignore
+
+// gen_corpus generates the labelled corpus that the accuracy test scans.
+// The corpus is checked in, so it only needs to be regenerated when a
+// table is added or changed:
+//
+//	go run testdata/gen_corpus.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const rowsPerTable = 100
+
+// table is a table in the corpus. Labels has the categories of every
+// property (path) in the schema, with an empty list for properties that
+// don't have PII.
+type table struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	RulePacks   []string                 `json:"rulePacks,omitempty"`
+	Labels      map[string][]string      `json:"labels"`
+	Schema      map[string]interface{}   `json:"schema"`
+	Records     []map[string]interface{} `json:"records"`
+}
+
+// column generates the values of a property.
+type column struct {
+	name   string
+	typ    string
+	labels []string
+	value  func(r *rand.Rand, i int) interface{}
+}
+
+var (
+	firstNames = []string{"jane", "john", "maria", "wei", "aisha", "lucas", "sofia", "omar", "emma", "raj", "yuki", "liam", "olga", "kofi", "ines"}
+
+	// Common surnames are in the scanner's dictionary, rare ones aren't.
+	commonSurnames = []string{"smith", "johnson", "garcia", "nguyen", "patel", "kim", "brown", "lopez", "wilson", "chen", "walker", "young"}
+	rareSurnames   = []string{"okafor", "lindqvist", "kowalczyk", "haddad", "tanaka", "oyelaran", "vukovic", "achterberg"}
+
+	domains = []string{"example.com", "mail.example.org", "corp.example.net", "example.co.uk"}
+	streets = []string{"Main St", "Oak Avenue", "Elm Street", "Pine Rd", "Maple Drive", "Cedar Ave"}
+	words   = []string{"the", "order", "was", "delayed", "customer", "asked", "about", "refund", "shipping", "update", "please", "thanks", "issue", "resolved", "account", "invoice", "white", "green", "young"}
+	skus    = []string{"SKU-1042", "SKU-2210", "SKU-7781", "SKU-3305"}
+)
+
+func pick(r *rand.Rand, list []string) string {
+	return list[r.Intn(len(list))]
+}
+
+func surname(r *rand.Rand) string {
+	if r.Float64() < 0.7 {
+		return pick(r, commonSurnames)
+	}
+
+	return pick(r, rareSurnames)
+}
+
+func email(r *rand.Rand, i int) string {
+	return fmt.Sprintf("%s.%s%d@%s", pick(r, firstNames), surname(r), i, pick(r, domains))
+}
+
+func phone(r *rand.Rand) string {
+	switch r.Intn(3) {
+	case 0:
+		return fmt.Sprintf("(%03d) %03d-%04d", 200+r.Intn(700), r.Intn(1000), r.Intn(10000))
+	case 1:
+		return fmt.Sprintf("%03d-%03d-%04d", 200+r.Intn(700), r.Intn(1000), r.Intn(10000))
+	}
+
+	return fmt.Sprintf("+1 %03d.%03d.%04d", 200+r.Intn(700), r.Intn(1000), r.Intn(10000))
+}
+
+func digits(r *rand.Rand, n int) string {
+	b := strings.Builder{}
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte('0' + r.Intn(10)))
+	}
+
+	return b.String()
+}
+
+// luhnDigit returns the check digit that makes s+digit pass the Luhn
+// check.
+func luhnDigit(s string) byte {
+	sum := 0
+
+	for i := 0; i < len(s); i++ {
+		d := int(s[len(s)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+func creditCard(r *rand.Rand) string {
+	prefix := pick(r, []string{"4", "51", "55"})
+	n := prefix + digits(r, 15-len(prefix))
+	n += string(luhnDigit(n))
+
+	if r.Intn(2) == 0 {
+		return n[0:4] + " " + n[4:8] + " " + n[8:12] + " " + n[12:16]
+	}
+
+	return n
+}
+
+// invalidCard returns a 16 digit number that fails the Luhn check.
+func invalidCard(r *rand.Rand) string {
+	n := "4" + digits(r, 14)
+	check := luhnDigit(n)
+
+	return n + string('0'+(check-'0'+1)%10)
+}
+
+func ssn(r *rand.Rand) string {
+	area := 1 + r.Intn(665)
+	if area == 666 {
+		area = 667
+	}
+
+	return fmt.Sprintf("%03d-%02d-%04d", area, 1+r.Intn(99), 1+r.Intn(9999))
+}
+
+func ipv4(r *rand.Rand) string {
+	return fmt.Sprintf("%d.%d.%d.%d", 1+r.Intn(222), r.Intn(256), r.Intn(256), 1+r.Intn(254))
+}
+
+func mac(r *rand.Rand) string {
+	parts := make([]string, 6)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%02x", r.Intn(256))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func hex(r *rand.Rand, n int) string {
+	const chars = "0123456789abcdef"
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[r.Intn(len(chars))]
+	}
+
+	return string(b)
+}
+
+func token(r *rand.Rand, n int) string {
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[r.Intn(len(chars))]
+	}
+
+	return string(b)
+}
+
+func sentence(r *rand.Rand, n int) string {
+	w := make([]string, n)
+	for i := range w {
+		w[i] = pick(r, words)
+	}
+
+	return strings.Join(w, " ")
+}
+
+// iban returns a valid German IBAN.
+func iban(r *rand.Rand) string {
+	bban := digits(r, 18)
+
+	// Move the country code and 00 to the end, and convert the letters
+	// (D=13, E=14).
+	n, _ := new(big.Int).SetString(bban+"131400", 10)
+	check := 98 - new(big.Int).Mod(n, big.NewInt(97)).Int64()
+
+	return fmt.Sprintf("DE%02d%s", check, bban)
+}
+
+func nino(r *rand.Rand) string {
+	return fmt.Sprintf("%s%s%s", pick(r, []string{"AB", "CE", "JK", "PR", "SW"}), digits(r, 6), pick(r, []string{"A", "B", "C", "D"}))
+}
+
+var (
+	verhoeffD = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6}, {3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8}, {5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2}, {7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4}, {9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffP = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2}, {8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0}, {4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5}, {7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+	verhoeffInv = [10]int{0, 4, 3, 2, 1, 5, 6, 7, 8, 9}
+)
+
+// aadhaar returns a valid Aadhaar number, formatted in groups of four.
+func aadhaar(r *rand.Rand) string {
+	n := strconv.Itoa(2+r.Intn(8)) + digits(r, 10)
+
+	c := 0
+	for i := 0; i < len(n); i++ {
+		c = verhoeffD[c][verhoeffP[(i+1)%8][int(n[len(n)-1-i]-'0')]]
+	}
+
+	n += strconv.Itoa(verhoeffInv[c])
+
+	return n[0:4] + " " + n[4:8] + " " + n[8:12]
+}
+
+func maybe(r *rand.Rand, p float64, v func() interface{}, otherwise interface{}) interface{} {
+	if r.Float64() < p {
+		return v()
+	}
+
+	return otherwise
+}
+
+var tables = []struct {
+	name        string
+	description string
+	rulePacks   []string
+	columns     []column
+}{
+	{
+		name:        "customers",
+		description: "A flat customer table with common PII columns, and columns with similar looking values.",
+		columns: []column{
+			{name: "id", typ: "integer", value: func(r *rand.Rand, i int) interface{} { return i + 1 }},
+			{name: "email", typ: "string", labels: []string{"email"}, value: func(r *rand.Rand, i int) interface{} { return email(r, i) }},
+			{name: "last_name", typ: "string", labels: []string{"surname"}, value: func(r *rand.Rand, i int) interface{} { return strings.Title(surname(r)) }},
+			{name: "phone", typ: "string", labels: []string{"phone"}, value: func(r *rand.Rand, i int) interface{} { return phone(r) }},
+			{name: "dob", typ: "string", labels: []string{"date_of_birth"}, value: func(r *rand.Rand, i int) interface{} {
+				return fmt.Sprintf("%d-%02d-%02d", 1940+r.Intn(65), 1+r.Intn(12), 1+r.Intn(28))
+			}},
+			{name: "zip", typ: "string", labels: []string{"postal_code"}, value: func(r *rand.Rand, i int) interface{} { return digits(r, 5) }},
+			{name: "created_at", typ: "string", value: func(r *rand.Rand, i int) interface{} {
+				return fmt.Sprintf("2022-%02d-%02dT%02d:%02d:00Z", 1+r.Intn(12), 1+r.Intn(28), r.Intn(24), r.Intn(60))
+			}},
+			{name: "status", typ: "string", value: func(r *rand.Rand, i int) interface{} { return pick(r, []string{"active", "inactive", "pending"}) }},
+			{name: "notes", typ: "string", value: func(r *rand.Rand, i int) interface{} { return sentence(r, 8) }},
+		},
+	},
+	{
+		name:        "payments",
+		description: "Card payments, with card numbers and numbers that look like them.",
+		columns: []column{
+			{name: "card_number", typ: "string", labels: []string{"credit_card"}, value: func(r *rand.Rand, i int) interface{} { return creditCard(r) }},
+			{name: "card_last4", typ: "string", value: func(r *rand.Rand, i int) interface{} { return digits(r, 4) }},
+			{name: "order_ref", typ: "string", value: func(r *rand.Rand, i int) interface{} { return invalidCard(r) }},
+			{name: "amount", typ: "number", value: func(r *rand.Rand, i int) interface{} { return float64(r.Intn(100000)) / 100 }},
+			{name: "billing_address", typ: "string", labels: []string{"street"}, value: func(r *rand.Rand, i int) interface{} {
+				return fmt.Sprintf("%d %s", 1+r.Intn(9999), pick(r, streets))
+			}},
+			{name: "client_ip", typ: "string", labels: []string{"ip"}, value: func(r *rand.Rand, i int) interface{} { return ipv4(r) }},
+			{name: "sku", typ: "string", value: func(r *rand.Rand, i int) interface{} { return pick(r, skus) }},
+		},
+	},
+	{
+		name:        "devices",
+		description: "Device and session data, with identifiers that look like PII.",
+		columns: []column{
+			{name: "user_id", typ: "integer", labels: []string{"user_id"}, value: func(r *rand.Rand, i int) interface{} { return 1000 + r.Intn(100000) }},
+			{name: "mac_address", typ: "string", labels: []string{"mac"}, value: func(r *rand.Rand, i int) interface{} { return mac(r) }},
+			{name: "serial", typ: "string", value: func(r *rand.Rand, i int) interface{} { return hex(r, 12) }},
+			{name: "firmware", typ: "string", value: func(r *rand.Rand, i int) interface{} {
+				return fmt.Sprintf("v%d.%d.%d", r.Intn(5), r.Intn(20), r.Intn(100))
+			}},
+			{name: "lat", typ: "number", labels: []string{"location"}, value: func(r *rand.Rand, i int) interface{} { return float64(r.Intn(180000000))/1e6 - 90 }},
+			{name: "lng", typ: "number", labels: []string{"location"}, value: func(r *rand.Rand, i int) interface{} { return float64(r.Intn(360000000))/1e6 - 180 }},
+			{name: "access_token", typ: "string", labels: []string{"oauth_token"}, value: func(r *rand.Rand, i int) interface{} { return "ya29." + token(r, 120) }},
+			{name: "session_hash", typ: "string", value: func(r *rand.Rand, i int) interface{} { return hex(r, 64) }},
+		},
+	},
+	{
+		name:        "employees",
+		description: "HR records, with SSNs, invalid SSNs and phone numbers in free text.",
+		columns: []column{
+			{name: "employee_ssn", typ: "string", labels: []string{"ssn"}, value: func(r *rand.Rand, i int) interface{} { return ssn(r) }},
+			{name: "badge_code", typ: "string", value: func(r *rand.Rand, i int) interface{} {
+				// The area 000 and 9xx are never issued.
+				return fmt.Sprintf("%03d-%02d-%04d", 900+r.Intn(100), r.Intn(100), r.Intn(10000))
+			}},
+			{name: "home_contact", typ: "string", labels: []string{"phone"}, value: func(r *rand.Rand, i int) interface{} {
+				return "call " + phone(r) + " after 5pm"
+			}},
+			{name: "department", typ: "string", value: func(r *rand.Rand, i int) interface{} {
+				return pick(r, []string{"Sales", "Engineering", "Support", "Finance"})
+			}},
+			{name: "manager_email", typ: "string", labels: []string{"email"}, value: func(r *rand.Rand, i int) interface{} {
+				return maybe(r, 0.8, func() interface{} { return email(r, i) }, nil)
+			}},
+		},
+	},
+	{
+		name:        "international",
+		description: "European and Indian identifiers, scanned with the eu, uk and in rule packs.",
+		rulePacks:   []string{"eu", "uk", "in"},
+		columns: []column{
+			{name: "iban", typ: "string", labels: []string{"bank_account"}, value: func(r *rand.Rand, i int) interface{} { return iban(r) }},
+			{name: "vat_number", typ: "string", labels: []string{"tax_id"}, value: func(r *rand.Rand, i int) interface{} { return "DE" + digits(r, 9) }},
+			{name: "ni_number", typ: "string", labels: []string{"national_id"}, value: func(r *rand.Rand, i int) interface{} { return nino(r) }},
+			{name: "aadhaar", typ: "string", labels: []string{"national_id"}, value: func(r *rand.Rand, i int) interface{} { return aadhaar(r) }},
+			{name: "account_code", typ: "string", value: func(r *rand.Rand, i int) interface{} { return "AC" + digits(r, 10) }},
+		},
+	},
+}
+
+// nestedTickets generates support tickets with nested objects and arrays.
+func nestedTickets(r *rand.Rand) table {
+	t := table{
+		Name:        "tickets",
+		Description: "Nested support tickets, with PII in objects, arrays and free text.",
+		Labels: map[string][]string{
+			"id":                    {},
+			"requester.email":       {"email"},
+			"requester.last_name":   {"surname"},
+			"messages[].body":       {"email"},
+			"messages[].sent_at":    {},
+			"tags[]":                {},
+			"assignee.display_name": {},
+		},
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{"type": "integer"},
+				"requester": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"email":     map[string]interface{}{"type": "string"},
+						"last_name": map[string]interface{}{"type": "string"},
+					},
+				},
+				"assignee": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"display_name": map[string]interface{}{"type": "string"},
+					},
+				},
+				"messages": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"body":    map[string]interface{}{"type": "string"},
+							"sent_at": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+				"tags": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	}
+
+	for i := 0; i < rowsPerTable; i++ {
+		messages := []interface{}{}
+		for m := 0; m < 1+r.Intn(3); m++ {
+			body := sentence(r, 10)
+			if r.Float64() < 0.4 {
+				body += " reply to " + email(r, i)
+			}
+
+			messages = append(messages, map[string]interface{}{
+				"body":    body,
+				"sent_at": fmt.Sprintf("2023-%02d-%02dT10:00:00Z", 1+r.Intn(12), 1+r.Intn(28)),
+			})
+		}
+
+		t.Records = append(t.Records, map[string]interface{}{
+			"id": i + 1,
+			"requester": map[string]interface{}{
+				"email":     email(r, i),
+				"last_name": strings.Title(surname(r)),
+			},
+			"assignee": map[string]interface{}{
+				"display_name": pick(r, []string{"Support Bot", "Tier 2", "Billing Team"}),
+			},
+			"messages": messages,
+			"tags":     []interface{}{pick(r, []string{"billing", "bug", "question"})},
+		})
+	}
+
+	return t
+}
+
+func main() {
+	dir := filepath.Join("testdata", "corpus")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic(err)
+	}
+
+	r := rand.New(rand.NewSource(41))
+
+	out := []table{}
+
+	for _, spec := range tables {
+		t := table{
+			Name:        spec.name,
+			Description: spec.description,
+			RulePacks:   spec.rulePacks,
+			Labels:      map[string][]string{},
+		}
+
+		props := map[string]interface{}{}
+		for _, c := range spec.columns {
+			props[c.name] = map[string]interface{}{"type": c.typ}
+
+			labels := c.labels
+			if labels == nil {
+				labels = []string{}
+			}
+
+			t.Labels[c.name] = labels
+		}
+
+		t.Schema = map[string]interface{}{"type": "object", "properties": props}
+
+		for i := 0; i < rowsPerTable; i++ {
+			record := map[string]interface{}{}
+			for _, c := range spec.columns {
+				if v := c.value(r, i); v != nil {
+					record[c.name] = v
+				}
+			}
+
+			t.Records = append(t.Records, record)
+		}
+
+		out = append(out, t)
+	}
+
+	out = append(out, nestedTickets(r))
+
+	for _, t := range out {
+		b, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, t.Name+".json"), append(b, '\n'), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
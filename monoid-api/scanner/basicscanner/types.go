@@ -1,5 +1,7 @@
 package basicscanner
 
+import "math/rand"
+
 type ScanOpts struct {
 	Limit       int
 	MatchConfig *MatchConfig
@@ -11,6 +13,10 @@ type MatchConfig struct {
 	MultiNameRules []multiNameRule
 	TokenRules     []tokenRule
 	MinCount       int
+
+	// SampleSize is the number of matched values kept for each rule and
+	// path.
+	SampleSize int
 }
 
 type MatchLine struct {
@@ -18,11 +24,33 @@ type MatchLine struct {
 	Line string
 }
 
+// matchStats counts the values at a path that matched a rule. Only a fixed
+// size, uniform sample of the matched values is kept (reservoir sampling),
+// so memory use doesn't grow with the number of records scanned.
+type matchStats struct {
+	Matched   int
+	Validated int
+
+	// Sample is a sample of the parts of the values that matched and
+	// passed validation.
+	Sample []string
+}
+
 type MatchFinder struct {
+	// dims [# rules][path]
+	ValueMatches []map[string]*matchStats
+	TokenMatches []map[string]*matchStats
+
 	// dims [# rules][# matches]
-	MatchedValues [][]MatchLine
-	TokenValues   [][]MatchLine
-	NameValues    [][]MatchLine
-	Count         int
-	matchConfig   *MatchConfig
+	NameValues [][]MatchLine
+
+	// MultiNameValues are the columns that matched a multi-name rule. The
+	// line is the path of the object the columns are in.
+	MultiNameValues [][]MatchLine
+	Count           int
+
+	// PathCounts is the number of values scanned for each path.
+	PathCounts  map[string]int
+	rand        *rand.Rand
+	matchConfig *MatchConfig
 }
@@ -0,0 +1,99 @@
+package basicscanner
+
+import (
+	"testing"
+
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator
+		value     string
+		valid     bool
+	}{
+		{"card passes luhn", validCreditCard, "4111 1111 1111 1111", true},
+		{"card fails luhn", validCreditCard, "4111-1111-1111-1112", false},
+		{"card repeated digits", validCreditCard, "0000000000000000", false},
+		{"ssn", validSSN, "123-45-6789", true},
+		{"ssn area 666", validSSN, "666-45-6789", false},
+		{"ssn area 9xx", validSSN, "912-45-6789", false},
+		{"ssn group 00", validSSN, "123-00-6789", false},
+		{"ip", validIPv4, "192.168.1.20", true},
+		{"ip octet out of range", validIPv4, "300.1.1.1", false},
+		{"ip leading zero", validIPv4, "10.01.1.1", false},
+		{"ip placeholder", validIPv4, "0.0.0.0", false},
+		{"ip sequential octets", validIPv4, "1.2.3.4", true},
+		{"email", validEmail, "jane@example.com", true},
+		{"email asset name", validEmail, "logo@2x.png", false},
+		{"email numeric tld", validEmail, "a@b.123", false},
+		{"mac", validMAC, "00:1a:2b:3c:4d:5e", true},
+		{"mac broadcast", validMAC, "ff:ff:ff:ff:ff:ff", false},
+		{"iban", validIBAN, "GB82 WEST 1234 5698 7654 32", true},
+		{"iban bad checksum", validIBAN, "GB82 WEST 1234 5698 7654 33", false},
+		{"nino", validNINO, "AB 12 34 56 C", true},
+		{"nino unallocated prefix", validNINO, "GB123456A", false},
+		{"steuer id", validSteuerID, "86 095 742 719", true},
+		{"steuer id bad check digit", validSteuerID, "86095742718", false},
+		{"nir", validNIR, "1 84 12 76 451 089 46", true},
+		{"nir bad key", validNIR, "1 84 12 76 451 089 47", false},
+		{"aadhaar", validAadhaar, "2341 2341 2346", true},
+		{"aadhaar bad checksum", validAadhaar, "2341 2341 2345", false},
+		{"passport mrz", validPassportMRZ, "L898902C36UTO7408122F1204159ZE184226B<<<<<10", true},
+		{"passport mrz bad check digit", validPassportMRZ, "L898902C37UTO7408122F1204159ZE184226B<<<<<10", false},
+		{"e164", validE164, "+44 20 7946 0958", true},
+		{"e164 too short", validE164, "+1 234", false},
+		{"ipv6", validIPv6, "2001:db8::1", true},
+		{"ipv6 time", validIPv6, "12:30:45", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.valid, test.validator(test.value), test.name)
+	}
+}
+
+func TestVersionContext(t *testing.T) {
+	rule := regexRules[1]
+	assert.Equal(t, "ip", rule.Name)
+
+	for v, match := range map[string]string{
+		"client 1.2.3.4 connected":          "1.2.3.4",
+		"v1.2.3.4":                          "",
+		"build v10.0.19041.1":               "",
+		"Version: 4.2.1.0":                  "",
+		"ver=1.0.0.1":                       "",
+		"release 2.4.1.7 from 10.0.0.12":    "10.0.0.12",
+		"upgraded to v2.1.0.3 on 192.0.2.1": "192.0.2.1",
+		"v 1.2.3.4":                         "",
+		"dev 1.2.3.4":                       "1.2.3.4",
+	} {
+		assert.Equal(t, match, firstValidMatch(rule, v), v)
+	}
+}
+
+func TestSummaryValidatedCounts(t *testing.T) {
+	conf, err := NewMatchConfig(scanner.RuleConfig{})
+	assert.NoError(t, err)
+
+	finder := NewMatchFinder(&conf)
+	for _, v := range []string{"4111 1111 1111 1111", "5500 0000 0000 0004", "4111 1111 1111 1112"} {
+		finder.ScanString(v, []string{"card"})
+	}
+
+	s := BasicScanner{MatchFinder: finder, MatchConfig: &conf}
+
+	for _, m := range s.Summary() {
+		if m.RuleName != "credit_card" {
+			continue
+		}
+
+		assert.Equal(t, 3, m.LineCount)
+		assert.Equal(t, 2, m.ValidatedCount)
+		assert.Equal(t, "medium", m.Confidence)
+		return
+	}
+
+	t.Fatal("no credit card match")
+}
@@ -0,0 +1,203 @@
+package basicscanner
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/monoid-privacy/monoid/jsonschema"
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+)
+
+// valuePathWalker collects the paths to the scalar values of a schema.
+type valuePathWalker struct {
+	root  *jsonschema.Schema
+	paths []scanner.ValuePath
+	index map[string]int
+}
+
+// lookupRef resolves a local reference to one of the root schema's
+// definitions. Remote references aren't supported.
+func (w *valuePathWalker) lookupRef(ref string) *jsonschema.Schema {
+	if ref == "#" {
+		return w.root
+	}
+
+	if strings.HasPrefix(ref, "#/$defs/") {
+		return w.root.Definitions[strings.TrimPrefix(ref, "#/$defs/")]
+	}
+
+	if strings.HasPrefix(ref, "#/definitions/") {
+		return w.root.LegacyDefinitions[strings.TrimPrefix(ref, "#/definitions/")]
+	}
+
+	return nil
+}
+
+// add records a value path. Composite schemas can declare the same path
+// more than once; strings take precedence, since they're what get scanned.
+func (w *valuePathWalker) add(path []string, schema *jsonschema.Schema) {
+	key := scanner.PathString(path)
+
+	if i, ok := w.index[key]; ok {
+		if schema.Type == "string" {
+			w.paths[i].Type = schema.Type
+			w.paths[i].Schema = schema
+		}
+
+		return
+	}
+
+	w.index[key] = len(w.paths)
+	w.paths = append(w.paths, scanner.ValuePath{
+		Path:   path,
+		Type:   schema.Type,
+		Schema: schema,
+	})
+}
+
+// walk descends into schema, following references and merging the
+// properties of composite (allOf/anyOf/oneOf) schemas. refs are the
+// references that have been followed to get to schema, so recursive
+// definitions aren't expanded into themselves.
+func (w *valuePathWalker) walk(schema *jsonschema.Schema, path []string, refs []string) {
+	if schema == nil {
+		return
+	}
+
+	if schema.Ref != "" && !stringInSlice(schema.Ref, refs) {
+		w.walk(w.lookupRef(schema.Ref), path, appendPath(refs, schema.Ref))
+	}
+
+	for _, subs := range [][]*jsonschema.Schema{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for _, sub := range subs {
+			w.walk(sub, path, refs)
+		}
+	}
+
+	switch schema.Type {
+	case "string", "number", "integer":
+		if len(path) > 0 {
+			w.add(path, schema)
+		}
+	case "array":
+		w.walk(schema.Items, appendPath(path, scanner.ArraySegment), refs)
+	default:
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			w.walk(schema.Properties[name], appendPath(path, name), refs)
+		}
+	}
+}
+
+// appendPath returns a copy of path with segment appended, so sibling
+// paths (and reference lists) don't share a backing array.
+func appendPath(path []string, segment string) []string {
+	res := make([]string, len(path), len(path)+1)
+	copy(res, path)
+
+	return append(res, segment)
+}
+
+// GetValuePaths returns the paths to all the scalar values in schema,
+// including the values nested in arrays, references and composite
+// schemas.
+func GetValuePaths(schema jsonschema.Schema) []scanner.ValuePath {
+	w := valuePathWalker{
+		root:  &schema,
+		paths: []scanner.ValuePath{},
+		index: map[string]int{},
+	}
+
+	w.walk(&schema, []string{}, []string{})
+
+	return w.paths
+}
+
+// propertyName returns the name of the property a value path points to,
+// ignoring the array segments.
+func propertyName(path []string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] != scanner.ArraySegment {
+			return path[i]
+		}
+	}
+
+	return ""
+}
+
+// formatValue formats a string or number to be scanned. It returns false
+// for other types.
+func formatValue(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case string:
+		return n, true
+	case json.Number:
+		return n.String(), true
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(n), 'f', -1, 32), true
+	case int:
+		return strconv.Itoa(n), true
+	case int64:
+		return strconv.FormatInt(n, 10), true
+	case int32:
+		return strconv.FormatInt(int64(n), 10), true
+	}
+
+	return "", false
+}
+
+// getValuesByPath returns the strings and numbers at path in data,
+// formatted as strings. Arrays in the path are expanded, so there can be
+// more than one value. Missing values and values of other types are
+// skipped.
+func getValuesByPath(path []string, data interface{}) []string {
+	if data == nil {
+		return nil
+	}
+
+	if len(path) == 0 {
+		if s, ok := formatValue(data); ok {
+			return []string{s}
+		}
+
+		return nil
+	}
+
+	if path[0] == scanner.ArraySegment {
+		items, ok := data.([]interface{})
+		if !ok {
+			return nil
+		}
+
+		res := []string{}
+		for _, item := range items {
+			res = append(res, getValuesByPath(path[1:], item)...)
+		}
+
+		return res
+	}
+
+	var obj map[string]interface{}
+
+	switch d := data.(type) {
+	case monoidprotocol.MonoidRecordData:
+		obj = d
+	case map[string]interface{}:
+		obj = d
+	default:
+		return nil
+	}
+
+	return getValuesByPath(path[1:], obj[path[0]])
+}
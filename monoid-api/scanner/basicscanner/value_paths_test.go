@@ -0,0 +1,110 @@
+package basicscanner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/jsonschema"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+const nestedSchema = `{
+	"type": "object",
+	"$defs": {
+		"contact": {
+			"type": "object",
+			"properties": {
+				"email": {"type": "string"},
+				"referrer": {"$ref": "#/$defs/contact"}
+			}
+		}
+	},
+	"properties": {
+		"id": {"type": "integer"},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"events": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"contact": {"$ref": "#/$defs/contact"},
+					"value": {"anyOf": [{"type": "number"}, {"type": ["string", "null"]}]}
+				}
+			}
+		},
+		"address": {
+			"allOf": [
+				{"type": "object", "properties": {"street": {"type": "string"}}},
+				{"type": "object", "properties": {"zip": {"type": "string"}}}
+			]
+		}
+	}
+}`
+
+func TestGetValuePaths(t *testing.T) {
+	raw := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(nestedSchema), &raw))
+
+	schema := jsonschema.Schema{}
+	assert.NoError(t, jsonschema.Decode(raw, &schema))
+
+	paths := map[string]string{}
+	for _, vp := range GetValuePaths(schema) {
+		paths[scanner.PathString(vp.Path)] = vp.Type
+	}
+
+	assert.Equal(t, map[string]string{
+		"address.street":         "string",
+		"address.zip":            "string",
+		"events[].contact.email": "string",
+		"events[].value":         "string",
+		"id":                     "integer",
+		"tags[]":                 "string",
+	}, paths)
+}
+
+// TestGetValuePathsDefinitions checks references to draft-07 style
+// definitions.
+func TestGetValuePathsDefinitions(t *testing.T) {
+	raw := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"definitions": {
+			"contact": {"type": "object", "properties": {"email": {"type": "string"}}}
+		},
+		"properties": {
+			"owner": {"$ref": "#/definitions/contact"}
+		}
+	}`), &raw))
+
+	schema := jsonschema.Schema{}
+	assert.NoError(t, jsonschema.Decode(raw, &schema))
+
+	paths := map[string]string{}
+	for _, vp := range GetValuePaths(schema) {
+		paths[scanner.PathString(vp.Path)] = vp.Type
+	}
+
+	assert.Equal(t, map[string]string{"owner.email": "string"}, paths)
+}
+
+func TestGetValuesByPath(t *testing.T) {
+	data := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"tags": ["a", "b"],
+		"events": [
+			{"contact": {"email": "a@example.com"}},
+			{"contact": null},
+			{"contact": {"email": "b@example.com"}}
+		]
+	}`), &data))
+
+	assert.Equal(t, []string{"a", "b"}, getValuesByPath([]string{"tags", "[]"}, data))
+	assert.Equal(
+		t,
+		[]string{"a@example.com", "b@example.com"},
+		getValuesByPath([]string{"events", "[]", "contact", "email"}, data),
+	)
+	assert.Empty(t, getValuesByPath([]string{"missing", "email"}, data))
+}
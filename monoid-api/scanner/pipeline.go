@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+)
+
+// defaultBufferSize is the number of records queued for each worker.
+const defaultBufferSize = 64
+
+// Pipeline scans records with a pool of workers. Scanners aren't safe for
+// concurrent use, so all the records for a scanner are scanned by the same
+// worker, and different schemas are scanned concurrently.
+//
+// Records are read from the input channel only as fast as the workers scan
+// them, so a slow scan applies backpressure to the connector producing the
+// records, rather than buffering them in memory.
+type Pipeline struct {
+	// Workers is the number of scanning goroutines, defaults to 1.
+	Workers int
+
+	// BufferSize is the number of records queued for each worker.
+	BufferSize int
+
+	// OnError is called (from a worker goroutine) if a record can't be
+	// scanned. Errors are ignored if it's nil.
+	OnError func(record *monoidprotocol.MonoidRecord, err error)
+}
+
+// Run scans the records from records until the channel is closed or ctx is
+// done. route returns the scanner for a record, or nil if it should be
+// skipped. Run returns once every record read has been scanned.
+//
+// If ctx is done first, the rest of records is drained in the background,
+// so the producer doesn't block on a send nobody will receive. The
+// producer must still close the channel.
+func (p Pipeline) Run(
+	ctx context.Context,
+	records <-chan monoidprotocol.MonoidRecord,
+	route func(record *monoidprotocol.MonoidRecord) Scanner,
+) error {
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	bufferSize := p.BufferSize
+	if bufferSize < 1 {
+		bufferSize = defaultBufferSize
+	}
+
+	type job struct {
+		record  monoidprotocol.MonoidRecord
+		scanner Scanner
+	}
+
+	queues := make([]chan job, workers)
+	wg := sync.WaitGroup{}
+
+	for i := range queues {
+		queues[i] = make(chan job, bufferSize)
+		wg.Add(1)
+
+		go func(queue chan job) {
+			defer wg.Done()
+
+			for j := range queue {
+				if err := j.scanner.Scan(&j.record); err != nil && p.OnError != nil {
+					p.OnError(&j.record, err)
+				}
+			}
+		}(queues[i])
+	}
+
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+
+		wg.Wait()
+	}()
+
+	// Scanners are assigned to workers round-robin as they're first seen.
+	assigned := map[Scanner]int{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			go drain(records)
+			return ctx.Err()
+		case record, ok := <-records:
+			if !ok {
+				return nil
+			}
+
+			sc := route(&record)
+			if sc == nil {
+				continue
+			}
+
+			w, ok := assigned[sc]
+			if !ok {
+				w = len(assigned) % workers
+				assigned[sc] = w
+			}
+
+			select {
+			case <-ctx.Done():
+				go drain(records)
+				return ctx.Err()
+			case queues[w] <- job{record: record, scanner: sc}:
+			}
+		}
+	}
+}
+
+// drain discards the records left in the channel until it's closed.
+func drain(records <-chan monoidprotocol.MonoidRecord) {
+	for range records {
+	}
+}
@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type countScanner struct {
+	count int
+}
+
+func (s *countScanner) Scan(*monoidprotocol.MonoidRecord) error {
+	s.count++
+	return nil
+}
+
+func (s *countScanner) Summary() []RuleMatch {
+	return nil
+}
+
+func TestPipelineRun(t *testing.T) {
+	scanners := map[string]*countScanner{"a": {}, "b": {}, "c": {}}
+	records := make(chan monoidprotocol.MonoidRecord)
+
+	go func() {
+		for i := 0; i < 1000; i++ {
+			for _, name := range []string{"a", "b", "c", "unknown"} {
+				records <- monoidprotocol.MonoidRecord{SchemaName: name}
+			}
+		}
+
+		close(records)
+	}()
+
+	err := Pipeline{Workers: 2}.Run(context.Background(), records, func(r *monoidprotocol.MonoidRecord) Scanner {
+		if s, ok := scanners[r.SchemaName]; ok {
+			return s
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+
+	for _, s := range scanners {
+		assert.Equal(t, 1000, s.count)
+	}
+}
+
+func TestPipelineRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Pipeline{}.Run(ctx, make(chan monoidprotocol.MonoidRecord), func(*monoidprotocol.MonoidRecord) Scanner {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPipelineRunCanceledDrains(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	records := make(chan monoidprotocol.MonoidRecord)
+	err := Pipeline{}.Run(ctx, records, func(*monoidprotocol.MonoidRecord) Scanner {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The producer can still send the rest of its records.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 10; i++ {
+			records <- monoidprotocol.MonoidRecord{}
+		}
+
+		close(records)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("producer blocked after cancel")
+	}
+}
@@ -8,3 +8,13 @@ type Scanner interface {
 	Scan(*monoidprotocol.MonoidRecord) error
 	Summary() []RuleMatch
 }
+
+// TextScanner is a Scanner that can also scan unstructured text, e.g. the
+// text extracted from files.
+type TextScanner interface {
+	Scanner
+
+	// ScanText scans the text of a document. Matches in documents are
+	// reported on the FileProperty identifier.
+	ScanText(text string) error
+}
@@ -0,0 +1,189 @@
+package textextract
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// attachment is a file attached to an email.
+type attachment struct {
+	name string
+	data []byte
+}
+
+// emailHeaders are the headers of an email that are scanned with its body.
+var emailHeaders = []string{"From", "To", "Cc", "Bcc", "Reply-To", "Subject"}
+
+// isEmail returns true if data starts with the headers of an email.
+func isEmail(data []byte) bool {
+	tp := textproto.NewReader(bufio.NewReader(io.LimitReader(bytes.NewReader(data), 64*1024)))
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return false
+	}
+
+	return header.Get("From") != "" && (header.Get("Date") != "" ||
+		header.Get("Message-Id") != "" || header.Get("Mime-Version") != "")
+}
+
+// extractEmail extracts the headers and text parts of an email, and the
+// documents in its attachments.
+func extractEmail(name string, data []byte, fn func(Document) error, depth int) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		// Not an email after all, so scan it as text.
+		if isText(data) {
+			return emit(name, string(data), fn)
+		}
+
+		return nil
+	}
+
+	b := strings.Builder{}
+	dec := mime.WordDecoder{}
+
+	for _, h := range emailHeaders {
+		v := msg.Header.Get(h)
+		if v == "" {
+			continue
+		}
+
+		if decoded, err := dec.DecodeHeader(v); err == nil {
+			v = decoded
+		}
+
+		b.WriteString(v)
+		b.WriteString("\n")
+	}
+
+	attachments := []attachment{}
+	if err := readPart(textproto.MIMEHeader(msg.Header), msg.Body, &b, &attachments, 0); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if err := emit(name, b.String(), fn); err != nil {
+		return err
+	}
+
+	if depth >= maxDepth {
+		return nil
+	}
+
+	for i, a := range attachments {
+		attachmentName := a.name
+		if attachmentName == "" {
+			attachmentName = fmt.Sprintf("attachment-%d", i+1)
+		}
+
+		err := extract(name+"/"+attachmentName, bytes.NewReader(a.data), fn, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPart writes the text in a MIME part to b, and adds its attachments
+// to attachments.
+func readPart(
+	header textproto.MIMEHeader,
+	body io.Reader,
+	b *strings.Builder,
+	attachments *[]attachment,
+	depth int,
+) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	body = decodeTransfer(header.Get("Content-Transfer-Encoding"), body)
+
+	if strings.HasPrefix(mediaType, "multipart/") && depth < maxDepth {
+		mr := multipart.NewReader(body, params["boundary"])
+
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := readPart(p.Header, p, b, attachments, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := readLimited(body)
+	if err != nil {
+		return err
+	}
+
+	_, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	if filename == "" && (mediaType == "text/plain" || mediaType == "text/html") {
+		if mediaType == "text/html" {
+			data = []byte(stripTags(string(data)))
+		}
+
+		b.Write(data)
+		b.WriteString("\n")
+
+		return nil
+	}
+
+	*attachments = append(*attachments, attachment{name: filename, data: data})
+
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+
+	return r
+}
+
+// stripTags removes the tags from HTML, keeping the text.
+func stripTags(html string) string {
+	b := strings.Builder{}
+	inTag := false
+
+	for _, r := range html {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+
+			b.WriteRune(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
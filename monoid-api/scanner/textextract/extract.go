@@ -0,0 +1,275 @@
+// Package textextract extracts the text from files, so that unstructured
+// data (documents, emails, archives of them) can be scanned for PII.
+package textextract
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/monoid-privacy/monoid/tartools"
+)
+
+const (
+	// maxFileSize is the number of bytes read from each file. Anything
+	// after it is ignored.
+	maxFileSize = 64 * 1024 * 1024
+
+	// maxTextSize is the maximum length of the text extracted from each
+	// file.
+	maxTextSize = 4 * 1024 * 1024
+
+	// maxDepth is the maximum nesting of archives that are extracted, e.g.
+	// a gzipped tar has a depth of 2.
+	maxDepth = 4
+)
+
+// ErrStop can be returned by a callback to stop extraction without an
+// error.
+var ErrStop = errors.New("stop extraction")
+
+// Document is the text extracted from a file, or a file in an archive.
+type Document struct {
+	// Name is the name of the file, with the names of the archives it's in
+	// as prefixes, e.g. export.tar.gz/users/notes.txt.
+	Name string
+	Text string
+}
+
+// ExtractFile extracts the documents in the file at path, and calls fn
+// with each of them. Files that don't contain text (e.g. images) are
+// skipped.
+func ExtractFile(path string, fn func(Document) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return Extract(filepath.Base(path), f, fn)
+}
+
+// Extract extracts the documents in r, which is the contents of the file
+// called name, and calls fn with each of them.
+func Extract(name string, r io.Reader, fn func(Document) error) error {
+	err := extract(name, r, fn, 0)
+	if err == ErrStop {
+		return nil
+	}
+
+	return err
+}
+
+func extract(name string, r io.Reader, fn func(Document) error, depth int) error {
+	br := bufio.NewReaderSize(r, 1024)
+
+	// A tar header is 512 bytes, with the magic at 257.
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		if depth >= maxDepth {
+			return nil
+		}
+
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		defer gz.Close()
+
+		return extract(strings.TrimSuffix(name, filepath.Ext(name)), gz, fn, depth+1)
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		if depth >= maxDepth {
+			return nil
+		}
+
+		return tartools.WalkTar(tar.NewReader(br), func(h *tar.Header, r io.Reader) error {
+			return extract(name+"/"+strings.TrimPrefix(h.Name, "./"), r, fn, depth+1)
+		})
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		data, err := readLimited(br)
+		if err != nil {
+			return err
+		}
+
+		return emit(name, pdfText(data), fn)
+	}
+
+	data, err := readLimited(br)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".jsonl", ".ndjson":
+		if text, ok := jsonText(data); ok {
+			return emit(name, text, fn)
+		}
+	case ".csv":
+		if text, ok := csvText(data); ok {
+			return emit(name, text, fn)
+		}
+	case ".eml":
+		return extractEmail(name, data, fn, depth)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		if text, ok := jsonText(data); ok {
+			return emit(name, text, fn)
+		}
+	}
+
+	if isEmail(data) {
+		return extractEmail(name, data, fn, depth)
+	}
+
+	if !isText(data) {
+		return nil
+	}
+
+	return emit(name, string(data), fn)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(r, maxFileSize))
+}
+
+func emit(name string, text string, fn func(Document) error) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	if len(text) > maxTextSize {
+		text = text[:maxTextSize]
+	}
+
+	return fn(Document{Name: name, Text: text})
+}
+
+// isText returns true if data looks like text, rather than a binary file.
+func isText(data []byte) bool {
+	sample := data
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+	}
+
+	if len(sample) == 0 {
+		return false
+	}
+
+	n := len(sample)
+	control := 0
+
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		sample = sample[size:]
+
+		// A rune cut off at the end of the sample is fine.
+		if r == utf8.RuneError && size == 1 && len(sample) > 3 {
+			return false
+		}
+
+		if r == 0 {
+			return false
+		}
+
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' {
+			control++
+		}
+	}
+
+	// Allow a few control characters, e.g. form feeds.
+	return control*100 < n
+}
+
+// jsonText returns the strings and numbers in a JSON document, or a
+// stream of them (e.g. JSON lines), one per line.
+func jsonText(data []byte) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	b := strings.Builder{}
+
+	for {
+		var v interface{}
+
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return "", false
+		}
+
+		writeJSONValues(&b, v)
+	}
+
+	return b.String(), true
+}
+
+func writeJSONValues(b *strings.Builder, v interface{}) {
+	switch val := v.(type) {
+	case string:
+		b.WriteString(val)
+		b.WriteString("\n")
+	case json.Number:
+		b.WriteString(val.String())
+		b.WriteString("\n")
+	case map[string]interface{}:
+		for _, item := range val {
+			writeJSONValues(b, item)
+		}
+	case []interface{}:
+		for _, item := range val {
+			writeJSONValues(b, item)
+		}
+	}
+}
+
+// csvText returns the cells of a CSV file, one per line.
+func csvText(data []byte) (string, bool) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	b := strings.Builder{}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return "", false
+		}
+
+		for _, cell := range row {
+			if cell != "" {
+				b.WriteString(cell)
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	return b.String(), true
+}
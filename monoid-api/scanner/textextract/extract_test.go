@@ -0,0 +1,107 @@
+package textextract
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/tartools"
+	"github.com/stretchr/testify/assert"
+)
+
+func extractAll(t *testing.T, name string, data []byte) []Document {
+	docs := []Document{}
+
+	err := Extract(name, bytes.NewReader(data), func(d Document) error {
+		docs = append(docs, d)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	return docs
+}
+
+func TestExtractStructured(t *testing.T) {
+	docs := extractAll(t, "notes.txt", []byte("call jane@example.com\n"))
+	assert.Equal(t, []Document{{Name: "notes.txt", Text: "call jane@example.com"}}, docs)
+
+	docs = extractAll(t, "users.json", []byte(`{"user": {"email": "jane@example.com", "age": 30}}`))
+	assert.Len(t, docs, 1)
+	assert.Contains(t, docs[0].Text, "jane@example.com\n")
+	assert.Contains(t, docs[0].Text, "30")
+
+	docs = extractAll(t, "users.csv", []byte("name,email\njane,jane@example.com\n"))
+	assert.Equal(t, "name\nemail\njane\njane@example.com", docs[0].Text)
+
+	// Binary files are skipped.
+	assert.Empty(t, extractAll(t, "image.png", []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0x0d, 0xff, 0xfe}))
+}
+
+func TestExtractArchive(t *testing.T) {
+	buf := bytes.Buffer{}
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	assert.NoError(t, tartools.AddFile(tw, "export/a.txt", []byte("jane@example.com"), 0600))
+	assert.NoError(t, tartools.AddFile(tw, "export/b.json", []byte(`["joe@example.org"]`), 0600))
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+
+	docs := extractAll(t, "export.tar.gz", buf.Bytes())
+	assert.Equal(t, []Document{
+		{Name: "export.tar/export/a.txt", Text: "jane@example.com"},
+		{Name: "export.tar/export/b.json", Text: "joe@example.org"},
+	}, docs)
+}
+
+func TestExtractEmail(t *testing.T) {
+	email := strings.Join([]string{
+		"From: Jane <jane@example.com>",
+		"To: joe@example.org",
+		"Subject: Invoice",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="b1"`,
+		"",
+		"--b1",
+		"Content-Type: text/plain",
+		"",
+		"My card is 4111 1111 1111 1111.",
+		"--b1",
+		"Content-Type: text/plain",
+		`Content-Disposition: attachment; filename="ssn.txt"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		"U1NOOiAxMjMtNDUtNjc4OQ==",
+		"--b1--",
+		"",
+	}, "\r\n")
+
+	docs := extractAll(t, "message", []byte(email))
+	assert.Len(t, docs, 2)
+	assert.Contains(t, docs[0].Text, "jane@example.com")
+	assert.Contains(t, docs[0].Text, "4111 1111 1111 1111")
+	assert.Equal(t, Document{Name: "message/ssn.txt", Text: "SSN: 123-45-6789"}, docs[1])
+}
+
+func TestExtractPDF(t *testing.T) {
+	content := []byte("BT /F1 12 Tf 72 712 Td (Contact: jane@example.com) Tj 0 -14 Td [(SSN ) -50 (123-45-6789)] TJ ET")
+
+	compressed := bytes.Buffer{}
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	pdf := bytes.Buffer{}
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	docs := extractAll(t, "doc.pdf", pdf.Bytes())
+	assert.Equal(t, []Document{{Name: "doc.pdf", Text: "Contact: jane@example.com\nSSN 123-45-6789"}}, docs)
+}
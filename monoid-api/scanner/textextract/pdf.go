@@ -0,0 +1,228 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// streamRegex matches the streams in a PDF, with the dictionary that
+// describes each of them.
+var streamRegex = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// pdfText extracts the text from a PDF. Only the text drawn in content
+// streams with simple (single byte) fonts is extracted; text in fonts
+// with custom encodings, or in images, isn't.
+func pdfText(data []byte) string {
+	b := strings.Builder{}
+
+	for _, loc := range streamRegex.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		start := loc[1]
+
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+
+		stream := data[start : start+end]
+
+		// Skip images and fonts.
+		if bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+
+			decoded, err := io.ReadAll(io.LimitReader(zr, maxFileSize))
+			zr.Close()
+
+			// Truncated streams still have text up to the error.
+			if len(decoded) == 0 && err != nil {
+				continue
+			}
+
+			stream = decoded
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Other filters aren't supported.
+			continue
+		}
+
+		contentText(&b, stream)
+
+		if b.Len() > maxTextSize {
+			break
+		}
+	}
+
+	return b.String()
+}
+
+// contentText writes the text shown by the operators in a content stream
+// to b. Each text object (BT ... ET), and each line in one, is written on
+// its own line.
+func contentText(b *strings.Builder, content []byte) {
+	inText := false
+	line := strings.Builder{}
+
+	flush := func() {
+		if s := strings.TrimSpace(line.String()); s != "" {
+			b.WriteString(s)
+			b.WriteString("\n")
+		}
+
+		line.Reset()
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+
+		switch {
+		case c == '(' && inText:
+			s, n := literalString(content[i:])
+			line.WriteString(s)
+			i += n
+		case c == '<' && inText && i+1 < len(content) && content[i+1] != '<':
+			s, n := hexString(content[i:])
+			line.WriteString(s)
+			i += n
+		case c == '%':
+			// Comments run to the end of the line.
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isRegular(c):
+			j := i
+			for j < len(content) && isRegular(content[j]) {
+				j++
+			}
+
+			op := string(content[i:j])
+			i = j
+
+			switch op {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+
+				flush()
+			case "Td", "TD", "T*", "Tm", "'", "\"":
+				flush()
+			default:
+				// Large negative adjustments in TJ arrays are spaces.
+				if inText && len(op) > 1 && op[0] == '-' {
+					if n, err := strconv.ParseFloat(op, 64); err == nil && n < -200 {
+						line.WriteString(" ")
+					}
+				}
+			}
+		default:
+			i++
+		}
+	}
+
+	flush()
+}
+
+// isRegular returns true for the characters in PDF operators and numbers.
+func isRegular(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || c == '*' || c == '\'' || c == '"' ||
+		c == '.' || c == '-' || c == '+'
+}
+
+// literalString decodes the PDF string literal at the start of s, e.g.
+// (Hello \(world\)), and returns it with the number of bytes it took up.
+func literalString(s []byte) (string, int) {
+	b := strings.Builder{}
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch c {
+		case '(':
+			if depth > 0 {
+				b.WriteByte(c)
+			}
+
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b.String(), i + 1
+			}
+
+			b.WriteByte(c)
+		case '\\':
+			i++
+			if i >= len(s) {
+				break
+			}
+
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation.
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+						j++
+					}
+
+					n, _ := strconv.ParseUint(string(s[i:j]), 8, 8)
+					b.WriteByte(byte(n))
+					i = j - 1
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), len(s)
+}
+
+// hexString decodes the PDF hex string at the start of s, e.g. <48656C6C6F>.
+func hexString(s []byte) (string, int) {
+	end := bytes.IndexByte(s, '>')
+	if end < 0 {
+		return "", len(s)
+	}
+
+	digits := []byte{}
+	for _, c := range s[1:end] {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	b := strings.Builder{}
+	for i := 0; i < len(digits); i += 2 {
+		n, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		b.WriteByte(byte(n))
+	}
+
+	return b.String(), end + 1
+}
@@ -1,17 +1,64 @@
 package scanner
 
-import "github.com/monoid-privacy/monoid/monoidprotocol"
+import (
+	"strings"
+
+	"github.com/monoid-privacy/monoid/jsonschema"
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+)
 
 type RuleMatch struct {
 	RuleName    string
 	DisplayName string
+	Category    string
 	Confidence  string
 	Identifier  string
-	MatchedData []string
 	MatchType   string
 	SchemaName  string
 	SchemaGroup *string
-	LineCount   int
+
+	// MatchedData are the paths of the columns that matched a name rule.
+	// Matched values are never kept; see Samples.
+	MatchedData []string
+
+	// Samples are masked examples of the matched values, e.g. j***@e***.com.
+	Samples []string
+
+	// RowCount is the number of records that were scanned, or the number
+	// of documents for matches on FileProperty.
+	RowCount int
+
+	// LineCount is the number of values that matched the rule, and
+	// ValidatedCount is the number of those that passed its validator.
+	LineCount      int
+	ValidatedCount int
+
+	// ValueCount is the number of values that were scanned for the
+	// identifier.
+	ValueCount int
+
+	// Score is the strength of the evidence the match gives for its
+	// category, from 0 to 1.
+	Score float64
+}
+
+// CombineScores combines the scores of independent evidence for the same
+// category (e.g. a column name and the column's values), so that each
+// piece of evidence raises the combined score.
+func CombineScores(scores ...float64) float64 {
+	miss := 1.0
+
+	for _, s := range scores {
+		if s < 0 {
+			s = 0
+		} else if s > 1 {
+			s = 1
+		}
+
+		miss *= 1 - s
+	}
+
+	return 1 - miss
 }
 
 type SchemaRecordGroup struct {
@@ -19,7 +66,73 @@ type SchemaRecordGroup struct {
 	Records []monoidprotocol.MonoidRecord
 }
 
+// FileProperty is the identifier that matches in the files of a data
+// source (rather than its structured records) are reported on.
+const FileProperty = "$file"
+
+// ArraySegment is the path segment for the items of an array.
+const ArraySegment = "[]"
+
 type ValuePath struct {
-	Path []string
-	Type string
+	Path   []string
+	Type   string
+	Schema *jsonschema.Schema
+}
+
+// PathString formats a value path as a property name, e.g. the path
+// [events, [], email] is formatted as events[].email.
+func PathString(path []string) string {
+	var b strings.Builder
+
+	for i, segment := range path {
+		if i > 0 && segment != ArraySegment {
+			b.WriteString(".")
+		}
+
+		b.WriteString(segment)
+	}
+
+	return b.String()
+}
+
+// RuleKind is the kind of check a CustomRule runs.
+type RuleKind string
+
+const (
+	// RuleKindName rules match the names of columns.
+	RuleKindName = RuleKind("NAME")
+
+	// RuleKindRegex rules match the values in columns against a regex.
+	RuleKindRegex = RuleKind("REGEX")
+)
+
+// CustomRule is a rule that is configured at runtime (e.g. for a
+// workspace), rather than built into a scanner.
+type CustomRule struct {
+	Name        string
+	DisplayName string
+	Kind        RuleKind
+	Pattern     string
+	ColumnNames []string
+	Confidence  string
+	Category    string
+}
+
+// RulePack is a set of optional rules that are enabled together, e.g. the
+// identifiers used in a country.
+type RulePack struct {
+	ID          string
+	Name        string
+	Description string
+
+	// Categories are the categories the pack's rules report.
+	Categories []string
+}
+
+// RuleConfig configures the rules a scanner runs in addition to its
+// built-in rules.
+type RuleConfig struct {
+	// RulePacks are the ids of the rule packs to enable.
+	RulePacks   []string
+	CustomRules []CustomRule
 }
@@ -126,3 +126,28 @@ func CopyTarToDir(tr *tar.Reader, dest string) error {
 		}
 	}
 }
+
+// WalkTar calls fn with the header and contents of each regular file in
+// tr, in order. It stops at the first error fn returns.
+func WalkTar(tr *tar.Reader, fn func(header *tar.Header, r io.Reader) error) error {
+	for {
+		header, err := tr.Next()
+
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		case header == nil:
+			continue
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := fn(header, tr); err != nil {
+			return err
+		}
+	}
+}
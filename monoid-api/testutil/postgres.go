@@ -0,0 +1,88 @@
+// Package testutil has helpers for tests that need a database.
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	// Registers the postgres driver used to create the test database.
+	_ "github.com/lib/pq"
+	"github.com/monoid-privacy/monoid/cmd"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/gorm"
+)
+
+// SetupDB starts a Postgres container and returns a database with all of
+// the models migrated. The caller should terminate the container once
+// it's done with it.
+func SetupDB() (container testcontainers.Container, db *gorm.DB, err error) {
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:latest",
+		ExposedPorts: []string{"5432/tcp"},
+		WaitingFor:   wait.ForListeningPort("5432/tcp"),
+		AutoRemove:   true,
+		Env: map[string]string{
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_PASSWORD": "postgres",
+			"POSTGRES_DB":       "postgres",
+		},
+	}
+
+	ctx := context.Background()
+
+	postgres, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			postgres.Terminate(context.Background())
+		}
+	}()
+
+	p, err := postgres.MappedPort(ctx, "5432")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := postgres.Host(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	psqlInfo := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		h, p.Port(), "postgres", "postgres", "postgres",
+	)
+
+	rawDB, err := sql.Open("postgres", psqlInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = rawDB.Exec("CREATE DATABASE monoidtest")
+	rawDB.Close()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db = cmd.InitDb(cmd.DBInfo{
+		User:     "postgres",
+		Password: "postgres",
+		TCPHost:  h,
+		Port:     p.Port(),
+		Name:     "monoidtest",
+	})
+
+	cmd.MigrateDBHelper(db, cmd.Models)
+
+	return postgres, db, nil
+}
@@ -6,16 +6,23 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/discovery"
+	"github.com/monoid-privacy/monoid/filestore"
 	"github.com/monoid-privacy/monoid/jsonschema"
 	"github.com/monoid-privacy/monoid/model"
 	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/notification"
 	"github.com/monoid-privacy/monoid/scanner"
 	"github.com/monoid-privacy/monoid/scanner/basicscanner"
+	"github.com/monoid-privacy/monoid/scanner/textextract"
 
 	"github.com/google/uuid"
-	"github.com/mitchellh/mapstructure"
 	"go.temporal.io/sdk/activity"
 	"gorm.io/gorm"
 )
@@ -40,6 +47,8 @@ func dedupCategories(
 		data, err := json.Marshal(model.NewCategoryDiscovery{
 			PropertyID: &propertyID,
 			CategoryID: cat.CategoryID,
+			Score:      cat.Score,
+			Evidence:   cat.Evidence,
 		})
 
 		if err != nil {
@@ -59,6 +68,259 @@ func dedupCategories(
 	return res
 }
 
+// schemaProperties returns the properties of a data source's schema: its
+// top-level properties, and the values nested in them, which are named by
+// their path (e.g. events[].email).
+func schemaProperties(schema jsonschema.Schema) map[string]*jsonschema.Schema {
+	res := map[string]*jsonschema.Schema{}
+	for k, v := range schema.Properties {
+		res[k] = v
+	}
+
+	for _, vp := range basicscanner.GetValuePaths(schema) {
+		res[scanner.PathString(vp.Path)] = vp.Schema
+	}
+
+	return res
+}
+
+// schemaType returns the type and format of a property's schema, nil if
+// the schema doesn't set them.
+func schemaType(schema *jsonschema.Schema) (*string, *string) {
+	if schema == nil {
+		return nil, nil
+	}
+
+	var typ, format *string
+
+	if schema.Type != "" {
+		t := schema.Type
+		typ = &t
+	}
+
+	if schema.Format != "" {
+		f := schema.Format
+		format = &f
+	}
+
+	return typ, format
+}
+
+func equalStrings(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// getPropertyChangedDiscovery returns a discovery if the type or format of
+// prop is different in its new schema, or nil if it's the same. Properties
+// that were never scanned don't have a type to compare with.
+func getPropertyChangedDiscovery(prop *model.Property, schema *jsonschema.Schema) *model.DataDiscovery {
+	if prop.Type == nil {
+		return nil
+	}
+
+	newType, newFormat := schemaType(schema)
+	if equalStrings(prop.Type, newType) && equalStrings(prop.Format, newFormat) {
+		return nil
+	}
+
+	data, err := json.Marshal(model.PropertyChangedDiscovery{
+		ID:        prop.ID,
+		OldType:   prop.Type,
+		NewType:   newType,
+		OldFormat: prop.Format,
+		NewFormat: newFormat,
+	})
+
+	if err != nil {
+		return nil
+	}
+
+	return &model.DataDiscovery{
+		ID:     uuid.NewString(),
+		Type:   model.DiscoveryTypePropertyChanged,
+		Status: model.DiscoveryStatusOpen,
+		Data:   data,
+	}
+}
+
+// backfillPropertyTypes stores the types of properties that were never
+// scanned, so that later scans can detect changes to them.
+func backfillPropertyTypes(
+	db *gorm.DB,
+	properties []*model.Property,
+	newProperties map[string]*jsonschema.Schema,
+) error {
+	for _, p := range properties {
+		schema, ok := newProperties[p.Name]
+		if p.Type != nil || !ok {
+			continue
+		}
+
+		typ, format := schemaType(schema)
+		if typ == nil {
+			continue
+		}
+
+		if err := db.Model(p).Updates(map[string]interface{}{
+			"type":   typ,
+			"format": format,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// discoveryOptions configures how the results of a scan are turned into
+// discoveries for a workspace.
+type discoveryOptions struct {
+	// ScoreThreshold is the minimum score a category needs to be
+	// discovered.
+	ScoreThreshold float64
+
+	// PrimaryKeys maps the API identifiers of the workspace's user primary
+	// keys to their ids, properties with a category that has the same id
+	// are suggested as links to the key.
+	PrimaryKeys map[string]string
+}
+
+// suggestPrimaryKey returns the user primary key to suggest for a property
+// with the given categories, and the category it was suggested for. Keys
+// that are already linked to a property of the data source aren't
+// suggested. Returns empty strings if there's no suggestion.
+func suggestPrimaryKey(
+	categoryIDs []string,
+	linkedKeys map[string]bool,
+	opts discoveryOptions,
+) (string, string) {
+	// Sort so that a property with several matching categories gets the
+	// same suggestion on every scan.
+	sort.Strings(categoryIDs)
+
+	for _, c := range categoryIDs {
+		keyID, ok := opts.PrimaryKeys[c]
+		if !ok || linkedKeys[keyID] {
+			continue
+		}
+
+		return keyID, c
+	}
+
+	return "", ""
+}
+
+// getPrimaryKeyDiscovery suggests linking prop to a user primary key, based
+// on its current categories and the ones found by the scan. Properties that
+// are already linked, or keys that are already linked to another property
+// of the data source, aren't suggested.
+func getPrimaryKeyDiscovery(
+	prop *model.Property,
+	cats []*model.DataDiscovery,
+	linkedKeys map[string]bool,
+	opts discoveryOptions,
+) *model.DataDiscovery {
+	if prop.UserPrimaryKeyID != nil || len(opts.PrimaryKeys) == 0 {
+		return nil
+	}
+
+	categoryIDs := []string{}
+	for _, c := range prop.Categories {
+		categoryIDs = append(categoryIDs, c.ID)
+	}
+
+	for _, d := range cats {
+		data, err := d.DeserializeData()
+		if err != nil {
+			continue
+		}
+
+		if c, ok := data.(model.NewCategoryDiscovery); ok {
+			categoryIDs = append(categoryIDs, c.CategoryID)
+		}
+	}
+
+	keyID, categoryID := suggestPrimaryKey(categoryIDs, linkedKeys, opts)
+	if keyID == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(model.PrimaryKeySuggestedDiscovery{
+		PropertyID:       prop.ID,
+		UserPrimaryKeyID: keyID,
+		CategoryID:       categoryID,
+	})
+
+	if err != nil {
+		return nil
+	}
+
+	return &model.DataDiscovery{
+		ID:     uuid.NewString(),
+		Type:   model.DiscoveryTypePrimaryKeySuggested,
+		Status: model.DiscoveryStatusOpen,
+		Data:   data,
+	}
+}
+
+// prefillPrimaryKeys links new properties to the user primary keys
+// suggested by their categories, so accepting the discovery that creates
+// them links them too. Each key is linked to at most one property of the
+// data source, the first one by name.
+func prefillPrimaryKeys(
+	props []*model.NewPropertyDiscovery,
+	linkedKeys map[string]bool,
+	opts discoveryOptions,
+) {
+	if len(opts.PrimaryKeys) == 0 {
+		return
+	}
+
+	sort.Slice(props, func(i, j int) bool {
+		return props[i].Name < props[j].Name
+	})
+
+	linked := make(map[string]bool, len(linkedKeys))
+	for k := range linkedKeys {
+		linked[k] = true
+	}
+
+	for _, p := range props {
+		categoryIDs := make([]string, 0, len(p.Categories))
+		for _, c := range p.Categories {
+			categoryIDs = append(categoryIDs, c.CategoryID)
+		}
+
+		keyID, _ := suggestPrimaryKey(categoryIDs, linked, opts)
+		if keyID == "" {
+			continue
+		}
+
+		p.UserPrimaryKeyID = &keyID
+		linked[keyID] = true
+	}
+}
+
+// getPrimaryKeys returns the ids of the workspace's user primary keys, by
+// API identifier.
+func getPrimaryKeys(db *gorm.DB, workspaceID string) (map[string]string, error) {
+	keys := []model.UserPrimaryKey{}
+	if err := db.Where("workspace_id = ?", workspaceID).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(keys))
+	for _, k := range keys {
+		res[k.APIIdentifier] = k.ID
+	}
+
+	return res, nil
+}
+
 // getPropertyDiscoveries matches newProperties with prevProperties, and returns a
 // list with all the discoveries.
 func getPropertyDiscoveries(
@@ -66,14 +328,21 @@ func getPropertyDiscoveries(
 	newProperties map[string]*jsonschema.Schema,
 	categoryMatches map[DataSourceMatcher]map[string][]scanner.RuleMatch,
 	dataSource *model.DataSource,
+	opts discoveryOptions,
 ) []*model.DataDiscovery {
 	propMap := map[string]*model.Property{}
+	linkedKeys := map[string]bool{}
 	for _, p := range prevProperties {
 		propMap[p.Name] = p
+
+		if p.UserPrimaryKeyID != nil {
+			linkedKeys[*p.UserPrimaryKeyID] = true
+		}
 	}
 
 	resPropMap := map[string]*model.DataDiscovery{}
 	res := []*model.DataDiscovery{}
+	newProps := []*model.NewPropertyDiscovery{}
 
 	// Detect properties in the new list of properties,
 	// add them to the result map and mark then as tentatively created.
@@ -86,11 +355,20 @@ func getPropertyDiscoveries(
 				categoryMatches,
 				NewDataSourceMatcher(dataSource.Name, dataSource.Group),
 				p,
+				opts,
 			)
 
 			newCats := dedupCategories(prop.ID, prop.Categories, cats)
 			res = append(res, newCats...)
 
+			if changed := getPropertyChangedDiscovery(prop, newProperties[p]); changed != nil {
+				res = append(res, changed)
+			}
+
+			if suggested := getPrimaryKeyDiscovery(prop, newCats, linkedKeys, opts); suggested != nil {
+				res = append(res, suggested)
+			}
+
 			continue
 		}
 
@@ -99,19 +377,28 @@ func getPropertyDiscoveries(
 			categoryMatches,
 			NewDataSourceMatcher(dataSource.Name, dataSource.Group),
 			p,
+			opts,
 		)
 
-		data, err := json.Marshal(model.NewPropertyDiscovery{
+		typ, format := schemaType(newProperties[p])
+		newProps = append(newProps, &model.NewPropertyDiscovery{
 			Name:         p,
 			DataSourceId: &dataSource.ID,
 			Categories:   cats,
+			Type:         typ,
+			Format:       format,
 		})
+	}
+
+	prefillPrimaryKeys(newProps, linkedKeys, opts)
 
+	for _, np := range newProps {
+		data, err := json.Marshal(np)
 		if err != nil {
 			continue
 		}
 
-		resPropMap[p] = &model.DataDiscovery{
+		resPropMap[np.Name] = &model.DataDiscovery{
 			ID:     uuid.NewString(),
 			Type:   model.DiscoveryTypePropertyFound,
 			Status: model.DiscoveryStatusOpen,
@@ -156,21 +443,23 @@ func getPropertyDiscoveries(
 
 // processDiscoveries processes the list of new discoveries, eliminating any duplicates,
 // updating them instead of creating, and closing any discoveries that are no longer relevant.
-// Returns the number of new discoveries made.
+// The workspace's discovery policies are then applied to the open discoveries.
+// Returns the new discoveries that were made.
 func processDiscoveries(
 	ctx context.Context,
-	db *gorm.DB,
+	conf *config.BaseConfig,
 	silo *model.SiloDefinition,
 	discoveries []*model.DataDiscovery,
-) (int, error) {
+) ([]*model.DataDiscovery, error) {
 	logger := activity.GetLogger(ctx)
+	db := conf.DB
 
 	openDiscoveries := []*model.DataDiscovery{}
 	if err := db.Where("silo_definition_id = ?", silo.ID).Where(
 		"status = ?",
 		model.DiscoveryStatusOpen,
 	).Find(&openDiscoveries).Error; err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	type discoveryKey struct {
@@ -192,7 +481,7 @@ func processDiscoveries(
 		}] = d
 	}
 
-	nDiscoveries := 0
+	newDiscoveries := []*model.DataDiscovery{}
 
 	if err := db.Transaction(func(tx *gorm.DB) error {
 		currDiscoveries := map[interface{}]bool{}
@@ -218,7 +507,7 @@ func processDiscoveries(
 					return err
 				}
 
-				nDiscoveries += 1
+				newDiscoveries = append(newDiscoveries, d)
 				continue
 			}
 
@@ -258,62 +547,290 @@ func processDiscoveries(
 
 		return nil
 	}); err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	// Policy errors don't fail the scan, the discoveries are left open to
+	// be handled by hand.
+	openDiscoveries = []*model.DataDiscovery{}
+	if err := db.Where("silo_definition_id = ?", silo.ID).Where(
+		"status = ?",
+		model.DiscoveryStatusOpen,
+	).Order("created_at").Find(&openDiscoveries).Error; err != nil {
+		logger.Error("Error finding open discoveries", "error", err)
+		return newDiscoveries, nil
+	}
+
+	handled, errs := discovery.ApplyPolicies(conf, silo.WorkspaceID, openDiscoveries)
+	for _, err := range errs {
+		logger.Error("Error applying discovery policy", "error", err)
 	}
 
-	return nDiscoveries, nil
+	logger.Info("Applied discovery policies", "handled", handled)
+
+	return newDiscoveries, nil
 }
 
 // getCategories finds the new category discoveries from the
 // result of scanProtocol and the data source and
-// property names.
+// property names. The evidence for each category is combined
+// into a score, and categories that score below the threshold
+// in opts are left out.
 func getCategories(
 	matches map[DataSourceMatcher]map[string][]scanner.RuleMatch,
 	source DataSourceMatcher,
 	propertyName string,
+	opts discoveryOptions,
 ) []model.NewCategoryDiscovery {
 	catMatcher, ok := matches[source]
+	if !ok {
+		return []model.NewCategoryDiscovery{}
+	}
 
-	if ok {
-		matches, pok := catMatcher[propertyName]
+	propMatches, ok := catMatcher[propertyName]
+	if !ok {
+		return []model.NewCategoryDiscovery{}
+	}
 
-		if pok {
-			res := make([]model.NewCategoryDiscovery, len(matches))
+	// Multiple rules can match the same category.
+	categoryScores := map[string][]float64{}
+	categoryEvidence := map[string][]model.CategoryEvidence{}
+	categoryOrder := []string{}
 
-			for i, m := range matches {
-				res[i] = model.NewCategoryDiscovery{
-					CategoryID: m.RuleName,
-				}
-			}
+	for _, m := range propMatches {
+		if _, ok := categoryScores[m.Category]; !ok {
+			categoryOrder = append(categoryOrder, m.Category)
+		}
+
+		categoryScores[m.Category] = append(categoryScores[m.Category], m.Score)
+
+		samples := m.Samples
+		if samples == nil {
+			samples = []string{}
+		}
+
+		categoryEvidence[m.Category] = append(categoryEvidence[m.Category], model.CategoryEvidence{
+			RuleName:    m.RuleName,
+			MatchType:   m.MatchType,
+			MatchCount:  m.ValidatedCount,
+			RowsScanned: m.RowCount,
+			Samples:     samples,
+		})
+	}
+
+	res := make([]model.NewCategoryDiscovery, 0, len(categoryOrder))
+
+	for _, c := range categoryOrder {
+		score := scanner.CombineScores(categoryScores[c]...)
+		if score < opts.ScoreThreshold {
+			continue
+		}
 
-			return res
+		res = append(res, model.NewCategoryDiscovery{
+			CategoryID: c,
+			Score:      &score,
+			Evidence:   categoryEvidence[c],
+		})
+	}
+
+	return res
+}
+
+// GetWorkspaceSettings returns the settings of a workspace.
+func GetWorkspaceSettings(db *gorm.DB, workspaceID string) (model.WorkspaceSettings, error) {
+	workspace := model.Workspace{}
+	if err := db.Where("id = ?", workspaceID).First(&workspace).Error; err != nil {
+		return model.WorkspaceSettings{}, err
+	}
+
+	settings := model.WorkspaceSettings{}
+	if len(workspace.Settings) > 0 {
+		if err := json.Unmarshal(workspace.Settings, &settings); err != nil {
+			return model.WorkspaceSettings{}, err
 		}
 	}
 
-	return []model.NewCategoryDiscovery{}
+	return settings, nil
 }
 
-// scanProtocol runs the PII scan using the monoid protocol,
-// and returns a 2D map, the first dimension of which is a DataSourceMatcher
-// key, and the second of which has the property path as a key.
+// getRuleConfig returns the rule packs a workspace has enabled, and its
+// custom scanner rules, including the rules that apply to all workspaces.
+func getRuleConfig(
+	db *gorm.DB,
+	workspaceID string,
+	settings model.WorkspaceSettings,
+) (scanner.RuleConfig, error) {
+	rules := []model.ScannerRule{}
+	if err := db.Where("workspace_id = ?", workspaceID).Or(
+		"workspace_id IS NULL",
+	).Find(&rules).Error; err != nil {
+		return scanner.RuleConfig{}, err
+	}
+
+	res := make([]scanner.CustomRule, 0, len(rules))
+
+	for _, r := range rules {
+		columnNames, err := r.ColumnNameList()
+		if err != nil {
+			return scanner.RuleConfig{}, err
+		}
+
+		pattern := ""
+		if r.Pattern != nil {
+			pattern = *r.Pattern
+		}
+
+		res = append(res, scanner.CustomRule{
+			Name:        r.Name,
+			DisplayName: r.Name,
+			Kind:        scanner.RuleKind(r.Kind),
+			Pattern:     pattern,
+			ColumnNames: columnNames,
+			Confidence:  r.Confidence,
+			Category:    r.CategoryID,
+		})
+	}
+
+	return scanner.RuleConfig{
+		RulePacks:   settings.ScannerRulePacks,
+		CustomRules: res,
+	}, nil
+}
+
+// fileScanner scans the files in FILE records, which the connector writes
+// to dir, as well as structured records. Files are only scanned once the
+// connector has exited (with scanFiles), as they may not be complete when
+// their record is read.
+type fileScanner struct {
+	scanner.TextScanner
+	dir string
+
+	// files are the files from FILE records that haven't been scanned.
+	files []string
+
+	// hasFiles is true if there were any FILE records.
+	hasFiles bool
+}
+
+func (s *fileScanner) Scan(record *monoidprotocol.MonoidRecord) error {
+	if record.RecordType == nil || *record.RecordType != monoidprotocol.MonoidRecordRecordTypeFILE {
+		return s.TextScanner.Scan(record)
+	}
+
+	if record.File == nil {
+		return fmt.Errorf("file record doesn't have a file")
+	}
+
+	s.hasFiles = true
+	s.files = append(s.files, *record.File)
+
+	return nil
+}
+
+// scanFile extracts the text from a file written by the connector, and
+// scans it.
+func (s *fileScanner) scanFile(file string) error {
+	dir := filepath.Clean(s.dir)
+	path := filepath.Join(dir, file)
+
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return fmt.Errorf("file %s is outside the connector's directory", file)
+	}
+
+	// The files are only needed for the scan, so they're removed as soon
+	// as they've been scanned, to bound the disk space a scan uses.
+	defer os.Remove(path)
+
+	return textextract.ExtractFile(path, func(doc textextract.Document) error {
+		return s.ScanText(doc.Text)
+	})
+}
+
+// fileRecordScanner scans the files of a fileScanner, so they can be
+// scanned in a scanner.Pipeline.
+type fileRecordScanner struct {
+	s *fileScanner
+}
+
+func (f fileRecordScanner) Scan(record *monoidprotocol.MonoidRecord) error {
+	return f.s.scanFile(*record.File)
+}
+
+func (f fileRecordScanner) Summary() []scanner.RuleMatch {
+	return nil
+}
+
+// scanFiles scans the files of every scanner in matchers, in parallel.
+func scanFiles(
+	ctx context.Context,
+	pipeline scanner.Pipeline,
+	matchers map[DataSourceMatcher]*fileScanner,
+) error {
+	fileRecords := make(chan monoidprotocol.MonoidRecord)
+
+	go func() {
+		defer close(fileRecords)
+
+		for k, m := range matchers {
+			for i := range m.files {
+				group := k.Group
+				record := monoidprotocol.MonoidRecord{
+					SchemaName:  k.Name,
+					SchemaGroup: &group,
+					File:        &m.files[i],
+				}
+
+				select {
+				case fileRecords <- record:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return pipeline.Run(ctx, fileRecords, func(record *monoidprotocol.MonoidRecord) scanner.Scanner {
+		return fileRecordScanner{s: matchers[NewDataSourceMatcher(record.SchemaName, record.SchemaGroup)]}
+	})
+}
+
+// scanResult is the result of scanning a silo.
+type scanResult struct {
+	// Matches is a 2D map, the first dimension of which is a
+	// DataSourceMatcher key, and the second of which has the property
+	// path as a key.
+	Matches map[DataSourceMatcher]map[string][]scanner.RuleMatch
+
+	// FileSources are the data sources that had FILE records.
+	FileSources map[DataSourceMatcher]bool
+}
+
+// scanProtocol runs the PII scan using the monoid protocol. dir is the
+// directory the connector writes files to.
 func scanProtocol(
 	ctx context.Context,
 	mp monoidprotocol.MonoidProtocol,
 	config map[string]interface{},
 	schemas []monoidprotocol.MonoidSchema,
-) (map[DataSourceMatcher]map[string][]scanner.RuleMatch, error) {
+	ruleConfig scanner.RuleConfig,
+	workers int,
+	dir string,
+) (scanResult, error) {
 	logger := activity.GetLogger(ctx)
 
 	// Create PII scanners for each schema
-	matchers := map[DataSourceMatcher]scanner.Scanner{}
+	matchers := map[DataSourceMatcher]*fileScanner{}
 	for _, s := range schemas {
-		sc, err := basicscanner.NewBasicScanner(s)
+		sc, err := basicscanner.NewBasicScanner(s, ruleConfig)
 
 		if err != nil {
-			return nil, err
+			return scanResult{}, err
 		}
 
-		matchers[NewDataSourceMatcher(s.Name, s.Group)] = sc
+		matchers[NewDataSourceMatcher(s.Name, s.Group)] = &fileScanner{
+			TextScanner: sc,
+			dir:         dir,
+		}
 	}
 
 	recordChan, resChan, err := mp.Scan(
@@ -323,28 +840,52 @@ func scanProtocol(
 	)
 
 	if err != nil {
-		return nil, err
+		return scanResult{}, err
 	}
 
 	// Get the schema and scan every output record
-	for record := range recordChan {
-		matcher := matchers[NewDataSourceMatcher(
-			record.SchemaName,
-			record.SchemaGroup,
-		)]
+	pipeline := scanner.Pipeline{
+		Workers: workers,
+		OnError: func(record *monoidprotocol.MonoidRecord, err error) {
+			logger.Error("Error scanning record", "schema", record.SchemaName, "error", err)
+		},
+	}
 
-		if err := matcher.Scan(&record); err != nil {
-			logger.Error("Error scanning record: %v", err)
+	err = pipeline.Run(ctx, recordChan, func(record *monoidprotocol.MonoidRecord) scanner.Scanner {
+		matcher, ok := matchers[NewDataSourceMatcher(record.SchemaName, record.SchemaGroup)]
+		if !ok {
+			return nil
 		}
+
+		return matcher
+	})
+
+	if err != nil {
+		return scanResult{}, err
+	}
+
+	status := <-resChan
+	if status != 0 {
+		return scanResult{}, fmt.Errorf("container exited with non-zero code (%d)", status)
+	}
+
+	if err := scanFiles(ctx, pipeline, matchers); err != nil {
+		return scanResult{}, err
 	}
 
 	// Get all the rule matches from each schema
 	res := map[DataSourceMatcher]map[string][]scanner.RuleMatch{}
+	fileSources := map[DataSourceMatcher]bool{}
+
 	for k, v := range matchers {
 		if _, ok := res[k]; !ok {
 			res[k] = map[string][]scanner.RuleMatch{}
 		}
 
+		if v.hasFiles {
+			fileSources[k] = true
+		}
+
 		matches := v.Summary()
 
 		for _, match := range matches {
@@ -356,12 +897,7 @@ func scanProtocol(
 		}
 	}
 
-	status := <-resChan
-	if status != 0 {
-		return nil, fmt.Errorf("container exited with non-zero code (%d)", status)
-	}
-
-	return res, nil
+	return scanResult{Matches: res, FileSources: fileSources}, nil
 }
 
 // DetectDSArgs are the arguments passed into a the activity.
@@ -370,9 +906,58 @@ type DetectDSArgs struct {
 	LogObjectName string
 }
 
+// DetectDSResult is the result of DetectDataSourcesWithEvent.
+type DetectDSResult struct {
+	NumDiscoveries int
+
+	// Event summarizes the new discoveries that are still open after the
+	// workspace's discovery policies ran, for its notification channels.
+	// It's nil if there weren't any.
+	Event *notification.DiscoveryEvent
+}
+
 // DetectDataSources scans for the data sources for a data silo, and returns the number of
-// discoveries that were made.
+// discoveries that were made. It's kept for workflows that started before
+// DetectDataSourcesWithEvent was added.
 func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (int, error) {
+	res, err := a.detectDataSources(ctx, args)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.NumDiscoveries, nil
+}
+
+// DetectDataSourcesWithEvent scans for the data sources for a data silo, and
+// returns the number of discoveries that were made, and the event to notify
+// the workspace of the ones that need a decision.
+func (a *Activity) DetectDataSourcesWithEvent(ctx context.Context, args DetectDSArgs) (DetectDSResult, error) {
+	return a.detectDataSources(ctx, args)
+}
+
+// openDiscoveries returns the discoveries that are still open, e.g. that
+// weren't accepted or rejected by a policy.
+func openDiscoveries(db *gorm.DB, discoveries []*model.DataDiscovery) ([]*model.DataDiscovery, error) {
+	if len(discoveries) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(discoveries))
+	for _, d := range discoveries {
+		ids = append(ids, d.ID)
+	}
+
+	res := []*model.DataDiscovery{}
+	if err := db.Where("id IN ?", ids).Where(
+		"status = ?", model.DiscoveryStatusOpen,
+	).Order("created_at").Find(&res).Error; err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *Activity) detectDataSources(ctx context.Context, args DetectDSArgs) (DetectDSResult, error) {
 	logger := activity.GetLogger(ctx)
 	go func() {
 		ticker := time.NewTicker(1 * time.Second)
@@ -394,7 +979,7 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 	if err := a.Conf.DB.Preload(
 		"SiloSpecification",
 	).Where("id = ?", args.SiloID).First(&dataSilo).Error; err != nil {
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	logger.Info("Getting schemas")
@@ -402,7 +987,7 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 	// Create a temporary directory that can be used by the docker container
 	dir, err := ioutil.TempDir(a.Conf.TempStorePath, "monoid")
 	if err != nil {
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	defer os.RemoveAll(dir)
@@ -415,23 +1000,27 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 
 	if err != nil {
 		logger.Error("Error creating docker client: %v", err)
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	defer mp.Teardown(ctx)
 
 	if err := mp.InitConn(ctx); err != nil {
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	logChan, err := mp.AttachLogs(ctx)
 	if err != nil {
 		logger.Error("Error attaching logs: %v", err)
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	go func() {
-		wr, _, err := a.Conf.FileStore.NewWriter(context.Background(), args.LogObjectName, true)
+		wr, _, err := a.Conf.FileStore.NewWriter(
+			filestore.WithFullPath(context.Background()),
+			args.LogObjectName,
+			true,
+		)
 		if err != nil {
 			logger.Error("Error opening log writer: %v", err)
 		}
@@ -464,13 +1053,13 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 
 	if err := mp.InitConn(ctx); err != nil {
 		logger.Error("Error creating docker connection", "error", err)
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	conf := map[string]interface{}{}
 	if err := json.Unmarshal([]byte(dataSilo.Config), &conf); err != nil {
 		logger.Error("Error decoding config", "error", err)
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	logger.Info("pulling schema")
@@ -479,15 +1068,40 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 
 	if err != nil {
 		logger.Error("Error running schema", err)
-		return 0, err
+		return DetectDSResult{}, err
+	}
+
+	settings, err := GetWorkspaceSettings(a.Conf.DB, dataSilo.WorkspaceID)
+	if err != nil {
+		logger.Error("Error getting workspace settings", "error", err)
+		return DetectDSResult{}, err
+	}
+
+	primaryKeys, err := getPrimaryKeys(a.Conf.DB, dataSilo.WorkspaceID)
+	if err != nil {
+		logger.Error("Error getting primary keys", "error", err)
+		return DetectDSResult{}, err
+	}
+
+	opts := discoveryOptions{
+		ScoreThreshold: settings.ScoreThreshold(),
+		PrimaryKeys:    primaryKeys,
+	}
+
+	ruleConfig, err := getRuleConfig(a.Conf.DB, dataSilo.WorkspaceID, settings)
+	if err != nil {
+		logger.Error("Error getting scanner rules", "error", err)
+		return DetectDSResult{}, err
 	}
 
-	matches, err := scanProtocol(ctx, mp, conf, schemas.Schemas)
+	scanRes, err := scanProtocol(ctx, mp, conf, schemas.Schemas, ruleConfig, a.Conf.ScanWorkers, dir)
 	if err != nil {
 		logger.Error("Error running scan", "error", err)
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
+	matches := scanRes.Matches
+
 	// Get all the data sources (with properties) that currently exist
 	// for this silo.
 	sources := []model.DataSource{}
@@ -495,7 +1109,7 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 		"silo_definition_id = ?", dataSilo.ID,
 	).Find(&sources).Error; err != nil {
 		logger.Error("Error getting silo def %v", err)
-		return 0, err
+		return DetectDSResult{}, err
 	}
 
 	// Detect the new data sources.
@@ -518,19 +1132,32 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 		currSource, ok := sourceMap[sourceMatcher]
 
 		parsedSchema := jsonschema.Schema{}
-		err := mapstructure.Decode(schema.JsonSchema, &parsedSchema)
+		err := jsonschema.Decode(schema.JsonSchema, &parsedSchema)
 		if err != nil {
 			logger.Error("Error decoding schema: %v", err)
 			continue
 		}
 
+		properties := schemaProperties(parsedSchema)
+
+		// Data sources with files have a property for the matches in
+		// their files.
+		if scanRes.FileSources[sourceMatcher] {
+			properties[scanner.FileProperty] = &jsonschema.Schema{Type: "string"}
+		}
+
 		// Process just the properties if the data source already exists.
 		if ok {
+			if err := backfillPropertyTypes(a.Conf.DB, currSource.Properties, properties); err != nil {
+				logger.Error("Error storing property types", "error", err)
+			}
+
 			propDiscoveries := getPropertyDiscoveries(
 				currSource.Properties,
-				parsedSchema.Properties,
+				properties,
 				matches,
 				currSource,
+				opts,
 			)
 
 			dataDiscoveries = append(dataDiscoveries, propDiscoveries...)
@@ -542,20 +1169,28 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 
 		// If the data source doesn't exist, create the properties manually,
 		// and add the new data source discovery.
-		properties := []model.NewPropertyDiscovery{}
-		for p := range parsedSchema.Properties {
-			discovery := model.NewPropertyDiscovery{
+		newProps := []*model.NewPropertyDiscovery{}
+		for p, propSchema := range properties {
+			typ, format := schemaType(propSchema)
+			newProps = append(newProps, &model.NewPropertyDiscovery{
 				Name:       p,
-				Categories: getCategories(matches, sourceMatcher, p),
-			}
+				Categories: getCategories(matches, sourceMatcher, p, opts),
+				Type:       typ,
+				Format:     format,
+			})
+		}
+
+		prefillPrimaryKeys(newProps, map[string]bool{}, opts)
 
-			properties = append(properties, discovery)
+		propDiscoveries := make([]model.NewPropertyDiscovery, 0, len(newProps))
+		for _, np := range newProps {
+			propDiscoveries = append(propDiscoveries, *np)
 		}
 
 		sourceData, err := json.Marshal(model.NewDataSourceDiscovery{
 			Group:      schema.Group,
 			Name:       schema.Name,
-			Properties: properties,
+			Properties: propDiscoveries,
 		})
 
 		if err != nil {
@@ -592,10 +1227,20 @@ func (a *Activity) DetectDataSources(ctx context.Context, args DetectDSArgs) (in
 		})
 	}
 
-	nDiscoveries, err := processDiscoveries(ctx, a.Conf.DB, &dataSilo, dataDiscoveries)
+	newDiscoveries, err := processDiscoveries(ctx, a.Conf, &dataSilo, dataDiscoveries)
 	if err != nil {
-		return 0, err
+		return DetectDSResult{}, err
+	}
+
+	// Discoveries a policy has already decided don't need attention, so
+	// they're left out of the notification.
+	pending, err := openDiscoveries(a.Conf.DB, newDiscoveries)
+	if err != nil {
+		logger.Error("Error finding open discoveries", "error", err)
 	}
 
-	return nDiscoveries, nil
+	return DetectDSResult{
+		NumDiscoveries: len(newDiscoveries),
+		Event:          notification.NewDiscoveryEvent(&dataSilo, pending, time.Now()),
+	}, nil
 }
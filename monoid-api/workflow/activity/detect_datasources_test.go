@@ -0,0 +1,213 @@
+package activity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/jsonschema"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/monoid-privacy/monoid/scanner/basicscanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCategoriesScores(t *testing.T) {
+	source := NewDataSourceMatcher("users", nil)
+	matches := map[DataSourceMatcher]map[string][]scanner.RuleMatch{
+		source: {
+			"email": {
+				{Category: "email", MatchType: "name", Score: 0.5},
+				{Category: "email", MatchType: "value", Score: 0.8},
+			},
+			"notes": {
+				{Category: "email", MatchType: "value", Score: 0.05},
+				{Category: "phone", MatchType: "value", Score: 0.3},
+			},
+		},
+	}
+
+	cats := getCategories(matches, source, "email", discoveryOptions{ScoreThreshold: 0.2})
+	assert.Len(t, cats, 1)
+	assert.Equal(t, "email", cats[0].CategoryID)
+	assert.InDelta(t, 0.9, *cats[0].Score, 1e-9)
+
+	// Weak evidence is dropped.
+	cats = getCategories(matches, source, "notes", discoveryOptions{ScoreThreshold: 0.2})
+	assert.Len(t, cats, 1)
+	assert.Equal(t, "phone", cats[0].CategoryID)
+
+	cats = getCategories(matches, source, "notes", discoveryOptions{})
+	assert.Len(t, cats, 2)
+}
+
+func TestGetPropertyDiscoveriesTypeChanged(t *testing.T) {
+	integer := "integer"
+	str := "string"
+	dataSource := &model.DataSource{ID: "ds1", Name: "users"}
+	prev := []*model.Property{
+		{ID: "p1", Name: "zip", Type: &integer},
+		{ID: "p2", Name: "name", Type: &str},
+		{ID: "p3", Name: "notes"},
+	}
+
+	discoveries := getPropertyDiscoveries(prev, map[string]*jsonschema.Schema{
+		"zip":   {Type: "string"},
+		"name":  {Type: "string"},
+		"notes": {Type: "object"},
+	}, nil, dataSource, discoveryOptions{})
+
+	// Properties that were never scanned have no type to compare with.
+	assert.Len(t, discoveries, 1)
+	assert.Equal(t, model.DiscoveryTypePropertyChanged, discoveries[0].Type)
+
+	data, err := discoveries[0].DeserializeData()
+	assert.NoError(t, err)
+	assert.Equal(t, model.PropertyChangedDiscovery{
+		ID:      "p1",
+		OldType: &integer,
+		NewType: &str,
+	}, data)
+
+	// A change in format is also a change.
+	discoveries = getPropertyDiscoveries(prev[1:2], map[string]*jsonschema.Schema{
+		"name": {Type: "string", Format: "date-time"},
+	}, nil, dataSource, discoveryOptions{})
+	assert.Len(t, discoveries, 1)
+}
+
+func TestGetPropertyDiscoveriesPrimaryKeySuggested(t *testing.T) {
+	source := NewDataSourceMatcher("users", nil)
+	dataSource := &model.DataSource{ID: "ds1", Name: "users"}
+	linked := "pk-user-id"
+	opts := discoveryOptions{PrimaryKeys: map[string]string{
+		"email":   "pk-email",
+		"user_id": "pk-user-id",
+	}}
+
+	prev := []*model.Property{
+		{ID: "p1", Name: "email", Categories: []*model.Category{{ID: "email"}}},
+		{ID: "p2", Name: "contact"},
+		{ID: "p3", Name: "id", Categories: []*model.Category{{ID: "user_id"}}, UserPrimaryKeyID: &linked},
+		{ID: "p4", Name: "owner", Categories: []*model.Category{{ID: "user_id"}}},
+	}
+
+	matches := map[DataSourceMatcher]map[string][]scanner.RuleMatch{
+		source: {
+			"contact": {{Category: "email", MatchType: "value", Score: 0.9}},
+		},
+	}
+
+	discoveries := getPropertyDiscoveries(prev, map[string]*jsonschema.Schema{
+		"email":   {Type: "string"},
+		"contact": {Type: "string"},
+		"id":      {Type: "string"},
+		"owner":   {Type: "string"},
+	}, matches, dataSource, opts)
+
+	suggested := map[string]string{}
+	for _, d := range discoveries {
+		if d.Type != model.DiscoveryTypePrimaryKeySuggested {
+			continue
+		}
+
+		data, err := d.DeserializeData()
+		assert.NoError(t, err)
+
+		s := data.(model.PrimaryKeySuggestedDiscovery)
+		suggested[s.PropertyID] = s.UserPrimaryKeyID
+	}
+
+	// The user_id key is already linked to a property of the data source,
+	// so it isn't suggested again.
+	assert.Equal(t, map[string]string{
+		"p1": "pk-email",
+		"p2": "pk-email",
+	}, suggested)
+}
+
+func TestGetPropertyDiscoveriesPrimaryKeyPrefilled(t *testing.T) {
+	source := NewDataSourceMatcher("users", nil)
+	dataSource := &model.DataSource{ID: "ds1", Name: "users"}
+	linked := "pk-user-id"
+	opts := discoveryOptions{PrimaryKeys: map[string]string{
+		"email":   "pk-email",
+		"user_id": "pk-user-id",
+	}}
+
+	prev := []*model.Property{
+		{ID: "p1", Name: "id", Categories: []*model.Category{{ID: "user_id"}}, UserPrimaryKeyID: &linked},
+	}
+
+	matches := map[DataSourceMatcher]map[string][]scanner.RuleMatch{
+		source: {
+			"owner":        {{Category: "user_id", MatchType: "name", Score: 0.9}},
+			"email":        {{Category: "email", MatchType: "value", Score: 0.9}},
+			"backup_email": {{Category: "email", MatchType: "value", Score: 0.9}},
+		},
+	}
+
+	discoveries := getPropertyDiscoveries(prev, map[string]*jsonschema.Schema{
+		"id":           {Type: "string"},
+		"owner":        {Type: "string"},
+		"email":        {Type: "string"},
+		"backup_email": {Type: "string"},
+	}, matches, dataSource, opts)
+
+	keys := map[string]*string{}
+	for _, d := range discoveries {
+		assert.Equal(t, model.DiscoveryTypePropertyFound, d.Type)
+
+		data, err := d.DeserializeData()
+		assert.NoError(t, err)
+
+		p := data.(model.NewPropertyDiscovery)
+		keys[p.Name] = p.UserPrimaryKeyID
+	}
+
+	// Each key is linked to one property, and keys that are already linked
+	// aren't used.
+	pkEmail := "pk-email"
+	assert.Equal(t, map[string]*string{
+		"backup_email": &pkEmail,
+		"email":        nil,
+		"owner":        nil,
+	}, keys)
+}
+
+func TestFileScanner(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("email jane@example.com"), 0600))
+
+	sc, err := basicscanner.NewBasicScanner(monoidprotocol.MonoidSchema{
+		Name:       "documents",
+		JsonSchema: map[string]interface{}{"type": "object"},
+	}, scanner.RuleConfig{})
+	assert.NoError(t, err)
+
+	fs := &fileScanner{TextScanner: sc, dir: dir}
+
+	fileType := monoidprotocol.MonoidRecordRecordTypeFILE
+	for _, f := range []string{"notes.txt", "../outside.txt"} {
+		file := f
+		assert.NoError(t, fs.Scan(&monoidprotocol.MonoidRecord{
+			SchemaName: "documents",
+			RecordType: &fileType,
+			File:       &file,
+		}))
+	}
+
+	assert.True(t, fs.hasFiles)
+	assert.NoError(t, fs.scanFile(fs.files[0]))
+	assert.Error(t, fs.scanFile(fs.files[1]))
+
+	// Scanned files are removed.
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	matches := fs.Summary()
+	assert.Len(t, matches, 1)
+	assert.Equal(t, scanner.FileProperty, matches[0].Identifier)
+	assert.Equal(t, "email", matches[0].Category)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/filestore"
 	"github.com/monoid-privacy/monoid/model"
 	"go.temporal.io/sdk/activity"
 )
@@ -41,7 +42,11 @@ func (a *Activity) FindOrCreateJob(ctx context.Context, jobIn JobInput) (model.J
 		}
 	}
 
-	wr, path, err := a.Conf.FileStore.NewWriter(ctx, uuid.NewString(), false)
+	wr, path, err := a.Conf.FileStore.NewWriter(
+		filestore.WithWorkspaceID(ctx, job.WorkspaceID),
+		uuid.NewString(),
+		false,
+	)
 	if err != nil {
 		logger.Error("Error creating file store")
 	}
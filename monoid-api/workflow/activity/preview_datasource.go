@@ -0,0 +1,382 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/monoid-privacy/monoid/scanner/basicscanner"
+	"go.temporal.io/sdk/activity"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPreviewRecords is the number of records previewed if no limit
+	// is given.
+	DefaultPreviewRecords = 5
+
+	// MaxPreviewRecords is the most records that can be previewed.
+	MaxPreviewRecords = 20
+
+	// MaxPreviewValueLength is the length strings in previews are truncated
+	// to.
+	MaxPreviewValueLength = 256
+
+	// MaxPreviewBytes is the most data (serialized as JSON) a preview
+	// returns, records past the limit are left out.
+	MaxPreviewBytes = 64 * 1024
+)
+
+// PreviewDataSourceArgs are the arguments to PreviewDataSource.
+type PreviewDataSourceArgs struct {
+	DataSourceID string
+	Limit        int
+
+	// DiscoveryID previews the data source of a DATA_SOURCE_FOUND discovery
+	// instead, so data sources can be previewed before they're accepted.
+	DiscoveryID string
+
+	// Unmask returns the values of sensitive properties as they are.
+	Unmask bool
+}
+
+// DataSourcePreview is a sample of the records of a data source.
+type DataSourcePreview struct {
+	Records []map[string]interface{} `json:"records"`
+
+	// Masked is true if the values of sensitive properties were masked.
+	Masked bool `json:"masked"`
+
+	// Truncated is true if records or values were left out or shortened to
+	// keep the preview under the size limits.
+	Truncated bool `json:"truncated"`
+}
+
+// previewSource is the data source a preview is made of.
+type previewSource struct {
+	Silo       model.SiloDefinition
+	Name       string
+	Group      *string
+	Properties []*model.Property
+}
+
+// getPreviewSource finds the data source to preview, either an existing
+// one or the one found by a discovery.
+func getPreviewSource(db *gorm.DB, args PreviewDataSourceArgs) (previewSource, error) {
+	if args.DiscoveryID == "" {
+		dataSource := model.DataSource{}
+		if err := db.Preload("Properties.Categories").Preload("SiloDefinition.SiloSpecification").Where(
+			"id = ?", args.DataSourceID,
+		).First(&dataSource).Error; err != nil {
+			return previewSource{}, err
+		}
+
+		return previewSource{
+			Silo:       dataSource.SiloDefinition,
+			Name:       dataSource.Name,
+			Group:      dataSource.Group,
+			Properties: dataSource.Properties,
+		}, nil
+	}
+
+	discovery := model.DataDiscovery{}
+	if err := db.Preload("SiloDefinition.SiloSpecification").Where(
+		"id = ?", args.DiscoveryID,
+	).First(&discovery).Error; err != nil {
+		return previewSource{}, err
+	}
+
+	if discovery.Type != model.DiscoveryTypeDataSourceFound {
+		return previewSource{}, fmt.Errorf("discovery %s is not a new data source", discovery.ID)
+	}
+
+	data := model.NewDataSourceDiscovery{}
+	if err := json.Unmarshal(discovery.Data, &data); err != nil {
+		return previewSource{}, err
+	}
+
+	return previewSource{
+		Silo:  discovery.SiloDefinition,
+		Name:  data.Name,
+		Group: data.Group,
+	}, nil
+}
+
+// PreviewDataSource scans a handful of records from a data source. The
+// preview is encrypted like a secret, since it's returned through the
+// workflow's history, and it isn't stored anywhere else.
+func (a *Activity) PreviewDataSource(ctx context.Context, args PreviewDataSourceArgs) ([]byte, error) {
+	logger := activity.GetLogger(ctx)
+
+	source, err := getPreviewSource(a.Conf.DB, args)
+	if err != nil {
+		return nil, err
+	}
+
+	silo := source.Silo
+
+	settings, err := GetWorkspaceSettings(a.Conf.DB, silo.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleConfig, err := getRuleConfig(a.Conf.DB, silo.WorkspaceID, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir(a.Conf.TempStorePath, "monoid")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	mp, err := a.Conf.ProtocolFactory.NewMonoidProtocol(
+		silo.SiloSpecification.DockerImage,
+		silo.SiloSpecification.DockerTag,
+		dir,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mp.Teardown(ctx)
+
+	if err := mp.InitConn(ctx); err != nil {
+		return nil, err
+	}
+
+	logChan, err := mp.AttachLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for l := range logChan {
+			logger.Info("container-log", "log", l.Message)
+		}
+	}()
+
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(silo.Config), &conf); err != nil {
+		return nil, err
+	}
+
+	schemas, err := mp.Schema(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := NewDataSourceMatcher(source.Name, source.Group)
+
+	var schema *monoidprotocol.MonoidSchema
+	for i, s := range schemas.Schemas {
+		if NewDataSourceMatcher(s.Name, s.Group) == matcher {
+			schema = &schemas.Schemas[i]
+			break
+		}
+	}
+
+	if schema == nil {
+		return nil, fmt.Errorf("data source %s is not in the silo's schema", source.Name)
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultPreviewRecords
+	}
+
+	if limit > MaxPreviewRecords {
+		limit = MaxPreviewRecords
+	}
+
+	// The scan is cancelled once there are enough records.
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	recordChan, _, err := mp.Scan(scanCtx, conf, monoidprotocol.MonoidSchemasMessage{
+		Schemas: []monoidprotocol.MonoidSchema{*schema},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := []monoidprotocol.MonoidRecord{}
+	for r := range recordChan {
+		if NewDataSourceMatcher(r.SchemaName, r.SchemaGroup) != matcher {
+			continue
+		}
+
+		records = append(records, r)
+		if len(records) == limit {
+			break
+		}
+	}
+
+	cancel()
+
+	go func() {
+		for range recordChan {
+		}
+	}()
+
+	sensitive, err := scannedProperties(*schema, records, ruleConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for p := range sensitiveProperties(source.Properties) {
+		sensitive[p] = true
+	}
+
+	preview := newPreview(records, sensitive, args.Unmask)
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.SecretString(data).ValueBytes()
+}
+
+// sensitiveProperties returns the names of the properties with categories.
+func sensitiveProperties(properties []*model.Property) map[string]bool {
+	res := map[string]bool{}
+
+	for _, p := range properties {
+		if len(p.Categories) != 0 {
+			res[p.Name] = true
+		}
+	}
+
+	return res
+}
+
+// scannedProperties returns the paths the scanner finds personal data in,
+// in the sampled records. Properties that haven't been categorized yet,
+// like those of new data sources, are masked if any of their values look
+// sensitive.
+func scannedProperties(
+	schema monoidprotocol.MonoidSchema,
+	records []monoidprotocol.MonoidRecord,
+	ruleConfig scanner.RuleConfig,
+) (map[string]bool, error) {
+	sc, err := basicscanner.NewBasicScanner(schema, ruleConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if err := sc.Scan(&records[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	res := map[string]bool{}
+	for _, m := range sc.Summary() {
+		res[m.Identifier] = true
+	}
+
+	return res, nil
+}
+
+// newPreview masks and truncates records to build a preview.
+func newPreview(
+	records []monoidprotocol.MonoidRecord,
+	sensitive map[string]bool,
+	unmask bool,
+) DataSourcePreview {
+	res := DataSourcePreview{
+		Records: []map[string]interface{}{},
+		Masked:  !unmask,
+	}
+
+	size := 0
+
+	for _, r := range records {
+		p := previewer{sensitive: sensitive, unmask: unmask}
+		record, _ := p.value(map[string]interface{}(r.Data), nil, false).(map[string]interface{})
+		if record == nil {
+			record = map[string]interface{}{}
+		}
+
+		if p.truncated {
+			res.Truncated = true
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+
+		if size+len(data) > MaxPreviewBytes {
+			res.Truncated = true
+			break
+		}
+
+		size += len(data)
+		res.Records = append(res.Records, record)
+	}
+
+	return res
+}
+
+type previewer struct {
+	sensitive map[string]bool
+	unmask    bool
+	truncated bool
+}
+
+// value returns the preview of a value at path. Everything under a
+// sensitive property is masked, unless the preview is unmasked.
+func (p *previewer) value(v interface{}, path []string, masked bool) interface{} {
+	if len(path) != 0 && p.sensitive[scanner.PathString(path)] && !p.unmask {
+		masked = true
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			res[k] = p.value(child, append(path[:len(path):len(path)], k), masked)
+		}
+
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, child := range val {
+			res[i] = p.value(child, append(path[:len(path):len(path)], scanner.ArraySegment), masked)
+		}
+
+		return res
+	case nil:
+		return nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		if !masked {
+			return v
+		}
+
+		s = fmt.Sprint(v)
+	}
+
+	if masked {
+		return basicscanner.MaskValue(s)
+	}
+
+	r := []rune(s)
+	if len(r) > MaxPreviewValueLength {
+		p.truncated = true
+		return string(r[:MaxPreviewValueLength])
+	}
+
+	return s
+}
@@ -0,0 +1,110 @@
+package activity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/monoidprotocol"
+	"github.com/monoid-privacy/monoid/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPreview(t *testing.T) {
+	records := []monoidprotocol.MonoidRecord{{
+		SchemaName: "users",
+		Data: monoidprotocol.MonoidRecordData{
+			"email": "jane@example.com",
+			"age":   42,
+			"bio":   strings.Repeat("a", MaxPreviewValueLength+10),
+			"address": map[string]interface{}{
+				"zip":  "94107",
+				"city": "Paris",
+			},
+			"contacts": []interface{}{
+				map[string]interface{}{"email": "joe@example.com"},
+			},
+		},
+	}}
+
+	sensitive := map[string]bool{
+		"email":            true,
+		"address":          true,
+		"contacts[].email": true,
+	}
+
+	preview := newPreview(records, sensitive, false)
+	assert.True(t, preview.Masked)
+	assert.True(t, preview.Truncated)
+	assert.Len(t, preview.Records, 1)
+
+	record := preview.Records[0]
+	assert.Equal(t, "j***@e***.com", record["email"])
+	assert.Equal(t, 42, record["age"])
+	assert.Len(t, record["bio"], MaxPreviewValueLength)
+
+	// Everything under a sensitive property is masked.
+	assert.Equal(t, map[string]interface{}{"zip": "9***", "city": "P***"}, record["address"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"email": "j***@e***.com"},
+	}, record["contacts"])
+
+	preview = newPreview(records, sensitive, true)
+	assert.False(t, preview.Masked)
+	assert.Equal(t, "jane@example.com", preview.Records[0]["email"])
+}
+
+func TestNewPreviewSizeLimit(t *testing.T) {
+	records := []monoidprotocol.MonoidRecord{}
+	for i := 0; i < MaxPreviewRecords; i++ {
+		records = append(records, monoidprotocol.MonoidRecord{
+			Data: monoidprotocol.MonoidRecordData{
+				"a": strings.Repeat("a", MaxPreviewValueLength),
+				"b": strings.Repeat("b", MaxPreviewValueLength),
+				"c": strings.Repeat("c", MaxPreviewValueLength),
+				"d": strings.Repeat("d", MaxPreviewValueLength),
+				"e": strings.Repeat("e", MaxPreviewValueLength),
+				"f": strings.Repeat("f", MaxPreviewValueLength),
+				"g": strings.Repeat("g", MaxPreviewValueLength),
+				"h": strings.Repeat("h", MaxPreviewValueLength),
+				"i": strings.Repeat("i", MaxPreviewValueLength),
+				"j": strings.Repeat("j", MaxPreviewValueLength),
+				"k": strings.Repeat("k", MaxPreviewValueLength),
+				"l": strings.Repeat("l", MaxPreviewValueLength),
+				"m": strings.Repeat("m", MaxPreviewValueLength),
+				"n": strings.Repeat("n", MaxPreviewValueLength),
+			},
+		})
+	}
+
+	preview := newPreview(records, nil, false)
+	assert.True(t, preview.Truncated)
+	assert.Less(t, len(preview.Records), MaxPreviewRecords)
+}
+
+func TestScannedProperties(t *testing.T) {
+	schema := monoidprotocol.MonoidSchema{
+		Name: "users",
+		JsonSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"contact": map[string]interface{}{"type": "string"},
+				"status":  map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+
+	records := []monoidprotocol.MonoidRecord{
+		{SchemaName: "users", Data: monoidprotocol.MonoidRecordData{"contact": "jane@example.com", "status": "active"}},
+		{SchemaName: "users", Data: monoidprotocol.MonoidRecordData{"contact": "joe@example.org", "status": "inactive"}},
+	}
+
+	// Neither property has a category yet, but the scanner finds emails in
+	// one of them.
+	sensitive, err := scannedProperties(schema, records, scanner.RuleConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"contact": true}, sensitive)
+
+	preview := newPreview(records, sensitive, false)
+	assert.Equal(t, "j***@e***.com", preview.Records[0]["contact"])
+	assert.Equal(t, "active", preview.Records[0]["status"])
+}
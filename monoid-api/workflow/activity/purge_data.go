@@ -0,0 +1,179 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/model"
+	"go.temporal.io/sdk/activity"
+	"gorm.io/gorm"
+)
+
+type FindExpiredRequestsArgs struct {
+	Now time.Time `json:"now"`
+}
+
+// finishedJobStatuses are the statuses of jobs that won't be updated
+// again.
+var finishedJobStatuses = []model.JobStatus{
+	model.JobStatusCompleted,
+	model.JobStatusPartialFailed,
+	model.JobStatusFailed,
+}
+
+// FindExpiredRequests returns the ids of all requests that finished before
+// their workspace's retention period, and haven't been purged yet. Requests
+// that are still running are never purged.
+func (a *Activity) FindExpiredRequests(
+	ctx context.Context,
+	args FindExpiredRequestsArgs,
+) ([]string, error) {
+	logger := activity.GetLogger(ctx)
+
+	workspaces := []model.Workspace{}
+	if err := a.Conf.DB.Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+
+	requestIDs := []string{}
+
+	for _, w := range workspaces {
+		settings := model.WorkspaceSettings{}
+		if err := json.Unmarshal(w.Settings, &settings); err != nil {
+			logger.Error("Error parsing workspace settings", "workspace", w.ID, "error", err)
+			continue
+		}
+
+		if settings.RequestRetentionDays <= 0 {
+			continue
+		}
+
+		cutoff := args.Now.AddDate(0, 0, -settings.RequestRetentionDays)
+
+		// The job is updated when the request finishes, so its update time
+		// is when the request completed.
+		ids := []string{}
+		if err := a.Conf.DB.Model(&model.Request{}).Joins(
+			"JOIN jobs ON jobs.id = requests.job_id",
+		).Where(
+			"requests.workspace_id = ? AND jobs.status IN ? AND jobs.updated_at < ?",
+			w.ID,
+			finishedJobStatuses,
+			cutoff,
+		).Where(
+			"requests.id NOT IN (?)",
+			a.Conf.DB.Model(&model.RequestTombstone{}).Select("request_id"),
+		).Pluck("requests.id", &ids).Error; err != nil {
+			return nil, err
+		}
+
+		requestIDs = append(requestIDs, ids...)
+	}
+
+	return requestIDs, nil
+}
+
+type PurgeRequestDataArgs struct {
+	RequestID string    `json:"requestId"`
+	Now       time.Time `json:"now"`
+}
+
+// PurgeRequestData removes the query results, files and primary key values
+// of a request, and records a tombstone for it. Files are removed before
+// the database rows that reference them, so the activity can be retried if
+// it fails part of the way through.
+func (a *Activity) PurgeRequestData(
+	ctx context.Context,
+	args PurgeRequestDataArgs,
+) (model.RequestTombstone, error) {
+	logger := activity.GetLogger(ctx)
+
+	request := model.Request{}
+	if err := a.Conf.DB.Preload("RequestStatuses").Preload(
+		"RequestStatuses.QueryResult",
+	).Preload("DownloadableFile").Where(
+		"id = ?", args.RequestID,
+	).First(&request).Error; err != nil {
+		return model.RequestTombstone{}, err
+	}
+
+	tombstone := model.RequestTombstone{
+		ID:          uuid.NewString(),
+		RequestID:   request.ID,
+		WorkspaceID: request.WorkspaceID,
+		PurgedAt:    args.Now,
+	}
+
+	queryResultIDs := []string{}
+
+	for _, rs := range request.RequestStatuses {
+		if rs.QueryResult == nil {
+			continue
+		}
+
+		queryResultIDs = append(queryResultIDs, rs.QueryResult.ID)
+
+		if rs.QueryResult.ResultType != model.ResultTypeFile || rs.QueryResult.Records == nil {
+			continue
+		}
+
+		data := model.QueryResultFileData{}
+		if err := json.Unmarshal([]byte(*rs.QueryResult.Records), &data); err != nil {
+			logger.Error("Error decoding file data", "queryResult", rs.QueryResult.ID, "error", err)
+			continue
+		}
+
+		if data.FilePath == "" {
+			continue
+		}
+
+		if err := a.Conf.FileStore.Delete(ctx, data.FilePath, false); err != nil {
+			return model.RequestTombstone{}, err
+		}
+
+		tombstone.FilesPurged++
+	}
+
+	df := request.DownloadableFile
+	if df != nil && df.PurgedAt == nil && df.StoragePath != "" {
+		if err := a.Conf.FileStore.Delete(ctx, df.StoragePath, false); err != nil {
+			return model.RequestTombstone{}, err
+		}
+
+		tombstone.FilesPurged++
+	}
+
+	tombstone.QueryResultsPurged = len(queryResultIDs)
+
+	if err := a.Conf.DB.Transaction(func(tx *gorm.DB) error {
+		if len(queryResultIDs) > 0 {
+			if err := tx.Where("id IN ?", queryResultIDs).Delete(&model.QueryResult{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if df != nil && df.PurgedAt == nil {
+			if err := tx.Model(df).Updates(map[string]interface{}{
+				"storage_path": "",
+				"purged_at":    args.Now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		res := tx.Where("request_id = ?", request.ID).Delete(&model.PrimaryKeyValue{})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		tombstone.PrimaryKeyValuesPurged = int(res.RowsAffected)
+
+		return tx.Create(&tombstone).Error
+	}); err != nil {
+		return model.RequestTombstone{}, err
+	}
+
+	return tombstone, nil
+}
@@ -0,0 +1,241 @@
+package activity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/filestore"
+	"github.com/monoid-privacy/monoid/filestore/localstore"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"go.temporal.io/sdk/testsuite"
+	"gorm.io/gorm"
+)
+
+const testEncKey = "Tc7ILcxCi68Xk7646IrNBYmbMzbWNU+s94fnZMJ1zzk="
+
+type purgeDataTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	container testcontainers.Container
+	db        *gorm.DB
+	dir       string
+	store     filestore.FileStore
+	a         *Activity
+	env       *testsuite.TestActivityEnvironment
+}
+
+func (s *purgeDataTestSuite) SetupSuite() {
+	container, db, err := testutil.SetupDB()
+	if err != nil {
+		s.T().Skipf("could not start postgres: %v", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(testEncKey)
+	s.Require().NoError(err)
+	model.SetEncryptionKey(key)
+
+	s.container = container
+	s.db = db
+}
+
+func (s *purgeDataTestSuite) TearDownSuite() {
+	if s.container != nil {
+		s.container.Terminate(context.Background())
+	}
+}
+
+func (s *purgeDataTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+	s.store = localstore.NewLocalFileStore(s.dir)
+	s.a = &Activity{Conf: &config.BaseConfig{DB: s.db, FileStore: s.store}}
+
+	s.env = s.NewTestActivityEnvironment()
+	s.env.RegisterActivity(s.a.FindExpiredRequests)
+	s.env.RegisterActivity(s.a.PurgeRequestData)
+}
+
+func (s *purgeDataTestSuite) workspace(retentionDays int) model.Workspace {
+	settings, err := json.Marshal(model.WorkspaceSettings{RequestRetentionDays: retentionDays})
+	s.Require().NoError(err)
+
+	w := model.Workspace{ID: uuid.NewString(), Settings: settings}
+	s.Require().NoError(s.db.Create(&w).Error)
+
+	return w
+}
+
+// request creates a request with a job that was last updated at
+// updatedAt.
+func (s *purgeDataTestSuite) request(
+	workspaceID string,
+	status model.JobStatus,
+	updatedAt time.Time,
+) model.Request {
+	job := model.Job{
+		ID:          uuid.NewString(),
+		WorkspaceID: workspaceID,
+		Status:      status,
+		CreatedAt:   updatedAt.AddDate(0, 0, -1),
+		UpdatedAt:   updatedAt,
+	}
+	s.Require().NoError(s.db.Create(&job).Error)
+
+	request := model.Request{
+		ID:          uuid.NewString(),
+		WorkspaceID: workspaceID,
+		Type:        model.UserDataRequestTypeQuery,
+		JobID:       &job.ID,
+		CreatedAt:   updatedAt.AddDate(0, 0, -1),
+	}
+	s.Require().NoError(s.db.Create(&request).Error)
+
+	return request
+}
+
+func (s *purgeDataTestSuite) writeFile(name string) string {
+	wr, fp, err := s.store.NewWriter(context.Background(), name, false)
+	s.Require().NoError(err)
+
+	_, err = wr.Write([]byte("results"))
+	s.Require().NoError(err)
+	s.Require().NoError(wr.Close())
+
+	return fp
+}
+
+func (s *purgeDataTestSuite) TestFindExpiredRequests() {
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+
+	w := s.workspace(30)
+	expired := s.request(w.ID, model.JobStatusCompleted, old)
+	failed := s.request(w.ID, model.JobStatusFailed, old)
+
+	// Requests that are still running, finished recently or were already
+	// purged aren't returned.
+	s.request(w.ID, model.JobStatusRunning, old)
+	s.request(w.ID, model.JobStatusCompleted, now.AddDate(0, 0, -5))
+
+	purged := s.request(w.ID, model.JobStatusCompleted, old)
+	s.Require().NoError(s.db.Create(&model.RequestTombstone{
+		ID:          uuid.NewString(),
+		RequestID:   purged.ID,
+		WorkspaceID: w.ID,
+		PurgedAt:    old,
+	}).Error)
+
+	// Workspaces without a retention period keep their results forever.
+	keepForever := s.workspace(0)
+	s.request(keepForever.ID, model.JobStatusCompleted, old)
+
+	val, err := s.env.ExecuteActivity(s.a.FindExpiredRequests, FindExpiredRequestsArgs{Now: now})
+	s.Require().NoError(err)
+
+	ids := []string{}
+	s.Require().NoError(val.Get(&ids))
+	s.ElementsMatch([]string{expired.ID, failed.ID}, ids)
+}
+
+func (s *purgeDataTestSuite) TestPurgeRequestData() {
+	now := time.Now()
+	w := s.workspace(30)
+
+	spec := model.SiloSpecification{ID: uuid.NewString(), Name: "spec"}
+	s.Require().NoError(s.db.Create(&spec).Error)
+
+	silo := model.SiloDefinition{
+		ID:                  uuid.NewString(),
+		WorkspaceID:         w.ID,
+		SiloSpecificationID: spec.ID,
+	}
+	s.Require().NoError(s.db.Create(&silo).Error)
+
+	ds := model.DataSource{ID: uuid.NewString(), SiloDefinitionID: silo.ID, Name: "users"}
+	s.Require().NoError(s.db.Create(&ds).Error)
+
+	pk := model.UserPrimaryKey{ID: uuid.NewString(), WorkspaceID: w.ID, APIIdentifier: "email"}
+	s.Require().NoError(s.db.Create(&pk).Error)
+
+	resultPath := s.writeFile("result")
+	downloadPath := s.writeFile("download.tar.gz")
+
+	df := model.DownloadableFile{ID: uuid.NewString(), StoragePath: downloadPath}
+	s.Require().NoError(s.db.Create(&df).Error)
+
+	request := s.request(w.ID, model.JobStatusCompleted, now.AddDate(0, 0, -40))
+	s.Require().NoError(s.db.Model(&request).Update("downloadable_file_id", df.ID).Error)
+
+	s.Require().NoError(s.db.Create(&model.PrimaryKeyValue{
+		ID:               uuid.NewString(),
+		UserPrimaryKeyID: pk.ID,
+		RequestID:        request.ID,
+		Value:            "jane@example.com",
+	}).Error)
+
+	records, err := json.Marshal(model.QueryResultFileData{FilePath: resultPath})
+	s.Require().NoError(err)
+	recordsSecret := model.SecretString(records)
+
+	rs := model.RequestStatus{
+		ID:           uuid.NewString(),
+		RequestID:    request.ID,
+		DataSourceID: ds.ID,
+		Status:       model.RequestStatusTypeExecuted,
+		QueryResult: &model.QueryResult{
+			ID:         uuid.NewString(),
+			ResultType: model.ResultTypeFile,
+			Records:    &recordsSecret,
+		},
+	}
+	s.Require().NoError(s.db.Create(&rs).Error)
+
+	val, err := s.env.ExecuteActivity(s.a.PurgeRequestData, PurgeRequestDataArgs{
+		RequestID: request.ID,
+		Now:       now,
+	})
+	s.Require().NoError(err)
+
+	tombstone := model.RequestTombstone{}
+	s.Require().NoError(val.Get(&tombstone))
+	s.Equal(1, tombstone.QueryResultsPurged)
+	s.Equal(2, tombstone.FilesPurged)
+	s.Equal(1, tombstone.PrimaryKeyValuesPurged)
+
+	// The files are removed from the file store.
+	for _, fp := range []string{resultPath, downloadPath} {
+		_, err := os.Stat(filepath.Join(s.dir, fp))
+		s.True(os.IsNotExist(err), fp)
+	}
+
+	var count int64
+	s.Require().NoError(s.db.Model(&model.QueryResult{}).Where("id = ?", rs.QueryResult.ID).Count(&count).Error)
+	s.Zero(count)
+
+	s.Require().NoError(s.db.Model(&model.PrimaryKeyValue{}).Where("request_id = ?", request.ID).Count(&count).Error)
+	s.Zero(count)
+
+	s.Require().NoError(s.db.Model(&model.RequestTombstone{}).Where("request_id = ?", request.ID).Count(&count).Error)
+	s.Equal(int64(1), count)
+
+	// The download link stays, but returns 410 Gone (see the download
+	// handler's tests).
+	purgedFile := model.DownloadableFile{}
+	s.Require().NoError(s.db.Where("id = ?", df.ID).First(&purgedFile).Error)
+	s.NotNil(purgedFile.PurgedAt)
+	s.Empty(purgedFile.StoragePath)
+}
+
+func TestPurgeDataSuite(t *testing.T) {
+	suite.Run(t, new(purgeDataTestSuite))
+}
@@ -13,6 +13,7 @@ import (
 	"sync"
 
 	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/filestore"
 	"github.com/monoid-privacy/monoid/model"
 	"github.com/monoid-privacy/monoid/monoidprotocol"
 	monoidactivity "github.com/monoid-privacy/monoid/workflow/activity"
@@ -311,7 +312,10 @@ func (a *RequestActivity) ProcessRequestResults(
 
 					f := filepath.Join(dir, *record.File)
 
-					fp, err := a.copyTarGzToStorage(ctx, f)
+					fp, err := a.copyTarGzToStorage(
+						filestore.WithWorkspaceID(ctx, request.WorkspaceID),
+						f,
+					)
 					if err != nil {
 						logger.Error("Error copying file", err)
 					}
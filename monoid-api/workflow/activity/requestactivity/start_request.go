@@ -76,6 +76,33 @@ type StartRequestArgs struct {
 	RequestID        string `json:"requestId"`
 }
 
+// unmappedResult returns the result for a data source that isn't linked to
+// a user primary key. Data sources with personal data are flagged on their
+// request status, and fail in workspaces that fail unmapped data sources.
+func (a *RequestActivity) unmappedResult(
+	requestStatus model.RequestStatus,
+	ds *model.DataSource,
+	settings model.WorkspaceSettings,
+) *RequestStatusItem {
+	if ds.UnmappedCategories() == nil {
+		return &RequestStatusItem{FullyComplete: true}
+	}
+
+	if err := a.Conf.DB.Model(&model.RequestStatus{ID: requestStatus.ID}).Update(
+		"unmapped_personal_data", true,
+	).Error; err != nil {
+		return &RequestStatusItem{Error: &RequestStatusError{Message: err.Error()}}
+	}
+
+	if settings.FailUnmappedDataSources {
+		return &RequestStatusItem{Error: &RequestStatusError{
+			Message: "data source has personal data, but no properties linked to a user primary key",
+		}}
+	}
+
+	return &RequestStatusItem{FullyComplete: true}
+}
+
 // StartRequestOnDataSource starts the request and returns the status
 // of the request, along with an indicator of if the request was already
 // finished.
@@ -92,7 +119,7 @@ func (a *RequestActivity) StartSiloRequestActivity(
 	if err := a.Conf.DB.Where(
 		"id = ?",
 		args.SiloDefinitionID,
-	).Preload("DataSources").Preload("DataSources.Properties").Preload("SiloSpecification").Preload(
+	).Preload("DataSources").Preload("DataSources.Properties.Categories").Preload("SiloSpecification").Preload(
 		"DataSources.RequestStatuses",
 		"request_id = ?",
 		args.RequestID,
@@ -123,6 +150,11 @@ func (a *RequestActivity) StartSiloRequestActivity(
 		return RequestStatusResult{}, err
 	}
 
+	settings, err := monoidactivity.GetWorkspaceSettings(a.Conf.DB, siloDef.WorkspaceID)
+	if err != nil {
+		return RequestStatusResult{}, err
+	}
+
 	primaryKeyMap := make(map[string]*model.PrimaryKeyValue)
 
 	for _, primaryKeyValue := range request.PrimaryKeyValues {
@@ -216,7 +248,7 @@ L:
 		}
 
 		if len(pkProperties) == 0 {
-			results[requestStatus.ID] = &RequestStatusItem{FullyComplete: true}
+			results[requestStatus.ID] = a.unmappedResult(requestStatus, ds, settings)
 			continue
 		}
 
@@ -0,0 +1,46 @@
+package activity
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/notification"
+)
+
+// NotificationTimeout is how long a channel has to respond to a
+// notification.
+const NotificationTimeout = 10 * time.Second
+
+// FindNotificationChannels returns the ids of a workspace's notification
+// channels.
+func (a *Activity) FindNotificationChannels(ctx context.Context, workspaceID string) ([]string, error) {
+	ids := []string{}
+	if err := a.Conf.DB.Model(&model.NotificationChannel{}).Where(
+		"workspace_id = ?", workspaceID,
+	).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// SendNotificationArgs are the arguments to SendNotification.
+type SendNotificationArgs struct {
+	ChannelID string
+	Event     *notification.DiscoveryEvent
+}
+
+// SendNotification sends an event to a notification channel. Failed
+// deliveries return an error so the activity is retried.
+func (a *Activity) SendNotification(ctx context.Context, args SendNotificationArgs) error {
+	channel := model.NotificationChannel{}
+	if err := a.Conf.DB.Where("id = ?", args.ChannelID).First(&channel).Error; err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: NotificationTimeout}
+
+	return notification.Send(ctx, client, &channel, args.Event)
+}
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"go.temporal.io/sdk/workflow"
+)
+
+// DefaultDetectAllConcurrency is the number of silos that are scanned at
+// once when a workspace's silos are all scanned.
+const DefaultDetectAllConcurrency = 3
+
+type DetectAllDSArgs struct {
+	WorkspaceID string
+
+	// Silos has the arguments of the scan of each silo, with the job that
+	// was created for it.
+	Silos []DetectDSArgs
+
+	// Concurrency is the maximum number of silos that are scanned at
+	// once, DefaultDetectAllConcurrency is used if it's 0.
+	Concurrency int
+}
+
+// DetectAllDSWorkflow scans all the silos in a workspace for data sources,
+// running DetectDSWorkflow as a child workflow for each one. A silo that
+// fails to be scanned doesn't stop the others, its job is marked as
+// failed.
+func (w *Workflow) DetectAllDSWorkflow(
+	ctx workflow.Context,
+	args DetectAllDSArgs,
+) error {
+	logger := workflow.GetLogger(ctx)
+
+	concurrency := args.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDetectAllConcurrency
+	}
+
+	selector := workflow.NewSelector(ctx)
+	running := 0
+
+	for _, silo := range args.Silos {
+		if running >= concurrency {
+			selector.Select(ctx)
+			running--
+		}
+
+		silo := silo
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID: silo.JobID,
+		})
+
+		selector.AddFuture(
+			workflow.ExecuteChildWorkflow(childCtx, w.DetectDSWorkflow, silo),
+			func(f workflow.Future) {
+				if err := f.Get(ctx, nil); err != nil {
+					logger.Error("Error scanning silo", "silo", silo.SiloDefID, "error", err)
+				}
+			},
+		)
+
+		running++
+	}
+
+	for ; running > 0; running-- {
+		selector.Select(ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	schedulepb "go.temporal.io/api/schedule/v1"
+	"go.temporal.io/sdk/testsuite"
+)
+
+type detectAllTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	w   *Workflow
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (s *detectAllTestSuite) SetupTest() {
+	s.w = &Workflow{Conf: &config.BaseConfig{}}
+
+	s.env = s.NewTestWorkflowEnvironment()
+	s.env.RegisterWorkflow(s.w.DetectAllDSWorkflow)
+	s.env.RegisterWorkflow(s.w.DetectDSWorkflow)
+}
+
+func (s *detectAllTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+// TestDetectAllContinuesOnError verifies that every silo is scanned, even
+// if scanning one of them fails.
+func (s *detectAllTestSuite) TestDetectAllContinuesOnError() {
+	s.env.OnWorkflow(s.w.DetectDSWorkflow, mock.Anything, mock.MatchedBy(
+		func(args DetectDSArgs) bool { return args.SiloDefID == "s1" },
+	)).Return(fmt.Errorf("scan failed")).Once()
+
+	s.env.OnWorkflow(s.w.DetectDSWorkflow, mock.Anything, mock.MatchedBy(
+		func(args DetectDSArgs) bool { return args.SiloDefID != "s1" },
+	)).Return(nil).Times(4)
+
+	silos := []DetectDSArgs{}
+	for i := 1; i <= 5; i++ {
+		silos = append(silos, DetectDSArgs{
+			SiloDefID:   fmt.Sprintf("s%d", i),
+			WorkspaceID: "w",
+			JobID:       fmt.Sprintf("j%d", i),
+		})
+	}
+
+	s.env.ExecuteWorkflow(s.w.DetectAllDSWorkflow, DetectAllDSArgs{
+		WorkspaceID: "w",
+		Silos:       silos,
+		Concurrency: 2,
+	})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func TestDetectAllSuite(t *testing.T) {
+	suite.Run(t, new(detectAllTestSuite))
+}
+
+func TestDiscoverySchedule(t *testing.T) {
+	sched, err := discoverySchedule(model.DiscoverySchedule{
+		SiloDefinitionID: "s1",
+		WorkspaceID:      "w",
+		Cron:             "*/30 2 * * 1-5",
+		JitterSeconds:    60,
+		Paused:           true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*schedulepb.CalendarSpec{{
+		Second:     "0",
+		Minute:     "*/30",
+		Hour:       "2",
+		DayOfMonth: "*",
+		Month:      "*",
+		DayOfWeek:  "1-5",
+		Year:       "*",
+	}}, sched.Spec.Calendar)
+	assert.Equal(t, 60.0, sched.Spec.Jitter.Seconds())
+	assert.True(t, sched.State.Paused)
+	assert.Equal(t, "discover-sources-s1", sched.Action.GetStartWorkflow().WorkflowId)
+
+	_, err = discoverySchedule(model.DiscoverySchedule{Cron: "0 2 * *"})
+	assert.Error(t, err)
+
+	_, err = discoverySchedule(model.DiscoverySchedule{Cron: "0 2 * * mon"})
+	assert.Error(t, err)
+}
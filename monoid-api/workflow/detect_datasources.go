@@ -4,15 +4,23 @@ import (
 	"time"
 
 	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/notification"
 	"github.com/monoid-privacy/monoid/workflow/activity"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
+// discoveryNotificationsChange is the change id of the version of
+// DetectDSWorkflow that sends notifications.
+const discoveryNotificationsChange = "discovery-notifications"
+
 type DetectDSArgs struct {
 	SiloDefID   string
 	WorkspaceID string
-	JobID       string
+
+	// JobID is the id of the job the workflow updates. Scheduled runs
+	// don't have one, so a new job is created for them.
+	JobID string
 }
 
 func (w *Workflow) DetectDSWorkflow(
@@ -41,7 +49,13 @@ func (w *Workflow) DetectDSWorkflow(
 	ctx = workflow.WithActivityOptions(ctx, options)
 	ac := activity.Activity{}
 
+	job := model.Job{ID: args.JobID}
+
 	defer func() {
+		if job.ID == "" {
+			return
+		}
+
 		status := model.JobStatusCompleted
 
 		if err != nil {
@@ -49,7 +63,7 @@ func (w *Workflow) DetectDSWorkflow(
 		}
 
 		terr := workflow.ExecuteActivity(cleanupCtx, ac.UpdateJobStatus, activity.JobStatusInput{
-			ID:     args.JobID,
+			ID:     job.ID,
 			Status: status,
 		}).Get(ctx, nil)
 
@@ -59,7 +73,6 @@ func (w *Workflow) DetectDSWorkflow(
 	}()
 
 	// Get or create (if this is scheduled) the job
-	job := model.Job{}
 	err = workflow.ExecuteActivity(ctx, ac.FindOrCreateJob, activity.JobInput{
 		ID:          args.JobID,
 		WorkspaceID: args.WorkspaceID,
@@ -72,17 +85,73 @@ func (w *Workflow) DetectDSWorkflow(
 		return err
 	}
 
-	numDiscoveries := 0
-
-	// Run the detection activity
-	err = workflow.ExecuteActivity(ctx, ac.DetectDataSources, activity.DetectDSArgs{
+	dsArgs := activity.DetectDSArgs{
 		SiloID:        args.SiloDefID,
 		LogObjectName: job.LogObject,
-	}).Get(ctx, &numDiscoveries)
+	}
+
+	// Runs that started before notifications were added still replay the
+	// activity that returns the number of discoveries.
+	v := workflow.GetVersion(ctx, discoveryNotificationsChange, workflow.DefaultVersion, 1)
+	if v == workflow.DefaultVersion {
+		numDiscoveries := 0
+
+		// Run the detection activity
+		return workflow.ExecuteActivity(ctx, ac.DetectDataSources, dsArgs).Get(ctx, &numDiscoveries)
+	}
+
+	res := activity.DetectDSResult{}
+
+	// Run the detection activity
+	err = workflow.ExecuteActivity(ctx, ac.DetectDataSourcesWithEvent, dsArgs).Get(ctx, &res)
 
 	if err != nil {
 		return err
 	}
 
+	if res.Event != nil {
+		w.sendNotifications(ctx, res.Event)
+	}
+
 	return nil
 }
+
+// sendNotifications sends an event to each of the workspace's notification
+// channels. Deliveries are retried, but failures are only logged, since
+// they shouldn't fail the scan.
+func (w *Workflow) sendNotifications(ctx workflow.Context, event *notification.DiscoveryEvent) {
+	logger := workflow.GetLogger(ctx)
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute * 1,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second * 5,
+			BackoffCoefficient: 2,
+			MaximumAttempts:    5,
+		},
+	})
+
+	ac := activity.Activity{}
+
+	channelIDs := []string{}
+	if err := workflow.ExecuteActivity(
+		ctx, ac.FindNotificationChannels, event.WorkspaceID,
+	).Get(ctx, &channelIDs); err != nil {
+		logger.Error("Error finding notification channels", "error", err)
+		return
+	}
+
+	futures := make([]workflow.Future, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		futures = append(futures, workflow.ExecuteActivity(ctx, ac.SendNotification, activity.SendNotificationArgs{
+			ChannelID: id,
+			Event:     event,
+		}))
+	}
+
+	for i, f := range futures {
+		if err := f.Get(ctx, nil); err != nil {
+			logger.Error("Error sending notification", "channel", channelIDs[i], "error", err)
+		}
+	}
+}
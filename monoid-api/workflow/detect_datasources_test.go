@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/workflow/activity"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+type detectDSTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	w   *Workflow
+	a   *activity.Activity
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (s *detectDSTestSuite) SetupTest() {
+	s.w = &Workflow{Conf: &config.BaseConfig{}}
+	s.a = &activity.Activity{}
+
+	s.env = s.NewTestWorkflowEnvironment()
+	s.env.RegisterWorkflow(s.w.DetectDSWorkflow)
+	s.env.RegisterActivity(s.a.FindOrCreateJob)
+	s.env.RegisterActivity(s.a.UpdateJobStatus)
+	s.env.RegisterActivity(s.a.DetectDataSources)
+	s.env.RegisterActivity(s.a.DetectDataSourcesWithEvent)
+
+	s.env.OnActivity(s.a.FindOrCreateJob, mock.Anything, mock.Anything).Return(model.Job{ID: "j1"}, nil)
+	s.env.OnActivity(s.a.UpdateJobStatus, mock.Anything, mock.Anything).Return(nil)
+}
+
+func (s *detectDSTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *detectDSTestSuite) TestDetectWithEvent() {
+	s.env.OnActivity(s.a.DetectDataSourcesWithEvent, mock.Anything, mock.Anything).Return(
+		activity.DetectDSResult{NumDiscoveries: 2}, nil,
+	).Once()
+
+	s.env.ExecuteWorkflow(s.w.DetectDSWorkflow, DetectDSArgs{SiloDefID: "s1", WorkspaceID: "w", JobID: "j1"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+// TestDetectBeforeNotifications checks that runs that started before
+// notifications were added keep running the activity that returns the
+// number of discoveries.
+func (s *detectDSTestSuite) TestDetectBeforeNotifications() {
+	s.env.OnGetVersion(discoveryNotificationsChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+	s.env.OnActivity(s.a.DetectDataSources, mock.Anything, mock.Anything).Return(2, nil).Once()
+
+	s.env.ExecuteWorkflow(s.w.DetectDSWorkflow, DetectDSArgs{SiloDefID: "s1", WorkspaceID: "w", JobID: "j1"})
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func TestDetectDSSuite(t *testing.T) {
+	suite.Run(t, new(detectDSTestSuite))
+}
@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/model"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	schedulepb "go.temporal.io/api/schedule/v1"
+	"go.temporal.io/api/serviceerror"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// detectDSWorkflowName is the name DetectDSWorkflow is registered with.
+const detectDSWorkflowName = "DetectDSWorkflow"
+
+// DiscoveryScheduleID returns the id of the Temporal schedule that runs
+// discovery on a silo.
+func DiscoveryScheduleID(siloDefID string) string {
+	return "discover-sources-" + siloDefID
+}
+
+// discoverySchedule converts a discovery schedule to a Temporal schedule
+// that starts DetectDSWorkflow. Scheduled runs don't have a job id, so
+// the workflow creates a job for each run.
+func discoverySchedule(schedule model.DiscoverySchedule) (*schedulepb.Schedule, error) {
+	cron, err := model.ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := converter.GetDefaultDataConverter().ToPayloads(DetectDSArgs{
+		SiloDefID:   schedule.SiloDefinitionID,
+		WorkspaceID: schedule.WorkspaceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jitter := time.Duration(schedule.JitterSeconds) * time.Second
+
+	return &schedulepb.Schedule{
+		Spec: &schedulepb.ScheduleSpec{
+			Calendar: []*schedulepb.CalendarSpec{calendarSpec(cron)},
+			Jitter:   &jitter,
+		},
+		Action: &schedulepb.ScheduleAction{
+			Action: &schedulepb.ScheduleAction_StartWorkflow{
+				StartWorkflow: &workflowpb.NewWorkflowExecutionInfo{
+					WorkflowId:   DiscoveryScheduleID(schedule.SiloDefinitionID),
+					WorkflowType: &commonpb.WorkflowType{Name: detectDSWorkflowName},
+					TaskQueue:    &taskqueuepb.TaskQueue{Name: DockerRunnerQueue},
+					Input:        input,
+				},
+			},
+		},
+		Policies: &schedulepb.SchedulePolicies{
+			// Don't start a scan while the last one is still running.
+			OverlapPolicy: enumspb.SCHEDULE_OVERLAP_POLICY_SKIP,
+		},
+		State: &schedulepb.ScheduleState{
+			Paused: schedule.Paused,
+		},
+	}, nil
+}
+
+// UpsertDiscoverySchedule creates the Temporal schedule for a discovery
+// schedule, or updates it if it already exists.
+func UpsertDiscoverySchedule(
+	ctx context.Context,
+	c client.Client,
+	schedule model.DiscoverySchedule,
+) error {
+	sched, err := discoverySchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	return upsertSchedule(ctx, c, DiscoveryScheduleID(schedule.SiloDefinitionID), sched)
+}
+
+// PauseDiscoverySchedule pauses or resumes the Temporal schedule of a silo.
+func PauseDiscoverySchedule(
+	ctx context.Context,
+	c client.Client,
+	siloDefID string,
+	paused bool,
+) error {
+	patch := schedulepb.SchedulePatch{}
+	if paused {
+		patch.Pause = "Paused from Monoid"
+	} else {
+		patch.Unpause = "Resumed from Monoid"
+	}
+
+	_, err := c.WorkflowService().PatchSchedule(ctx, &workflowservice.PatchScheduleRequest{
+		Namespace:  client.DefaultNamespace,
+		ScheduleId: DiscoveryScheduleID(siloDefID),
+		Patch:      &patch,
+		RequestId:  uuid.NewString(),
+	})
+
+	return err
+}
+
+// DeleteDiscoverySchedule deletes the Temporal schedule of a silo, if it
+// has one.
+func DeleteDiscoverySchedule(
+	ctx context.Context,
+	c client.Client,
+	siloDefID string,
+) error {
+	_, err := c.WorkflowService().DeleteSchedule(ctx, &workflowservice.DeleteScheduleRequest{
+		Namespace:  client.DefaultNamespace,
+		ScheduleId: DiscoveryScheduleID(siloDefID),
+	})
+
+	if _, ok := err.(*serviceerror.NotFound); ok {
+		return nil
+	}
+
+	return err
+}
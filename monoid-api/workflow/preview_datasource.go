@@ -0,0 +1,32 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/monoid-privacy/monoid/workflow/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// PreviewDataSourceWorkflow scans a sample of records from a data source,
+// the result is encrypted (see activity.PreviewDataSource).
+func (w *Workflow) PreviewDataSourceWorkflow(
+	ctx workflow.Context,
+	args activity.PreviewDataSourceArgs,
+) ([]byte, error) {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute * 2,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 1,
+		},
+	}
+
+	ctx = workflow.WithActivityOptions(ctx, options)
+
+	var res []byte
+
+	ac := activity.Activity{}
+	err := workflow.ExecuteActivity(ctx, ac.PreviewDataSource, args).Get(ctx, &res)
+
+	return res, err
+}
@@ -0,0 +1,157 @@
+package workflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/workflow/activity"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	schedulepb "go.temporal.io/api/schedule/v1"
+	"go.temporal.io/api/serviceerror"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// PurgeDataWorkflowID is the id of the Temporal schedule that purges
+// expired request data, and of the workflows it starts.
+const PurgeDataWorkflowID = "purge-expired-data"
+
+// purgeDataWorkflowName is the name PurgeExpiredDataWorkflow is registered
+// with.
+const purgeDataWorkflowName = "PurgeExpiredDataWorkflow"
+
+// calendarSpec converts the fields of a cron expression to a Temporal
+// calendar spec.
+func calendarSpec(cron model.CronFields) *schedulepb.CalendarSpec {
+	return &schedulepb.CalendarSpec{
+		Second:     "0",
+		Minute:     cron.Minute,
+		Hour:       cron.Hour,
+		DayOfMonth: cron.DayOfMonth,
+		Month:      cron.Month,
+		DayOfWeek:  cron.DayOfWeek,
+		Year:       "*",
+	}
+}
+
+// upsertSchedule creates a Temporal schedule, or updates it if it already
+// exists.
+func upsertSchedule(
+	ctx context.Context,
+	c client.Client,
+	id string,
+	sched *schedulepb.Schedule,
+) error {
+	_, err := c.WorkflowService().UpdateSchedule(ctx, &workflowservice.UpdateScheduleRequest{
+		Namespace:  client.DefaultNamespace,
+		ScheduleId: id,
+		Schedule:   sched,
+		RequestId:  uuid.NewString(),
+	})
+
+	if _, ok := err.(*serviceerror.NotFound); !ok {
+		return err
+	}
+
+	_, err = c.WorkflowService().CreateSchedule(ctx, &workflowservice.CreateScheduleRequest{
+		Namespace:  client.DefaultNamespace,
+		ScheduleId: id,
+		Schedule:   sched,
+		RequestId:  uuid.NewString(),
+	})
+
+	return err
+}
+
+// purgeSchedule returns the Temporal schedule that runs
+// PurgeExpiredDataWorkflow on a cron expression.
+func purgeSchedule(cronExpr string) (*schedulepb.Schedule, error) {
+	cron, err := model.ParseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedulepb.Schedule{
+		Spec: &schedulepb.ScheduleSpec{
+			Calendar: []*schedulepb.CalendarSpec{calendarSpec(cron)},
+		},
+		Action: &schedulepb.ScheduleAction{
+			Action: &schedulepb.ScheduleAction_StartWorkflow{
+				StartWorkflow: &workflowpb.NewWorkflowExecutionInfo{
+					WorkflowId:   PurgeDataWorkflowID,
+					WorkflowType: &commonpb.WorkflowType{Name: purgeDataWorkflowName},
+					TaskQueue:    &taskqueuepb.TaskQueue{Name: DockerRunnerQueue},
+				},
+			},
+		},
+		Policies: &schedulepb.SchedulePolicies{
+			OverlapPolicy: enumspb.SCHEDULE_OVERLAP_POLICY_SKIP,
+		},
+	}, nil
+}
+
+// UpsertPurgeSchedule creates the schedule that purges expired request
+// data, or updates it to cronExpr if it already exists, so changes to the
+// schedule take effect when the worker restarts.
+func UpsertPurgeSchedule(ctx context.Context, c client.Client, cronExpr string) error {
+	sched, err := purgeSchedule(cronExpr)
+	if err != nil {
+		return err
+	}
+
+	return upsertSchedule(ctx, c, PurgeDataWorkflowID, sched)
+}
+
+// PurgeExpiredDataWorkflow purges the results of all requests that
+// finished before their workspace's retention period. It's run on a
+// schedule created by the worker.
+func (w *Workflow) PurgeExpiredDataWorkflow(ctx workflow.Context) error {
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute * 5,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 5,
+		},
+	}
+
+	ctx = workflow.WithActivityOptions(ctx, options)
+	logger := workflow.GetLogger(ctx)
+	ac := activity.Activity{}
+	now := workflow.Now(ctx)
+
+	requestIDs := []string{}
+	if err := workflow.ExecuteActivity(ctx, ac.FindExpiredRequests, activity.FindExpiredRequestsArgs{
+		Now: now,
+	}).Get(ctx, &requestIDs); err != nil {
+		return err
+	}
+
+	for _, id := range requestIDs {
+		tombstone := model.RequestTombstone{}
+		if err := workflow.ExecuteActivity(ctx, ac.PurgeRequestData, activity.PurgeRequestDataArgs{
+			RequestID: id,
+			Now:       now,
+		}).Get(ctx, &tombstone); err != nil {
+			// Keep purging the other requests, this one will be picked up
+			// again on the next run.
+			logger.Error("Error purging request data", "request", id, "error", err)
+			continue
+		}
+
+		logger.Info(
+			"Purged request data",
+			"request", id,
+			"queryResults", tombstone.QueryResultsPurged,
+			"files", tombstone.FilesPurged,
+			"primaryKeyValues", tombstone.PrimaryKeyValuesPurged,
+		)
+	}
+
+	return nil
+}
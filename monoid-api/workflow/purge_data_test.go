@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/monoid-privacy/monoid/config"
+	"github.com/monoid-privacy/monoid/model"
+	"github.com/monoid-privacy/monoid/workflow/activity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	schedulepb "go.temporal.io/api/schedule/v1"
+	"go.temporal.io/sdk/testsuite"
+)
+
+type purgeDataTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	a   *activity.Activity
+	w   *Workflow
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (s *purgeDataTestSuite) SetupTest() {
+	s.a = &activity.Activity{Conf: &config.BaseConfig{}}
+	s.w = &Workflow{Conf: &config.BaseConfig{}}
+
+	s.env = s.NewTestWorkflowEnvironment()
+	s.env.RegisterActivity(s.a.FindExpiredRequests)
+	s.env.RegisterActivity(s.a.PurgeRequestData)
+	s.env.RegisterWorkflow(s.w.PurgeExpiredDataWorkflow)
+}
+
+func (s *purgeDataTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+// TestPurgeContinuesOnError verifies that a request that can't be purged
+// doesn't stop the other requests from being purged.
+func (s *purgeDataTestSuite) TestPurgeContinuesOnError() {
+	s.env.OnActivity(s.a.FindExpiredRequests, mock.Anything, mock.Anything).Return(
+		[]string{"r1", "r2"}, nil,
+	)
+
+	s.env.OnActivity(s.a.PurgeRequestData, mock.Anything, mock.MatchedBy(
+		func(args activity.PurgeRequestDataArgs) bool { return args.RequestID == "r1" },
+	)).Return(model.RequestTombstone{}, fmt.Errorf("purge failed"))
+
+	s.env.OnActivity(s.a.PurgeRequestData, mock.Anything, mock.MatchedBy(
+		func(args activity.PurgeRequestDataArgs) bool { return args.RequestID == "r2" },
+	)).Return(model.RequestTombstone{RequestID: "r2"}, nil).Once()
+
+	s.env.ExecuteWorkflow(s.w.PurgeExpiredDataWorkflow)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func TestPurgeDataSuite(t *testing.T) {
+	suite.Run(t, new(purgeDataTestSuite))
+}
+
+func TestPurgeSchedule(t *testing.T) {
+	sched, err := purgeSchedule("0 3 * * *")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*schedulepb.CalendarSpec{{
+		Second:     "0",
+		Minute:     "0",
+		Hour:       "3",
+		DayOfMonth: "*",
+		Month:      "*",
+		DayOfWeek:  "*",
+		Year:       "*",
+	}}, sched.Spec.Calendar)
+	assert.Equal(t, PurgeDataWorkflowID, sched.Action.GetStartWorkflow().WorkflowId)
+	assert.Equal(t, "PurgeExpiredDataWorkflow", sched.Action.GetStartWorkflow().WorkflowType.Name)
+
+	_, err = purgeSchedule("@daily")
+	assert.Error(t, err)
+}